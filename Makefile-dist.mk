@@ -133,6 +133,10 @@ lint-fix: golangci-lint ## Run golangci-lint linter and perform fixes
 build: manifests generate fmt vet ## Build manager binary.
 	go build -o bin/manager cmd/main.go
 
+.PHONY: build-plugin
+build-plugin: fmt vet ## Build kubectl-lms plugin binary.
+	go build -o bin/kubectl-lms ./cmd/kubectl-lms
+
 .PHONY: run
 run: manifests generate fmt vet ## Run a controller from your host.
 	go run ./cmd/main.go
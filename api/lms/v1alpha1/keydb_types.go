@@ -122,6 +122,18 @@ type KeydbSpec struct {
 	// +optional
 	KeydbNetpolEgressIpblock string `json:"keydbNetpolEgressIpblock,omitempty"`
 
+	// KeydbNetpolIngressPeers defines the typed ingress peer for keydb default network policy.
+	// Its ipBlock cidr is serialized into keydbNetpolIngressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	KeydbNetpolIngressPeers []NetworkPolicyPeer `json:"keydbNetpolIngressPeers,omitempty"`
+
+	// KeydbNetpolEgressPeers defines the typed egress peer for keydb default network policy.
+	// Its ipBlock cidr is serialized into keydbNetpolEgressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	KeydbNetpolEgressPeers []NetworkPolicyPeer `json:"keydbNetpolEgressPeers,omitempty"`
+
 	// KeydbNetpolIngressExtraPorts defines extra ingress ports for keydb default network policy
 	// +optional
 	KeydbNetpolIngressExtraPorts []NetworkPolicyExtraPort `json:"keydbNetpolIngressExtraPorts,omitempty"`
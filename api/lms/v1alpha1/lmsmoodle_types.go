@@ -24,6 +24,7 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // LMSMoodleSpec defines the desired state of LMSMoodle
+// +kubebuilder:validation:XValidation:rule="has(self.targetNamespace) == has(oldSelf.targetNamespace)",message="targetNamespace cannot be set or unset once created"
 type LMSMoodleSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
@@ -34,7 +35,8 @@ type LMSMoodleSpec struct {
 	LMSMoodleTemplateName string `json:"lmsMoodleTemplateName"`
 
 	// LMSMoodleNetpolOmit whether to omit default network policy for the namespace. Default: false
-	// It will deny all ingress and egress traffic to the namespace
+	// It will deny all ingress and egress traffic to the namespace or, in a target namespace,
+	// to LMSMoodle pods
 	// Intended to be used with custom network policies already in place or
 	// by not omitting default network policies of each dependant resource
 	// +optional
@@ -46,6 +48,41 @@ type LMSMoodleSpec struct {
 	// +optional
 	DesiredState string `json:"desiredState,omitempty"`
 
+	// Paused whether LMSMoodle reconciliation is paused. Default: false
+	// While paused, dependant resources are neither applied, created nor deleted,
+	// only status is reported. It can also be paused with lms.krestomat.io/paused
+	// annotation set to "true". Deleting a paused LMSMoodle waits until it is unpaused
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// ComponentStates defines the desired state of each LMSMoodle component
+	// It only applies when desiredState is Ready. Dependency order is kept: Postgres, Keydb
+	// and NFS Ganesha are not suspended while Moodle is Ready
+	// +optional
+	ComponentStates ComponentStates `json:"componentStates,omitempty"`
+
+	// TargetNamespace defines an existing namespace to deploy dependant resources in,
+	// instead of creating one for the LMSMoodle. The namespace is neither owned, labeled
+	// nor deleted by the operator, and namespace metadata, quota and limit range are not
+	// applied to it. Several LMSMoodles can share it. It must be allowed by the operator target
+	// namespaces, never system ones nor the operator namespace. It cannot be changed once set
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="targetNamespace is immutable"
+	// +optional
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+
+	// UnsetFields lists template component spec fields the LMSMoodle deletes, dot separated,
+	// such as moodleSpec.moodleHost. Items of lists merged by key are deleted by their keys,
+	// such as moodleSpec.phpFpmTolerations[key=dedicated,effect=NoSchedule]. It is the same
+	// as a `$patch: delete` marker, which free-form fields such as moodleConfigAdditionalCfg
+	// accept too
+	// +kubebuilder:validation:MaxItems=100
+	// +kubebuilder:validation:items:MaxLength=255
+	// +kubebuilder:validation:items:Pattern=`^(moodleSpec|postgresSpec|nfsSpec|keydbSpec)(\.[A-Za-z0-9_-]+)+(\[[A-Za-z0-9_-]+=[^,\]]*(,[A-Za-z0-9_-]+=[^,\]]*)*\])?$`
+	// +optional
+	UnsetFields []string `json:"unsetFields,omitempty"`
+
 	// LMSMoodleTemplateSpec to set same fields as LMSMoodleTemplate
 	LMSMoodleTemplateSpec `json:",inline"`
 }
@@ -85,6 +122,210 @@ type LMSMoodleStatus struct {
 	// Release defines LMSMoodle moodle version
 	// +optional
 	Release string `json:"release,omitempty"`
+
+	// ComponentStates describes the state and observed status of each present LMSMoodle
+	// component: moodle, moodleCron, postgres, keydb and nfs
+	// +optional
+	ComponentStates map[string]ComponentStatus `json:"componentStates,omitempty"`
+
+	// ObservedGeneration is the most recent LMSMoodle generation observed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// AppliedSpecHash is the hash of the combined dependant specs last rolled out successfully
+	// +optional
+	AppliedSpecHash string `json:"appliedSpecHash,omitempty"`
+
+	// Timeline describes when the current operation started and when each component
+	// was first applied and became ready
+	// +optional
+	Timeline Timeline `json:"timeline,omitempty"`
+
+	// LastTimeline describes the last completed operation, kept once a new operation starts
+	// +optional
+	LastTimeline *Timeline `json:"lastTimeline,omitempty"`
+
+	// Notifications describes the last delivery to each notifier endpoint, by endpoint name
+	// +optional
+	Notifications map[string]NotificationStatus `json:"notifications,omitempty"`
+
+	// History of the last transitions to a settled, failed, paused or terminated state,
+	// oldest first. Intermediate states are not recorded
+	// +optional
+	History []StateTransition `json:"history,omitempty"`
+
+	// EffectiveSpecs describes the combined spec sent to each dependant, by component:
+	// moodle, postgres, keydb and nfs. Specs are published, with secrets redacted and
+	// the provenance of each field, in lmsmoodle-effective-specs ConfigMap in LMSMoodle namespace
+	// +optional
+	EffectiveSpecs map[string]EffectiveSpecStatus `json:"effectiveSpecs,omitempty"`
+
+	// Names of LMSMoodle namespace and dependant resources in use. They are kept
+	// even if operator naming policy changes
+	// +optional
+	Names DependantNames `json:"names,omitempty"`
+}
+
+// DependantNames describes names of LMSMoodle namespace and dependant resources
+type DependantNames struct {
+	// Namespace name of LMSMoodle dependant resources
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Base name of LMSMoodle dependant resources, such as Moodle or Postgres
+	// +optional
+	Base string `json:"base,omitempty"`
+
+	// LimitRange name of LMSMoodle namespace, once applied
+	// +optional
+	LimitRange string `json:"limitRange,omitempty"`
+
+	// ResourceQuota name of LMSMoodle namespace, once applied
+	// +optional
+	ResourceQuota string `json:"resourceQuota,omitempty"`
+}
+
+// EffectiveSpecStatus describes a combined dependant spec
+type EffectiveSpecStatus struct {
+	// Hash of the combined spec
+	// +optional
+	Hash string `json:"hash,omitempty"`
+
+	// SiteFields is the number of combined spec fields from LMSMoodle override
+	// +optional
+	SiteFields int32 `json:"siteFields,omitempty"`
+
+	// TemplateFields is the number of combined spec fields from LMSMoodleTemplate
+	// +optional
+	TemplateFields int32 `json:"templateFields,omitempty"`
+
+	// OperatorFields is the number of combined spec fields set by the operator
+	// +optional
+	OperatorFields int32 `json:"operatorFields,omitempty"`
+}
+
+// StateTransition describes a change of LMSMoodle state
+type StateTransition struct {
+	// PreviousState of LMSMoodle, as last recorded in history
+	// +optional
+	PreviousState string `json:"previousState,omitempty"`
+
+	// State of LMSMoodle after the transition
+	State string `json:"state"`
+
+	// Reason of the transition
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Timestamp of the transition
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// TemplateName of the LMSMoodleTemplate used
+	// +optional
+	TemplateName string `json:"templateName,omitempty"`
+
+	// TemplateGeneration of the LMSMoodleTemplate used
+	// +optional
+	TemplateGeneration int64 `json:"templateGeneration,omitempty"`
+}
+
+// Timeline describes the timing of a LMSMoodle operation: Provision, Upgrade or Resume
+type Timeline struct {
+	// Operation being timed
+	// +kubebuilder:validation:Enum=Provision;Upgrade;Resume
+	// +optional
+	Operation string `json:"operation,omitempty"`
+
+	// StartedAt is when the operation started
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// CompletedAt is when LMSMoodle settled after the operation started, as Ready,
+	// PartiallySuspended or Suspended
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// CompletedState is the state LMSMoodle settled in when the operation completed
+	// +optional
+	CompletedState string `json:"completedState,omitempty"`
+
+	// Components timeline by component: namespace, postgres, keydb, nfs and moodle
+	// +optional
+	Components map[string]ComponentTimeline `json:"components,omitempty"`
+}
+
+// ComponentTimeline describes when a component was first applied and became ready
+type ComponentTimeline struct {
+	// AppliedAt is when the component was first applied
+	// +optional
+	AppliedAt *metav1.Time `json:"appliedAt,omitempty"`
+
+	// ReadyAt is when the component first became ready
+	// +optional
+	ReadyAt *metav1.Time `json:"readyAt,omitempty"`
+}
+
+// ComponentStatus describes the state and observed status of a LMSMoodle component
+type ComponentStatus struct {
+	// State of the component, such as Ready, Suspended or the reason of its dependant
+	// ready condition
+	// +optional
+	State string `json:"state,omitempty"`
+
+	// Name of the dependant resource
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Namespace of the dependant resource
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Ready whether the dependant ready condition is true
+	Ready bool `json:"ready"`
+
+	// Reason of the dependant ready condition
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message of the dependant ready condition
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime of the dependant ready condition
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// ObservedGeneration of the dependant resource
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// ComponentStates defines the desired state of each LMSMoodle component
+type ComponentStates struct {
+	// Moodle desired state
+	// +kubebuilder:validation:Enum=Ready;Suspended
+	// +optional
+	Moodle string `json:"moodle,omitempty"`
+
+	// MoodleCron desired state. Moodle cronjob can be suspended while Moodle stays Ready
+	// +kubebuilder:validation:Enum=Ready;Suspended
+	// +optional
+	MoodleCron string `json:"moodleCron,omitempty"`
+
+	// Postgres desired state. It can only be suspended if Moodle is suspended
+	// +kubebuilder:validation:Enum=Ready;Suspended
+	// +optional
+	Postgres string `json:"postgres,omitempty"`
+
+	// Keydb desired state. It can only be suspended if Moodle is suspended
+	// +kubebuilder:validation:Enum=Ready;Suspended
+	// +optional
+	Keydb string `json:"keydb,omitempty"`
+
+	// Nfs desired state. It can only be suspended if Moodle is suspended
+	// +kubebuilder:validation:Enum=Ready;Suspended
+	// +optional
+	Nfs string `json:"nfs,omitempty"`
 }
 
 const (
@@ -108,6 +349,28 @@ const (
 
 	// Resource is successful
 	SuspendedState string = "Suspended"
+
+	// Resource is ready but some of its components are suspended
+	PartiallySuspendedState string = "PartiallySuspended"
+
+	// Resource reconciliation is paused
+	PausedState string = "Paused"
+)
+
+const (
+	// PausedAnnotation pauses LMSMoodle reconciliation when set to "true"
+	PausedAnnotation string = "lms.krestomat.io/paused"
+)
+
+const (
+	// LMSMoodle is being provisioned
+	ProvisionOperation string = "Provision"
+
+	// LMSMoodle spec is being rolled out after being ready
+	UpgradeOperation string = "Upgrade"
+
+	// LMSMoodle is being resumed after being suspended
+	ResumeOperation string = "Resume"
 )
 
 // +kubebuilder:object:root=true
@@ -130,6 +393,11 @@ type LMSMoodle struct {
 	Status LMSMoodleStatus `json:"status,omitempty"`
 }
 
+// IsPaused whether LMSMoodle reconciliation is paused, by spec or annotation
+func (l *LMSMoodle) IsPaused() bool {
+	return l.Spec.Paused || l.GetAnnotations()[PausedAnnotation] == "true"
+}
+
 // +kubebuilder:object:root=true
 
 // LMSMoodleList contains a list of LMSMoodle
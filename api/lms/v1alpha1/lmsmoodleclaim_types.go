@@ -0,0 +1,224 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LMSMoodleClaimSpec defines the desired state of LMSMoodleClaim
+type LMSMoodleClaimSpec struct {
+	// LMSMoodleTemplateName defines what LMS Moodle template to use. The template
+	// must allow claims from the claim namespace. It cannot be changed
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=255
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="lmsMoodleTemplateName is immutable"
+	LMSMoodleTemplateName string `json:"lmsMoodleTemplateName"`
+
+	// AgreeLicense whether agree to Moodle license. Required
+	AgreeLicense bool `json:"agreeLicense"`
+
+	// AdminMail is the admin email to set in new instance. Required
+	// +kubebuilder:validation:MinLength=3
+	// +kubebuilder:validation:MaxLength=100
+	AdminMail string `json:"adminMail"`
+
+	// AdminPassHash is the bcrypt compatible admin password to set in new instance. Required
+	// +kubebuilder:validation:MinLength=60
+	// +kubebuilder:validation:MaxLength=60
+	// +kubebuilder:validation:Pattern="^\\$2[ayb]\\$.{56}$"
+	AdminPassHash string `json:"adminPassHash"`
+
+	// Overrides of LMSMoodleTemplate fields. Only those allowed by the template can be set
+	// +optional
+	Overrides LMSMoodleClaimOverrides `json:"overrides,omitempty"`
+}
+
+// LMSMoodleClaimOverrides defines LMSMoodleTemplate fields a claim can override,
+// when allowed by the template
+type LMSMoodleClaimOverrides struct {
+	// DesiredState defines the desired state to put the claimed LMSMoodle
+	// +kubebuilder:validation:Enum=Ready;Suspended
+	// +optional
+	DesiredState string `json:"desiredState,omitempty"`
+
+	// Host defines Moodle host for url
+	// +kubebuilder:validation:MinLength=2
+	// +kubebuilder:validation:MaxLength=100
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// Fullname of new instance
+	// +kubebuilder:validation:MaxLength=100
+	// +optional
+	Fullname string `json:"fullname,omitempty"`
+
+	// Shortname of new instance
+	// +kubebuilder:validation:MaxLength=100
+	// +optional
+	Shortname string `json:"shortname,omitempty"`
+
+	// Summary of new instance
+	// +kubebuilder:validation:MaxLength=300
+	// +optional
+	Summary string `json:"summary,omitempty"`
+
+	// Lang defines new instance language code
+	// +kubebuilder:validation:Pattern="^[a-z_]+$"
+	// +kubebuilder:validation:MinLength=2
+	// +kubebuilder:validation:MaxLength=15
+	// +optional
+	Lang string `json:"lang,omitempty"`
+
+	// StorageSize defines moodledata storage size, such as 10Gi
+	// +kubebuilder:validation:MinLength=2
+	// +kubebuilder:validation:MaxLength=100
+	// +optional
+	StorageSize string `json:"storageSize,omitempty"`
+}
+
+// LMSMoodleClaimPolicy defines whether and how LMSMoodleClaims can use a LMSMoodleTemplate.
+// It is only read from LMSMoodleTemplate
+type LMSMoodleClaimPolicy struct {
+	// Allowed whether LMSMoodleClaims can use the template. Default: false
+	// +optional
+	Allowed bool `json:"allowed,omitempty"`
+
+	// NamespaceSelector selects namespaces whose claims can use the template. All
+	// namespaces, if not set
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// AllowedOverrides are claim overrides allowed. Claims setting others are not bound
+	// +optional
+	AllowedOverrides []LMSMoodleClaimOverride `json:"allowedOverrides,omitempty"`
+
+	// ReclaimPolicy of claimed LMSMoodles once their claim is deleted: Delete them
+	// or Retain them, released from the claim. Default: Delete
+	// +kubebuilder:validation:Enum=Delete;Retain
+	// +optional
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+}
+
+// LMSMoodleClaimOverride describes a claim override
+// +kubebuilder:validation:Enum=desiredState;host;fullname;shortname;summary;lang;storageSize
+type LMSMoodleClaimOverride string
+
+const (
+	// Claimed LMSMoodle is deleted along with its claim
+	DeleteReclaimPolicy string = "Delete"
+
+	// Claimed LMSMoodle is kept once its claim is deleted
+	RetainReclaimPolicy string = "Retain"
+
+	// Claim is waiting to be bound
+	PendingClaimPhase string = "Pending"
+
+	// Claim is bound to a LMSMoodle
+	BoundClaimPhase string = "Bound"
+
+	// Claim LMSMoodle no longer exists or is bound to another claim
+	LostClaimPhase string = "Lost"
+)
+
+const (
+	// ClaimAnnotation names the claim a LMSMoodle is bound to, as namespace/name
+	ClaimAnnotation string = "lms.krestomat.io/claim"
+
+	// ClaimUIDAnnotation is the uid of the claim a LMSMoodle is bound to
+	ClaimUIDAnnotation string = "lms.krestomat.io/claim-uid"
+)
+
+// LMSMoodleClaimStatus defines the observed state of LMSMoodleClaim
+type LMSMoodleClaimStatus struct {
+	// Conditions represent the latest available observations of the claim: Bound,
+	// Accepted and Ready, as mirrored from the claimed LMSMoodle
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// Phase of the claim: Pending, Bound or Lost
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// LMSMoodleName of the claimed LMSMoodle
+	// +optional
+	LMSMoodleName string `json:"lmsMoodleName,omitempty"`
+
+	// ReclaimPolicy of the claimed LMSMoodle, as set by the template when bound
+	// +optional
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+
+	// State of the claimed LMSMoodle
+	// +optional
+	State string `json:"state,omitempty"`
+
+	// Url of the claimed LMSMoodle
+	// +optional
+	Url string `json:"url,omitempty"`
+
+	// StorageGb of the claimed LMSMoodle in use
+	// +optional
+	StorageGb string `json:"storageGb,omitempty"`
+
+	// RegisteredUsers of the claimed LMSMoodle
+	// +optional
+	RegisteredUsers int64 `json:"registeredUsers,omitempty"`
+
+	// Release of the claimed LMSMoodle moodle
+	// +optional
+	Release string `json:"release,omitempty"`
+
+	// ObservedGeneration is the most recent claim generation observed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced,categories={lms},shortName=lmc
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp",description="Age of the resource",priority=0
+// +kubebuilder:printcolumn:name="PHASE",type="string",description="LMSMoodleClaim phase such as Pending/Bound/Lost",JSONPath=".status.phase",priority=0
+// +kubebuilder:printcolumn:name="LMSMOODLE",type="string",description="Claimed LMSMoodle name",JSONPath=".status.lmsMoodleName",priority=0
+// +kubebuilder:printcolumn:name="STATUS",type="string",description="Claimed LMSMoodle status",JSONPath=".status.state",priority=0
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".status.url",description="Claimed LMSMoodle URL",priority=0
+
+// LMSMoodleClaim is the Schema for the lmsmoodleclaims API. It requests a LMSMoodle
+// from a namespace, as persistent volume claims do with persistent volumes
+type LMSMoodleClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LMSMoodleClaimSpec   `json:"spec,omitempty"`
+	Status LMSMoodleClaimStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LMSMoodleClaimList contains a list of LMSMoodleClaim
+type LMSMoodleClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LMSMoodleClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LMSMoodleClaim{}, &LMSMoodleClaimList{})
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -42,6 +43,297 @@ type LMSMoodleTemplateSpec struct {
 	// KeydbSpec defines Keydb spec to deploy optionally
 	// +optional
 	KeydbSpec KeydbSpec `json:"keydbSpec"`
+
+	// ReadinessTimeouts defines how long to wait for each component to be ready
+	// before setting LMSMoodle as failed. No timeout by default
+	// +optional
+	ReadinessTimeouts ReadinessTimeouts `json:"readinessTimeouts,omitempty"`
+
+	// Notifier defines endpoints the operator notifies about LMSMoodle state changes
+	// +optional
+	Notifier Notifier `json:"notifier,omitempty"`
+
+	// StateHistory defines how LMSMoodle state transitions are kept
+	// +optional
+	StateHistory StateHistory `json:"stateHistory,omitempty"`
+
+	// Drift defines how changes made to dependant resources by other field managers are handled
+	// +optional
+	Drift Drift `json:"drift,omitempty"`
+
+	// NamespaceNetworkPolicy defines traffic allowed by the default network policy of LMSMoodle namespace.
+	// Any other ingress and egress traffic of the namespace is denied
+	// +optional
+	NamespaceNetworkPolicy NamespaceNetworkPolicy `json:"namespaceNetworkPolicy,omitempty"`
+
+	// NamespaceQuota defines the ResourceQuota of LMSMoodle namespace, created when set.
+	// LMSMoodle fields override template ones
+	// +optional
+	NamespaceQuota *NamespaceQuota `json:"namespaceQuota,omitempty"`
+
+	// NamespaceLimitRange defines the container LimitRange of LMSMoodle namespace, created when set
+	// or when a namespace quota is.
+	// LMSMoodle fields override template ones
+	// +optional
+	NamespaceLimitRange *NamespaceLimitRange `json:"namespaceLimitRange,omitempty"`
+
+	// NamespaceMetadata defines Pod Security Admission levels, labels and annotations of
+	// LMSMoodle namespace. LMSMoodle fields override template ones
+	// +optional
+	NamespaceMetadata NamespaceMetadata `json:"namespaceMetadata,omitempty"`
+
+	// Claims defines whether and how namespaced LMSMoodleClaims can use the template.
+	// It is only read from LMSMoodleTemplate
+	// +optional
+	Claims LMSMoodleClaimPolicy `json:"claims,omitempty"`
+
+	// OverridePolicy defines which LMSMoodle spec fields can override template ones.
+	// It is only read from LMSMoodleTemplate
+	// +optional
+	OverridePolicy OverridePolicy `json:"overridePolicy,omitempty"`
+
+	// MergeStrategies override how LMSMoodle component spec fields are merged with template
+	// ones, by field path. It is only read from LMSMoodleTemplate
+	// +listType=map
+	// +listMapKey=path
+	// +optional
+	MergeStrategies []FieldMergeStrategy `json:"mergeStrategies,omitempty"`
+
+	// MetadataPropagation defines which label and annotation keys propagate to which targets.
+	// Rules of each kind, if set, replace operator ones. It is only read from LMSMoodleTemplate
+	// +optional
+	MetadataPropagation MetadataPropagation `json:"metadataPropagation,omitempty"`
+}
+
+// Drift defines how changes made to dependant resources by other field managers are handled.
+// A field has drifted when its live value differs from the combined spec and it is owned
+// by a field manager other than the operator
+type Drift struct {
+	// Policy for drifted fields: Overwrite them with the combined spec, removing the ones no longer
+	// in it that the operator applied before, Report them only, keeping their live values, or Adopt
+	// them into LMSMoodle spec overrides. Either way, fields never applied by the operator are kept.
+	// Default: Overwrite
+	// +kubebuilder:validation:Enum=Overwrite;Report;Adopt
+	// +optional
+	Policy string `json:"policy,omitempty"`
+
+	// IgnoredManagers are field managers whose changes are not considered drift,
+	// such as dependant operators updating their own resources
+	// +optional
+	IgnoredManagers []string `json:"ignoredManagers,omitempty"`
+}
+
+const (
+	// Drifted fields are overwritten with the combined spec
+	OverwriteDriftPolicy string = "Overwrite"
+
+	// Drifted fields are reported only, keeping their live values
+	ReportDriftPolicy string = "Report"
+
+	// Drifted fields are adopted into LMSMoodle spec overrides
+	AdoptDriftPolicy string = "Adopt"
+)
+
+// OverridePolicy defines which LMSMoodle spec fields can override LMSMoodleTemplate ones.
+// Lifecycle fields lmsMoodleTemplateName, desiredState, paused and componentStates can always
+// be set. Fields set by a LMSMoodleClaim, as allowed by template claims, are allowed too
+type OverridePolicy struct {
+	// Restricted whether LMSMoodles can only set allowed fields. Default: false, any field
+	// +optional
+	Restricted bool `json:"restricted,omitempty"`
+
+	// Action on overrides not allowed: Ignore them, reconciling LMSMoodle as if they were
+	// not set, or Reject them, not reconciling LMSMoodle until they are removed. Either way,
+	// they are reported in OverridesAllowed condition. Default: Ignore
+	// +kubebuilder:validation:Enum=Ignore;Reject
+	// +optional
+	Action string `json:"action,omitempty"`
+
+	// Allowed fields LMSMoodles can set, with optional constraints on their values
+	// +optional
+	Allowed []AllowedOverride `json:"allowed,omitempty"`
+}
+
+// AllowedOverride defines a LMSMoodle spec field allowed to override template one. When
+// its value is a list, constraints apply to each item
+type AllowedOverride struct {
+	// Path of the field in LMSMoodle spec, dot separated, such as moodleSpec.moodleHost.
+	// Fields nested in it are allowed too, with the same constraints, unless a longer path
+	// allows them
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=255
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`
+	Path string `json:"path"`
+
+	// Enum of allowed values, written as strings, such as "true", "2" or "Ready"
+	// +optional
+	Enum []string `json:"enum,omitempty"`
+
+	// Minimum of a number or quantity, such as 1 or 10Gi
+	// +optional
+	Minimum *resource.Quantity `json:"minimum,omitempty"`
+
+	// Maximum of a number or quantity, such as 3 or 100Gi
+	// +optional
+	Maximum *resource.Quantity `json:"maximum,omitempty"`
+
+	// Pattern is a regular expression a value must match, such as ^[a-z]+\.example\.com$
+	// +kubebuilder:validation:MaxLength=1024
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+}
+
+const (
+	// Overrides not allowed are ignored, as if they were not set
+	IgnoreOverrideAction string = "Ignore"
+
+	// Overrides not allowed reject LMSMoodle, which is not reconciled until they are removed
+	RejectOverrideAction string = "Reject"
+)
+
+// FieldMergeStrategy defines how a LMSMoodle component spec field is merged with template one.
+// By default, maps are merged field by field, tolerations are merged by key and effect, network
+// policy extra ports by port and protocol, network policy peers are appended, notify headers
+// are merged by name, ingress annotations key by key and any other field is replaced
+type FieldMergeStrategy struct {
+	// Path of the field, dot separated, such as moodleSpec.phpFpmTolerations
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=255
+	// +kubebuilder:validation:Pattern=`^(moodleSpec|postgresSpec|nfsSpec|keydbSpec)(\.[A-Za-z0-9_-]+)+$`
+	Path string `json:"path"`
+
+	// Strategy to merge the field: Replace template value with LMSMoodle one, Append LMSMoodle
+	// list items to template ones, skipping those already there, or MergeByKey, merging list
+	// items with the same keys field by field and YAML maps, such as ingress annotations, key by key
+	// +kubebuilder:validation:Enum=Replace;Append;MergeByKey
+	Strategy string `json:"strategy"`
+
+	// Keys identifying list items merged by key, such as key and effect for tolerations. Whole
+	// items, if not set
+	// +optional
+	Keys []string `json:"keys,omitempty"`
+}
+
+const (
+	// Template value is replaced with LMSMoodle one
+	ReplaceMergeStrategy string = "Replace"
+
+	// LMSMoodle list items are appended to template ones
+	AppendMergeStrategy string = "Append"
+
+	// LMSMoodle list items and YAML map keys are merged with template ones with the same keys
+	MergeByKeyMergeStrategy string = "MergeByKey"
+)
+
+// MetadataPropagation defines rules propagating label and annotation keys to targets. Labels
+// and annotations propagate to LMSMoodle from its template, and to other targets from LMSMoodle.
+// Keys removed from the source, or no longer propagated, are removed from targets
+type MetadataPropagation struct {
+	// Labels propagation rules. LMSMoodle base labels, such as lms.krestomat.io/lms-name,
+	// always propagate
+	// +kubebuilder:validation:MaxItems=50
+	// +optional
+	Labels []PropagationRule `json:"labels,omitempty"`
+
+	// Annotations propagation rules. Annotations cannot propagate to Pods. Those with
+	// lms.krestomat.io/ and kubectl.kubernetes.io/ prefixes never propagate
+	// +kubebuilder:validation:MaxItems=50
+	// +kubebuilder:validation:XValidation:rule="self.all(r, !('Pods' in r.targets))",message="annotations cannot propagate to Pods"
+	// +optional
+	Annotations []PropagationRule `json:"annotations,omitempty"`
+}
+
+// PropagationRule selects label or annotation keys by prefix or regex, propagating them to
+// targets or, if excluded, not propagating them. For each target, the first rule matching
+// a key applies. Keys no rule matches do not propagate
+// +kubebuilder:validation:XValidation:rule="has(self.prefix) != has(self.regex)",message="either prefix or regex must be set"
+type PropagationRule struct {
+	// Prefix of keys, such as example.com/
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Regex keys match, such as ^team$
+	// +kubebuilder:validation:MaxLength=1024
+	// +optional
+	Regex string `json:"regex,omitempty"`
+
+	// Targets of keys: LMSMoodle, from its template, Namespace, unless it is a target one,
+	// Moodle, Postgres, Nfs and Keydb resources, and Pods, through component commonLabels
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=7
+	Targets []PropagationTarget `json:"targets"`
+
+	// Exclude whether keys are not propagated to targets. Default: false
+	// +optional
+	Exclude bool `json:"exclude,omitempty"`
+}
+
+// PropagationTarget describes a target of label and annotation keys
+// +kubebuilder:validation:Enum=LMSMoodle;Namespace;Moodle;Postgres;Nfs;Keydb;Pods
+type PropagationTarget string
+
+const (
+	// LMSMoodle, from its template
+	LMSMoodlePropagationTarget PropagationTarget = "LMSMoodle"
+
+	// LMSMoodle namespace, unless it is a target one
+	NamespacePropagationTarget PropagationTarget = "Namespace"
+
+	// Moodle resource
+	MoodlePropagationTarget PropagationTarget = "Moodle"
+
+	// Postgres resource
+	PostgresPropagationTarget PropagationTarget = "Postgres"
+
+	// NFS Ganesha resource
+	NfsPropagationTarget PropagationTarget = "Nfs"
+
+	// Keydb resource
+	KeydbPropagationTarget PropagationTarget = "Keydb"
+
+	// Pods of each component, through their commonLabels. Only labels propagate to them
+	PodsPropagationTarget PropagationTarget = "Pods"
+)
+
+// StateHistory defines how LMSMoodle state transitions are kept
+type StateHistory struct {
+	// Limit of transitions kept in LMSMoodle status. 10 by default
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Limit int32 `json:"limit,omitempty"`
+
+	// ConfigMap whether transitions are mirrored to a ConfigMap in the
+	// LMSMoodle namespace, for longer retention
+	// +optional
+	ConfigMap bool `json:"configMap,omitempty"`
+
+	// ConfigMapLimit of transitions kept in the ConfigMap. 500 by default.
+	// Oldest transitions are also dropped to keep the ConfigMap under 512KiB
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=5000
+	// +optional
+	ConfigMapLimit int32 `json:"configMapLimit,omitempty"`
+}
+
+// ReadinessTimeouts defines readiness deadline of each LMSMoodle component
+type ReadinessTimeouts struct {
+	// Moodle readiness timeout, such as 30m
+	// +optional
+	Moodle *metav1.Duration `json:"moodle,omitempty"`
+
+	// Postgres readiness timeout, such as 15m
+	// +optional
+	Postgres *metav1.Duration `json:"postgres,omitempty"`
+
+	// Keydb readiness timeout, such as 10m
+	// +optional
+	Keydb *metav1.Duration `json:"keydb,omitempty"`
+
+	// Nfs readiness timeout, such as 10m
+	// +optional
+	Nfs *metav1.Duration `json:"nfs,omitempty"`
 }
 
 // LMSMoodleTemplateStatus defines the observed state of LMSMoodleTemplate
@@ -124,6 +124,10 @@ type MoodleSpec struct {
 	// +optional
 	MoodleCronjobAffinity string `json:"moodleCronjobAffinity,omitempty"`
 
+	// MoodleCronjobSuspend whether moodle cronjob is suspended. Default: false
+	// +optional
+	MoodleCronjobSuspend bool `json:"moodleCronjobSuspend,omitempty"`
+
 	// MoodleCronjobResourceRequests whether moodle cronjob resource requests are added. Default: true
 	// +optional
 	MoodleCronjobResourceRequests bool `json:"moodleCronjobResourceRequests,omitempty"`
@@ -271,6 +275,18 @@ type MoodleSpec struct {
 	// +optional
 	MoodleNetpolEgressIpblock string `json:"moodleNetpolEgressIpblock,omitempty"`
 
+	// MoodleNetpolIngressPeers defines the typed ingress peer for moodle default network policy.
+	// Its ipBlock cidr is serialized into moodleNetpolIngressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	MoodleNetpolIngressPeers []NetworkPolicyPeer `json:"moodleNetpolIngressPeers,omitempty"`
+
+	// MoodleNetpolEgressPeers defines the typed egress peer for moodle default network policy.
+	// Its ipBlock cidr is serialized into moodleNetpolEgressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	MoodleNetpolEgressPeers []NetworkPolicyPeer `json:"moodleNetpolEgressPeers,omitempty"`
+
 	// MoodleNetpolIngressExtraPorts defines extra ingress ports for moodle default network policy
 	// +optional
 	MoodleNetpolIngressExtraPorts []NetworkPolicyExtraPort `json:"moodleNetpolIngressExtraPorts,omitempty"`
@@ -356,6 +372,18 @@ type MoodleSpec struct {
 	// +optional
 	NginxNetpolEgressIpblock string `json:"nginxNetpolEgressIpblock,omitempty"`
 
+	// NginxNetpolIngressPeers defines the typed ingress peer for nginx default network policy.
+	// Its ipBlock cidr is serialized into nginxNetpolIngressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	NginxNetpolIngressPeers []NetworkPolicyPeer `json:"nginxNetpolIngressPeers,omitempty"`
+
+	// NginxNetpolEgressPeers defines the typed egress peer for nginx default network policy.
+	// Its ipBlock cidr is serialized into nginxNetpolEgressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	NginxNetpolEgressPeers []NetworkPolicyPeer `json:"nginxNetpolEgressPeers,omitempty"`
+
 	// NginxNetpolIngressExtraPorts defines extra ingress ports for nginx default network policy
 	// +optional
 	NginxNetpolIngressExtraPorts []NetworkPolicyExtraPort `json:"nginxNetpolIngressExtraPorts,omitempty"`
@@ -447,6 +475,18 @@ type MoodleSpec struct {
 	// +optional
 	PhpFpmNetpolEgressIpblock string `json:"phpFpmNetpolEgressIpblock,omitempty"`
 
+	// PhpFpmNetpolIngressPeers defines the typed ingress peer for php-fpm default network policy.
+	// Its ipBlock cidr is serialized into phpFpmNetpolIngressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	PhpFpmNetpolIngressPeers []NetworkPolicyPeer `json:"phpFpmNetpolIngressPeers,omitempty"`
+
+	// PhpFpmNetpolEgressPeers defines the typed egress peer for php-fpm default network policy.
+	// Its ipBlock cidr is serialized into phpFpmNetpolEgressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	PhpFpmNetpolEgressPeers []NetworkPolicyPeer `json:"phpFpmNetpolEgressPeers,omitempty"`
+
 	// PhpFpmNetpolIngressExtraPorts defines extra ingress ports for php-fpm default network policy
 	// +optional
 	PhpFpmNetpolIngressExtraPorts []NetworkPolicyExtraPort `json:"phpFpmNetpolIngressExtraPorts,omitempty"`
@@ -566,15 +606,28 @@ type RoutineStatusCrNotifyUUID string
 
 // RoutineStatusCrNotifyHeaders used when notifying status to an endpoint
 // +optional
-type RoutineStatusCrNotifyHeaders struct{}
+type RoutineStatusCrNotifyHeaders map[string]string
+
+// RoutineStatusCrNotifyHeaderFrom is a header used when notifying status to an endpoint,
+// with its value from a secret key
+type RoutineStatusCrNotifyHeaderFrom struct {
+	// Name of the header
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`
+	Name string `json:"name"`
+	// SecretKeyRef selects a key of a secret, in LMSMoodle or an allowed secret namespace
+	SecretKeyRef SecretKeySelector `json:"secretKeyRef"`
+}
 
 // RoutineStatusCrNotify specification using ansible URI module
 type RoutineStatusCrNotify struct {
 	// HTTP or HTTPS URL in the form (http|https)://host.domain[:port]/path
+	// +kubebuilder:validation:Pattern=`^https?://[^\s/?#]+[^\s]*$`
 	Url string `json:"url"`
 	// StatusCode A list of valid, numeric, HTTP status codes that signifies success of the request.
+	// +kubebuilder:validation:items:Minimum=100
+	// +kubebuilder:validation:items:Maximum=599
 	// +optional
-	StatusCode []int8 `json:"statusCode,omitempty"`
+	StatusCode []int32 `json:"statusCode,omitempty"`
 	// Method The HTTP method of the request or response.
 	// +kubebuilder:validation:Enum=GET;POST;PUT;PATCH;DELETE
 	// +optional
@@ -585,9 +638,19 @@ type RoutineStatusCrNotify struct {
 	// Headers used when notifying status to an endpoint
 	// +optional
 	Headers RoutineStatusCrNotifyHeaders `json:"headers,omitempty"`
+	// HeadersFrom are headers with values from secrets, in LMSMoodle or an allowed secret namespace.
+	// The operator copies them to a secret in LMSMoodle namespace, referenced in Moodle spec
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	HeadersFrom []RoutineStatusCrNotifyHeaderFrom `json:"headersFrom,omitempty"`
 	// JwtSecretEnvName environment variable name that holds secret to generate jwt tokens
 	// +optional
 	JwtSecretEnvName string `json:"jwtSecretEnvName,omitempty"`
+	// JwtSecretRef selects a secret key that holds secret to generate jwt tokens, in LMSMoodle
+	// or an allowed secret namespace. The operator copies it to a secret in LMSMoodle namespace, referenced in Moodle spec
+	// +optional
+	JwtSecretRef *SecretKeySelector `json:"jwtSecretRef,omitempty"`
 }
 
 type NetworkPolicyExtraPort struct {
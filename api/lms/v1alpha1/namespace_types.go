@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NamespaceQuota defines the ResourceQuota of LMSMoodle namespace. Hard limits not set
+// are calculated from resource requests in combined specs, plus headroom
+type NamespaceQuota struct {
+	// Omit whether to omit namespace resource quota, such as one set by the template
+	// +optional
+	Omit bool `json:"omit,omitempty"`
+
+	// Hard limits of the namespace, such as requests.cpu, limits.memory or pods.
+	// They override calculated ones
+	// +optional
+	Hard corev1.ResourceList `json:"hard,omitempty"`
+
+	// HeadroomPercent added to resource requests in combined specs to calculate
+	// requests.cpu and requests.memory hard limits, leaving room for rolling updates,
+	// jobs and autoscaling. Default: 50
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	HeadroomPercent *int32 `json:"headroomPercent,omitempty"`
+}
+
+// NamespaceLimitRange defines the container LimitRange of LMSMoodle namespace.
+// Default requests and limits not set are the smallest ones in combined specs. Those
+// of resources constrained by namespace quota hard limits are required
+type NamespaceLimitRange struct {
+	// Omit whether to omit namespace limit range, such as one set by the template
+	// +optional
+	Omit bool `json:"omit,omitempty"`
+
+	// DefaultRequest of containers without resource requests
+	// +optional
+	DefaultRequest corev1.ResourceList `json:"defaultRequest,omitempty"`
+
+	// Default limits of containers without resource limits
+	// +optional
+	Default corev1.ResourceList `json:"default,omitempty"`
+
+	// Max resources of a container
+	// +optional
+	Max corev1.ResourceList `json:"max,omitempty"`
+
+	// Min resources of a container
+	// +optional
+	Min corev1.ResourceList `json:"min,omitempty"`
+}
+
+// NamespaceMetadata defines labels and annotations of LMSMoodle namespace, kept up to date.
+// Those removed from spec are removed from the namespace too
+type NamespaceMetadata struct {
+	// PodSecurity defines Pod Security Admission levels of the namespace
+	// +optional
+	PodSecurity *NamespacePodSecurity `json:"podSecurity,omitempty"`
+
+	// Labels of the namespace, such as service mesh injection ones: istio-injection: enabled.
+	// LMSMoodle labels take precedence
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations of the namespace, such as cost centre, owner or openshift.io/node-selector
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// NamespacePodSecurity defines Pod Security Admission levels and versions, set as
+// pod-security.kubernetes.io labels of the namespace
+type NamespacePodSecurity struct {
+	// Enforce level. Pods violating it are rejected
+	// +optional
+	Enforce PodSecurityLevel `json:"enforce,omitempty"`
+
+	// EnforceVersion of the enforce level policy, such as latest or v1.31
+	// +optional
+	EnforceVersion PodSecurityVersion `json:"enforceVersion,omitempty"`
+
+	// Audit level. Pods violating it are annotated in audit log
+	// +optional
+	Audit PodSecurityLevel `json:"audit,omitempty"`
+
+	// AuditVersion of the audit level policy, such as latest or v1.31
+	// +optional
+	AuditVersion PodSecurityVersion `json:"auditVersion,omitempty"`
+
+	// Warn level. Pods violating it trigger a warning to the user
+	// +optional
+	Warn PodSecurityLevel `json:"warn,omitempty"`
+
+	// WarnVersion of the warn level policy, such as latest or v1.31
+	// +optional
+	WarnVersion PodSecurityVersion `json:"warnVersion,omitempty"`
+}
+
+// PodSecurityLevel describes a Pod Security Standards level
+// +kubebuilder:validation:Enum=privileged;baseline;restricted
+type PodSecurityLevel string
+
+// PodSecurityVersion describes a Pod Security Standards version
+// +kubebuilder:validation:Pattern=`^(latest|v[0-9]+\.[0-9]+)$`
+type PodSecurityVersion string
@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// NamespaceNetworkPolicy defines traffic allowed by the default network policy of
+// LMSMoodle namespace. Any other ingress and egress traffic of the namespace is denied
+type NamespaceNetworkPolicy struct {
+	// AllowSameNamespace whether to allow ingress and egress traffic between pods within the namespace
+	// +optional
+	AllowSameNamespace *bool `json:"allowSameNamespace,omitempty"`
+
+	// AllowDNS whether to allow DNS egress traffic to kube-system namespace, on port 53
+	// +optional
+	AllowDNS *bool `json:"allowDNS,omitempty"`
+
+	// IngressController allows ingress traffic from the ingress controller namespace
+	// +optional
+	IngressController *NamespaceNetworkPolicyIngress `json:"ingressController,omitempty"`
+
+	// Monitoring allows ingress traffic from the monitoring namespace, for metrics scrapes
+	// +optional
+	Monitoring *NamespaceNetworkPolicyIngress `json:"monitoring,omitempty"`
+
+	// ExtraEgress allows egress traffic to CIDRs, such as SMTP servers and external APIs.
+	// LMSMoodle rules override template rules with the same name
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ExtraEgress []NamespaceNetworkPolicyEgress `json:"extraEgress,omitempty"`
+}
+
+// NamespaceNetworkPolicyIngress allows ingress traffic from a namespace matching labels
+type NamespaceNetworkPolicyIngress struct {
+	// NamespaceLabels of the namespace allowed, such as kubernetes.io/metadata.name: ingress-nginx
+	// +kubebuilder:validation:MinProperties=1
+	NamespaceLabels map[string]string `json:"namespaceLabels"`
+
+	// PodLabels of the pods allowed in that namespace. Any pod, if not set
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// Ports allowed. Any port, if not set
+	// +optional
+	Ports []NetworkPolicyExtraPort `json:"ports,omitempty"`
+}
+
+// NamespaceNetworkPolicyEgress allows egress traffic to a CIDR
+type NamespaceNetworkPolicyEgress struct {
+	// Name of the egress rule, such as smtp
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// CIDR allowed, such as 203.0.113.0/24 or 2001:db8::/32
+	// +kubebuilder:validation:Pattern=`^(((25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\.){3}(25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])/(3[0-2]|[12]?[0-9])|[0-9a-fA-F:]*:[0-9a-fA-F:.]*/(12[0-8]|1[01][0-9]|[1-9]?[0-9]))$`
+	CIDR string `json:"cidr"`
+
+	// Ports allowed. Any port, if not set
+	// +optional
+	Ports []NetworkPolicyExtraPort `json:"ports,omitempty"`
+}
+
+// NetworkPolicyPeer selects a peer allowed by a component default network policy, as
+// networking.k8s.io/v1 NetworkPolicyPeer does. Dependants only support ipBlock cidr
+type NetworkPolicyPeer struct {
+	// IPBlock selects an IPv4 or IPv6 CIDR
+	IPBlock NetworkPolicyIPBlock `json:"ipBlock"`
+}
+
+// NetworkPolicyIPBlock selects an IPv4 or IPv6 CIDR
+type NetworkPolicyIPBlock struct {
+	// CIDR allowed, such as 203.0.113.0/24 or 2001:db8::/32
+	// +kubebuilder:validation:Pattern=`^(((25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\.){3}(25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])/(3[0-2]|[12]?[0-9])|[0-9a-fA-F:]*:[0-9a-fA-F:.]*/(12[0-8]|1[01][0-9]|[1-9]?[0-9]))$`
+	CIDR string `json:"cidr"`
+}
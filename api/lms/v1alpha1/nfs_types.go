@@ -132,6 +132,18 @@ type NfsSpec struct {
 	// +optional
 	GaneshaNetpolEgressIpblock string `json:"ganeshaNetpolEgressIpblock,omitempty"`
 
+	// GaneshaNetpolIngressPeers defines the typed ingress peer for ganesha default network policy.
+	// Its ipBlock cidr is serialized into ganeshaNetpolIngressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	GaneshaNetpolIngressPeers []NetworkPolicyPeer `json:"ganeshaNetpolIngressPeers,omitempty"`
+
+	// GaneshaNetpolEgressPeers defines the typed egress peer for ganesha default network policy.
+	// Its ipBlock cidr is serialized into ganeshaNetpolEgressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	GaneshaNetpolEgressPeers []NetworkPolicyPeer `json:"ganeshaNetpolEgressPeers,omitempty"`
+
 	// GaneshaNetpolIngressExtraPorts defines extra ingress ports for ganesha default network policy
 	// +optional
 	GaneshaNetpolIngressExtraPorts []NetworkPolicyExtraPort `json:"ganeshaNetpolIngressExtraPorts,omitempty"`
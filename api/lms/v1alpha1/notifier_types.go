@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Notifier defines endpoints the operator notifies about LMSMoodle state changes,
+// sending CloudEvents over HTTP
+type Notifier struct {
+	// Endpoints to send CloudEvents to. LMSMoodle endpoints override template
+	// endpoints with the same name
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Endpoints []NotifierEndpoint `json:"endpoints,omitempty"`
+}
+
+// NotifierEndpoint defines an HTTP endpoint receiving CloudEvents
+type NotifierEndpoint struct {
+	// Name identifies the endpoint
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Url in the form (http|https)://host.domain[:port]/path. The operator may restrict
+	// endpoint hosts, and does not reach private addresses, such as cluster ones, by default
+	// +kubebuilder:validation:Pattern=`^https?://.+`
+	Url string `json:"url"`
+
+	// States to notify about. Ready, Failed, Suspended and Terminated by default
+	// +optional
+	States []NotifierState `json:"states,omitempty"`
+
+	// Signing defines how requests are signed. Not signed by default
+	// +optional
+	Signing *NotifierSigning `json:"signing,omitempty"`
+
+	// MaxAttempts before giving up on delivering an event. 10 by default
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+}
+
+// NotifierState is a LMSMoodle state to notify about
+// +kubebuilder:validation:Enum=Ready;Failed;Suspended;Terminated
+type NotifierState string
+
+// NotifierSigning defines how requests to an endpoint are signed
+type NotifierSigning struct {
+	// Method used for signing: HMAC, signature of the body in X-LMS-Signature header,
+	// or JWT, HS256 bearer token in Authorization header
+	// +kubebuilder:validation:Enum=HMAC;JWT
+	Method string `json:"method"`
+
+	// SecretRef selects the secret key holding the signing key. The secret must be in
+	// the operator namespace, labeled lms.krestomat.io/notifier-signing=true
+	SecretRef SecretKeySelector `json:"secretRef"`
+}
+
+// SecretKeySelector selects a key of a secret
+type SecretKeySelector struct {
+	// Name of the secret
+	Name string `json:"name"`
+
+	// Namespace of the secret
+	Namespace string `json:"namespace"`
+
+	// Key of the secret
+	Key string `json:"key"`
+}
+
+// NotificationStatus describes the last delivery to an endpoint
+type NotificationStatus struct {
+	// EventID of the last event delivered or attempted
+	// +optional
+	EventID string `json:"eventID,omitempty"`
+
+	// EventType of the last event delivered or attempted
+	// +optional
+	EventType string `json:"eventType,omitempty"`
+
+	// Result of the last delivery
+	// +kubebuilder:validation:Enum=Delivered;Retrying;Failed
+	// +optional
+	Result string `json:"result,omitempty"`
+
+	// Attempts made to deliver the last event
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// Message of the last delivery, such as the HTTP status or error
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastAttemptTime is when the last delivery was attempted
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// Pending events to deliver to the endpoint
+	// +optional
+	Pending int32 `json:"pending,omitempty"`
+}
+
+const (
+	// HMACSigningMethod signs the request body with HMAC-SHA256
+	HMACSigningMethod string = "HMAC"
+
+	// JWTSigningMethod adds a HS256 JWT bearer token
+	JWTSigningMethod string = "JWT"
+
+	// DeliveredNotificationResult event was delivered
+	DeliveredNotificationResult string = "Delivered"
+
+	// RetryingNotificationResult event delivery failed and will be retried
+	RetryingNotificationResult string = "Retrying"
+
+	// FailedNotificationResult event delivery failed and will not be retried
+	FailedNotificationResult string = "Failed"
+)
@@ -126,6 +126,18 @@ type PostgresSpec struct {
 	// +optional
 	PostgresNetpolEgressIpblock string `json:"postgresNetpolEgressIpblock,omitempty"`
 
+	// PostgresNetpolIngressPeers defines the typed ingress peer for postgres default network policy.
+	// Its ipBlock cidr is serialized into postgresNetpolIngressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	PostgresNetpolIngressPeers []NetworkPolicyPeer `json:"postgresNetpolIngressPeers,omitempty"`
+
+	// PostgresNetpolEgressPeers defines the typed egress peer for postgres default network policy.
+	// Its ipBlock cidr is serialized into postgresNetpolEgressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	PostgresNetpolEgressPeers []NetworkPolicyPeer `json:"postgresNetpolEgressPeers,omitempty"`
+
 	// PostgresNetpolIngressExtraPorts defines extra ingress ports for postgres default network policy
 	// +optional
 	PostgresNetpolIngressExtraPorts []NetworkPolicyExtraPort `json:"postgresNetpolIngressExtraPorts,omitempty"`
@@ -270,6 +282,18 @@ type PostgresSpec struct {
 	// +optional
 	PgbouncerNetpolEgressIpblock string `json:"pgbouncerNetpolEgressIpblock,omitempty"`
 
+	// PgbouncerNetpolIngressPeers defines the typed ingress peer for pgbouncer default network policy.
+	// Its ipBlock cidr is serialized into pgbouncerNetpolIngressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	PgbouncerNetpolIngressPeers []NetworkPolicyPeer `json:"pgbouncerNetpolIngressPeers,omitempty"`
+
+	// PgbouncerNetpolEgressPeers defines the typed egress peer for pgbouncer default network policy.
+	// Its ipBlock cidr is serialized into pgbouncerNetpolEgressIpblock, replacing it. Dependants support a single CIDR
+	// +optional
+	// +kubebuilder:validation:MaxItems=1
+	PgbouncerNetpolEgressPeers []NetworkPolicyPeer `json:"pgbouncerNetpolEgressPeers,omitempty"`
+
 	// PgbouncerNetpolIngressExtraPorts defines extra ingress ports for pgbouncer default network policy
 	// +optional
 	PgbouncerNetpolIngressExtraPorts []NetworkPolicyExtraPort `json:"pgbouncerNetpolIngressExtraPorts,omitempty"`
@@ -26,6 +26,163 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AllowedOverride) DeepCopyInto(out *AllowedOverride) {
+	*out = *in
+	if in.Enum != nil {
+		in, out := &in.Enum, &out.Enum
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Minimum != nil {
+		in, out := &in.Minimum, &out.Minimum
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Maximum != nil {
+		in, out := &in.Maximum, &out.Maximum
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AllowedOverride.
+func (in *AllowedOverride) DeepCopy() *AllowedOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(AllowedOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
+func (in *ComponentStatus) DeepCopy() *ComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentTimeline) DeepCopyInto(out *ComponentTimeline) {
+	*out = *in
+	if in.AppliedAt != nil {
+		in, out := &in.AppliedAt, &out.AppliedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ReadyAt != nil {
+		in, out := &in.ReadyAt, &out.ReadyAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentTimeline.
+func (in *ComponentTimeline) DeepCopy() *ComponentTimeline {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentTimeline)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentStates) DeepCopyInto(out *ComponentStates) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStates.
+func (in *ComponentStates) DeepCopy() *ComponentStates {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentStates)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DependantNames) DeepCopyInto(out *DependantNames) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DependantNames.
+func (in *DependantNames) DeepCopy() *DependantNames {
+	if in == nil {
+		return nil
+	}
+	out := new(DependantNames)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Drift) DeepCopyInto(out *Drift) {
+	*out = *in
+	if in.IgnoredManagers != nil {
+		in, out := &in.IgnoredManagers, &out.IgnoredManagers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Drift.
+func (in *Drift) DeepCopy() *Drift {
+	if in == nil {
+		return nil
+	}
+	out := new(Drift)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EffectiveSpecStatus) DeepCopyInto(out *EffectiveSpecStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EffectiveSpecStatus.
+func (in *EffectiveSpecStatus) DeepCopy() *EffectiveSpecStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EffectiveSpecStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FieldMergeStrategy) DeepCopyInto(out *FieldMergeStrategy) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldMergeStrategy.
+func (in *FieldMergeStrategy) DeepCopy() *FieldMergeStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldMergeStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeydbSpec) DeepCopyInto(out *KeydbSpec) {
 	*out = *in
@@ -36,6 +193,16 @@ func (in *KeydbSpec) DeepCopyInto(out *KeydbSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.KeydbNetpolIngressPeers != nil {
+		in, out := &in.KeydbNetpolIngressPeers, &out.KeydbNetpolIngressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeydbNetpolEgressPeers != nil {
+		in, out := &in.KeydbNetpolEgressPeers, &out.KeydbNetpolEgressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
 	if in.KeydbNetpolIngressExtraPorts != nil {
 		in, out := &in.KeydbNetpolIngressExtraPorts, &out.KeydbNetpolIngressExtraPorts
 		*out = make([]NetworkPolicyExtraPort, len(*in))
@@ -85,6 +252,143 @@ func (in *LMSMoodle) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LMSMoodleClaim) DeepCopyInto(out *LMSMoodleClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMSMoodleClaim.
+func (in *LMSMoodleClaim) DeepCopy() *LMSMoodleClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(LMSMoodleClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LMSMoodleClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LMSMoodleClaimList) DeepCopyInto(out *LMSMoodleClaimList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LMSMoodleClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMSMoodleClaimList.
+func (in *LMSMoodleClaimList) DeepCopy() *LMSMoodleClaimList {
+	if in == nil {
+		return nil
+	}
+	out := new(LMSMoodleClaimList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LMSMoodleClaimList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LMSMoodleClaimOverrides) DeepCopyInto(out *LMSMoodleClaimOverrides) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMSMoodleClaimOverrides.
+func (in *LMSMoodleClaimOverrides) DeepCopy() *LMSMoodleClaimOverrides {
+	if in == nil {
+		return nil
+	}
+	out := new(LMSMoodleClaimOverrides)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LMSMoodleClaimPolicy) DeepCopyInto(out *LMSMoodleClaimPolicy) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowedOverrides != nil {
+		in, out := &in.AllowedOverrides, &out.AllowedOverrides
+		*out = make([]LMSMoodleClaimOverride, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMSMoodleClaimPolicy.
+func (in *LMSMoodleClaimPolicy) DeepCopy() *LMSMoodleClaimPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(LMSMoodleClaimPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LMSMoodleClaimSpec) DeepCopyInto(out *LMSMoodleClaimSpec) {
+	*out = *in
+	out.Overrides = in.Overrides
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMSMoodleClaimSpec.
+func (in *LMSMoodleClaimSpec) DeepCopy() *LMSMoodleClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LMSMoodleClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LMSMoodleClaimStatus) DeepCopyInto(out *LMSMoodleClaimStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMSMoodleClaimStatus.
+func (in *LMSMoodleClaimStatus) DeepCopy() *LMSMoodleClaimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LMSMoodleClaimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LMSMoodleList) DeepCopyInto(out *LMSMoodleList) {
 	*out = *in
@@ -120,6 +424,12 @@ func (in *LMSMoodleList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LMSMoodleSpec) DeepCopyInto(out *LMSMoodleSpec) {
 	*out = *in
+	out.ComponentStates = in.ComponentStates
+	if in.UnsetFields != nil {
+		in, out := &in.UnsetFields, &out.UnsetFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.LMSMoodleTemplateSpec.DeepCopyInto(&out.LMSMoodleTemplateSpec)
 }
 
@@ -143,6 +453,40 @@ func (in *LMSMoodleStatus) DeepCopyInto(out *LMSMoodleStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ComponentStates != nil {
+		in, out := &in.ComponentStates, &out.ComponentStates
+		*out = make(map[string]ComponentStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	in.Timeline.DeepCopyInto(&out.Timeline)
+	if in.LastTimeline != nil {
+		in, out := &in.LastTimeline, &out.LastTimeline
+		*out = new(Timeline)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = make(map[string]NotificationStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]StateTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EffectiveSpecs != nil {
+		in, out := &in.EffectiveSpecs, &out.EffectiveSpecs
+		*out = make(map[string]EffectiveSpecStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMSMoodleStatus.
@@ -221,6 +565,32 @@ func (in *LMSMoodleTemplateSpec) DeepCopyInto(out *LMSMoodleTemplateSpec) {
 	in.PostgresSpec.DeepCopyInto(&out.PostgresSpec)
 	in.NfsSpec.DeepCopyInto(&out.NfsSpec)
 	in.KeydbSpec.DeepCopyInto(&out.KeydbSpec)
+	in.ReadinessTimeouts.DeepCopyInto(&out.ReadinessTimeouts)
+	in.Notifier.DeepCopyInto(&out.Notifier)
+	out.StateHistory = in.StateHistory
+	in.Drift.DeepCopyInto(&out.Drift)
+	in.NamespaceNetworkPolicy.DeepCopyInto(&out.NamespaceNetworkPolicy)
+	if in.NamespaceQuota != nil {
+		in, out := &in.NamespaceQuota, &out.NamespaceQuota
+		*out = new(NamespaceQuota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceLimitRange != nil {
+		in, out := &in.NamespaceLimitRange, &out.NamespaceLimitRange
+		*out = new(NamespaceLimitRange)
+		(*in).DeepCopyInto(*out)
+	}
+	in.NamespaceMetadata.DeepCopyInto(&out.NamespaceMetadata)
+	in.Claims.DeepCopyInto(&out.Claims)
+	in.OverridePolicy.DeepCopyInto(&out.OverridePolicy)
+	if in.MergeStrategies != nil {
+		in, out := &in.MergeStrategies, &out.MergeStrategies
+		*out = make([]FieldMergeStrategy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.MetadataPropagation.DeepCopyInto(&out.MetadataPropagation)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMSMoodleTemplateSpec.
@@ -248,6 +618,35 @@ func (in *LMSMoodleTemplateStatus) DeepCopy() *LMSMoodleTemplateStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataPropagation) DeepCopyInto(out *MetadataPropagation) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]PropagationRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make([]PropagationRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataPropagation.
+func (in *MetadataPropagation) DeepCopy() *MetadataPropagation {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataPropagation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MoodleConfigProperty) DeepCopyInto(out *MoodleConfigProperty) {
 	*out = *in
@@ -288,6 +687,16 @@ func (in *MoodleSpec) DeepCopyInto(out *MoodleSpec) {
 		}
 	}
 	out.MoodleConfigAdditionalCfg = in.MoodleConfigAdditionalCfg
+	if in.MoodleNetpolIngressPeers != nil {
+		in, out := &in.MoodleNetpolIngressPeers, &out.MoodleNetpolIngressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
+	if in.MoodleNetpolEgressPeers != nil {
+		in, out := &in.MoodleNetpolEgressPeers, &out.MoodleNetpolEgressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
 	if in.MoodleNetpolIngressExtraPorts != nil {
 		in, out := &in.MoodleNetpolIngressExtraPorts, &out.MoodleNetpolIngressExtraPorts
 		*out = make([]NetworkPolicyExtraPort, len(*in))
@@ -305,6 +714,16 @@ func (in *MoodleSpec) DeepCopyInto(out *MoodleSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.NginxNetpolIngressPeers != nil {
+		in, out := &in.NginxNetpolIngressPeers, &out.NginxNetpolIngressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
+	if in.NginxNetpolEgressPeers != nil {
+		in, out := &in.NginxNetpolEgressPeers, &out.NginxNetpolEgressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
 	if in.NginxNetpolIngressExtraPorts != nil {
 		in, out := &in.NginxNetpolIngressExtraPorts, &out.NginxNetpolIngressExtraPorts
 		*out = make([]NetworkPolicyExtraPort, len(*in))
@@ -322,6 +741,16 @@ func (in *MoodleSpec) DeepCopyInto(out *MoodleSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PhpFpmNetpolIngressPeers != nil {
+		in, out := &in.PhpFpmNetpolIngressPeers, &out.PhpFpmNetpolIngressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
+	if in.PhpFpmNetpolEgressPeers != nil {
+		in, out := &in.PhpFpmNetpolEgressPeers, &out.PhpFpmNetpolEgressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
 	if in.PhpFpmNetpolIngressExtraPorts != nil {
 		in, out := &in.PhpFpmNetpolIngressExtraPorts, &out.PhpFpmNetpolIngressExtraPorts
 		*out = make([]NetworkPolicyExtraPort, len(*in))
@@ -347,9 +776,224 @@ func (in *MoodleSpec) DeepCopy() *MoodleSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NetworkPolicyExtraPort) DeepCopyInto(out *NetworkPolicyExtraPort) {
+func (in *NamespaceLimitRange) DeepCopyInto(out *NamespaceLimitRange) {
 	*out = *in
-}
+	if in.DefaultRequest != nil {
+		in, out := &in.DefaultRequest, &out.DefaultRequest
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Default != nil {
+		in, out := &in.Default, &out.Default
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Min != nil {
+		in, out := &in.Min, &out.Min
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceLimitRange.
+func (in *NamespaceLimitRange) DeepCopy() *NamespaceLimitRange {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceLimitRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceMetadata) DeepCopyInto(out *NamespaceMetadata) {
+	*out = *in
+	if in.PodSecurity != nil {
+		in, out := &in.PodSecurity, &out.PodSecurity
+		*out = new(NamespacePodSecurity)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceMetadata.
+func (in *NamespaceMetadata) DeepCopy() *NamespaceMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceNetworkPolicy) DeepCopyInto(out *NamespaceNetworkPolicy) {
+	*out = *in
+	if in.AllowSameNamespace != nil {
+		in, out := &in.AllowSameNamespace, &out.AllowSameNamespace
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AllowDNS != nil {
+		in, out := &in.AllowDNS, &out.AllowDNS
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IngressController != nil {
+		in, out := &in.IngressController, &out.IngressController
+		*out = new(NamespaceNetworkPolicyIngress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(NamespaceNetworkPolicyIngress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExtraEgress != nil {
+		in, out := &in.ExtraEgress, &out.ExtraEgress
+		*out = make([]NamespaceNetworkPolicyEgress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceNetworkPolicy.
+func (in *NamespaceNetworkPolicy) DeepCopy() *NamespaceNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceNetworkPolicyEgress) DeepCopyInto(out *NamespaceNetworkPolicyEgress) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]NetworkPolicyExtraPort, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceNetworkPolicyEgress.
+func (in *NamespaceNetworkPolicyEgress) DeepCopy() *NamespaceNetworkPolicyEgress {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceNetworkPolicyEgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceNetworkPolicyIngress) DeepCopyInto(out *NamespaceNetworkPolicyIngress) {
+	*out = *in
+	if in.NamespaceLabels != nil {
+		in, out := &in.NamespaceLabels, &out.NamespaceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodLabels != nil {
+		in, out := &in.PodLabels, &out.PodLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]NetworkPolicyExtraPort, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceNetworkPolicyIngress.
+func (in *NamespaceNetworkPolicyIngress) DeepCopy() *NamespaceNetworkPolicyIngress {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceNetworkPolicyIngress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacePodSecurity) DeepCopyInto(out *NamespacePodSecurity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacePodSecurity.
+func (in *NamespacePodSecurity) DeepCopy() *NamespacePodSecurity {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacePodSecurity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceQuota) DeepCopyInto(out *NamespaceQuota) {
+	*out = *in
+	if in.Hard != nil {
+		in, out := &in.Hard, &out.Hard
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.HeadroomPercent != nil {
+		in, out := &in.HeadroomPercent, &out.HeadroomPercent
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceQuota.
+func (in *NamespaceQuota) DeepCopy() *NamespaceQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicyExtraPort) DeepCopyInto(out *NetworkPolicyExtraPort) {
+	*out = *in
+}
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicyExtraPort.
 func (in *NetworkPolicyExtraPort) DeepCopy() *NetworkPolicyExtraPort {
@@ -361,6 +1005,37 @@ func (in *NetworkPolicyExtraPort) DeepCopy() *NetworkPolicyExtraPort {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicyIPBlock) DeepCopyInto(out *NetworkPolicyIPBlock) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicyIPBlock.
+func (in *NetworkPolicyIPBlock) DeepCopy() *NetworkPolicyIPBlock {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkPolicyIPBlock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicyPeer) DeepCopyInto(out *NetworkPolicyPeer) {
+	*out = *in
+	out.IPBlock = in.IPBlock
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicyPeer.
+func (in *NetworkPolicyPeer) DeepCopy() *NetworkPolicyPeer {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkPolicyPeer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NfsSpec) DeepCopyInto(out *NfsSpec) {
 	*out = *in
@@ -371,6 +1046,16 @@ func (in *NfsSpec) DeepCopyInto(out *NfsSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.GaneshaNetpolIngressPeers != nil {
+		in, out := &in.GaneshaNetpolIngressPeers, &out.GaneshaNetpolIngressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
+	if in.GaneshaNetpolEgressPeers != nil {
+		in, out := &in.GaneshaNetpolEgressPeers, &out.GaneshaNetpolEgressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
 	if in.GaneshaNetpolIngressExtraPorts != nil {
 		in, out := &in.GaneshaNetpolIngressExtraPorts, &out.GaneshaNetpolIngressExtraPorts
 		*out = make([]NetworkPolicyExtraPort, len(*in))
@@ -393,6 +1078,110 @@ func (in *NfsSpec) DeepCopy() *NfsSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationStatus) DeepCopyInto(out *NotificationStatus) {
+	*out = *in
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationStatus.
+func (in *NotificationStatus) DeepCopy() *NotificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Notifier) DeepCopyInto(out *Notifier) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]NotifierEndpoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Notifier.
+func (in *Notifier) DeepCopy() *Notifier {
+	if in == nil {
+		return nil
+	}
+	out := new(Notifier)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierEndpoint) DeepCopyInto(out *NotifierEndpoint) {
+	*out = *in
+	if in.States != nil {
+		in, out := &in.States, &out.States
+		*out = make([]NotifierState, len(*in))
+		copy(*out, *in)
+	}
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(NotifierSigning)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierEndpoint.
+func (in *NotifierEndpoint) DeepCopy() *NotifierEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierSigning) DeepCopyInto(out *NotifierSigning) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierSigning.
+func (in *NotifierSigning) DeepCopy() *NotifierSigning {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierSigning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OverridePolicy) DeepCopyInto(out *OverridePolicy) {
+	*out = *in
+	if in.Allowed != nil {
+		in, out := &in.Allowed, &out.Allowed
+		*out = make([]AllowedOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OverridePolicy.
+func (in *OverridePolicy) DeepCopy() *OverridePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(OverridePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostgresSpec) DeepCopyInto(out *PostgresSpec) {
 	*out = *in
@@ -403,6 +1192,16 @@ func (in *PostgresSpec) DeepCopyInto(out *PostgresSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PostgresNetpolIngressPeers != nil {
+		in, out := &in.PostgresNetpolIngressPeers, &out.PostgresNetpolIngressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostgresNetpolEgressPeers != nil {
+		in, out := &in.PostgresNetpolEgressPeers, &out.PostgresNetpolEgressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
 	if in.PostgresNetpolIngressExtraPorts != nil {
 		in, out := &in.PostgresNetpolIngressExtraPorts, &out.PostgresNetpolIngressExtraPorts
 		*out = make([]NetworkPolicyExtraPort, len(*in))
@@ -427,6 +1226,16 @@ func (in *PostgresSpec) DeepCopyInto(out *PostgresSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PgbouncerNetpolIngressPeers != nil {
+		in, out := &in.PgbouncerNetpolIngressPeers, &out.PgbouncerNetpolIngressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
+	if in.PgbouncerNetpolEgressPeers != nil {
+		in, out := &in.PgbouncerNetpolEgressPeers, &out.PgbouncerNetpolEgressPeers
+		*out = make([]NetworkPolicyPeer, len(*in))
+		copy(*out, *in)
+	}
 	if in.PgbouncerNetpolIngressExtraPorts != nil {
 		in, out := &in.PgbouncerNetpolIngressExtraPorts, &out.PgbouncerNetpolIngressExtraPorts
 		*out = make([]NetworkPolicyExtraPort, len(*in))
@@ -456,15 +1265,86 @@ func (in *PostgresSpec) DeepCopy() *PostgresSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PropagationRule) DeepCopyInto(out *PropagationRule) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]PropagationTarget, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PropagationRule.
+func (in *PropagationRule) DeepCopy() *PropagationRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PropagationRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessTimeouts) DeepCopyInto(out *ReadinessTimeouts) {
+	*out = *in
+	if in.Moodle != nil {
+		in, out := &in.Moodle, &out.Moodle
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Postgres != nil {
+		in, out := &in.Postgres, &out.Postgres
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Keydb != nil {
+		in, out := &in.Keydb, &out.Keydb
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Nfs != nil {
+		in, out := &in.Nfs, &out.Nfs
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessTimeouts.
+func (in *ReadinessTimeouts) DeepCopy() *ReadinessTimeouts {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessTimeouts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RoutineStatusCrNotify) DeepCopyInto(out *RoutineStatusCrNotify) {
 	*out = *in
 	if in.StatusCode != nil {
 		in, out := &in.StatusCode, &out.StatusCode
-		*out = make([]int8, len(*in))
+		*out = make([]int32, len(*in))
 		copy(*out, *in)
 	}
-	out.Headers = in.Headers
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(RoutineStatusCrNotifyHeaders, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HeadersFrom != nil {
+		in, out := &in.HeadersFrom, &out.HeadersFrom
+		*out = make([]RoutineStatusCrNotifyHeaderFrom, len(*in))
+		copy(*out, *in)
+	}
+	if in.JwtSecretRef != nil {
+		in, out := &in.JwtSecretRef, &out.JwtSecretRef
+		*out = new(SecretKeySelector)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoutineStatusCrNotify.
@@ -478,16 +1358,114 @@ func (in *RoutineStatusCrNotify) DeepCopy() *RoutineStatusCrNotify {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RoutineStatusCrNotifyHeaders) DeepCopyInto(out *RoutineStatusCrNotifyHeaders) {
+func (in *RoutineStatusCrNotifyHeaderFrom) DeepCopyInto(out *RoutineStatusCrNotifyHeaderFrom) {
 	*out = *in
+	out.SecretKeyRef = in.SecretKeyRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoutineStatusCrNotifyHeaderFrom.
+func (in *RoutineStatusCrNotifyHeaderFrom) DeepCopy() *RoutineStatusCrNotifyHeaderFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(RoutineStatusCrNotifyHeaderFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in RoutineStatusCrNotifyHeaders) DeepCopyInto(out *RoutineStatusCrNotifyHeaders) {
+	{
+		in := &in
+		*out = make(RoutineStatusCrNotifyHeaders, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoutineStatusCrNotifyHeaders.
-func (in *RoutineStatusCrNotifyHeaders) DeepCopy() *RoutineStatusCrNotifyHeaders {
+func (in RoutineStatusCrNotifyHeaders) DeepCopy() RoutineStatusCrNotifyHeaders {
 	if in == nil {
 		return nil
 	}
 	out := new(RoutineStatusCrNotifyHeaders)
 	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeySelector) DeepCopyInto(out *SecretKeySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeySelector.
+func (in *SecretKeySelector) DeepCopy() *SecretKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateHistory) DeepCopyInto(out *StateHistory) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateHistory.
+func (in *StateHistory) DeepCopy() *StateHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(StateHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateTransition) DeepCopyInto(out *StateTransition) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateTransition.
+func (in *StateTransition) DeepCopy() *StateTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(StateTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Timeline) DeepCopyInto(out *Timeline) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make(map[string]ComponentTimeline, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Timeline.
+func (in *Timeline) DeepCopy() *Timeline {
+	if in == nil {
+		return nil
+	}
+	out := new(Timeline)
+	in.DeepCopyInto(out)
 	return out
 }
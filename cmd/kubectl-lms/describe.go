@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	lmscontroller "github.com/krestomatio/lms-moodle-operator/internal/controller/lms"
+)
+
+// describe prints LMSMoodle components, conditions and effective specs
+func describe(ctx context.Context, c client.Client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	name, err := nameArg(fs, args)
+	if err != nil {
+		return err
+	}
+	lmsMoodle := &lmsv1alpha1.LMSMoodle{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, lmsMoodle); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", lmsMoodle.Name)
+	fmt.Fprintf(w, "Template:\t%s\n", lmsMoodle.Spec.LMSMoodleTemplateName)
+	fmt.Fprintf(w, "Desired State:\t%s\n", valueOrNone(lmsMoodle.Spec.DesiredState))
+	fmt.Fprintf(w, "State:\t%s\n", stateColumn(lmsMoodle))
+	fmt.Fprintf(w, "URL:\t%s\n", valueOrNone(lmsMoodle.Status.Url))
+	fmt.Fprintf(w, "Release:\t%s\n", valueOrNone(lmsMoodle.Status.Release))
+	fmt.Fprintf(w, "Registered Users:\t%d\n", lmsMoodle.Status.RegisteredUsers)
+	fmt.Fprintf(w, "Storage GB:\t%s\n", valueOrNone(lmsMoodle.Status.StorageGb))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "\nComponents:")
+	w = tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  COMPONENT\tSTATE\tREADY\tREASON\tMESSAGE")
+	for _, component := range sortedKeys(lmsMoodle.Status.ComponentStates) {
+		status := lmsMoodle.Status.ComponentStates[component]
+		fmt.Fprintf(w, "  %s\t%s\t%t\t%s\t%s\n", component, valueOrNone(status.State), status.Ready, status.Reason, status.Message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "\nConditions:")
+	w = tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  TYPE\tSTATUS\tREASON\tSINCE\tMESSAGE")
+	for _, condition := range lmsMoodle.Status.Conditions {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason,
+			duration.HumanDuration(time.Since(condition.LastTransitionTime.Time)), condition.Message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "\nEffective Specs:")
+	effectiveSpecs, err := getEffectiveSpecs(ctx, c, lmsMoodle)
+	if err != nil {
+		return err
+	}
+	for _, component := range sortedKeys(lmsMoodle.Status.EffectiveSpecs) {
+		effectiveSpec := lmsMoodle.Status.EffectiveSpecs[component]
+		fmt.Fprintf(out, "  %s (hash %s, fields: %d site, %d template, %d operator):\n", component, effectiveSpec.Hash,
+			effectiveSpec.SiteFields, effectiveSpec.TemplateFields, effectiveSpec.OperatorFields)
+		if spec, found := effectiveSpecs[component+".yaml"]; found {
+			fmt.Fprintln(out, indent(spec, "    "))
+		} else {
+			fmt.Fprintln(out, "    <not published>")
+		}
+	}
+	return nil
+}
+
+// getEffectiveSpecs returns combined dependant specs, as published in LMSMoodle namespace
+func getEffectiveSpecs(ctx context.Context, c client.Client, lmsMoodle *lmsv1alpha1.LMSMoodle) (map[string]string, error) {
+	namespace := lmsMoodle.Status.ComponentStates["moodle"].Namespace
+	if namespace == "" {
+		return nil, nil
+	}
+	configMap := &corev1.ConfigMap{}
+	configMapName := lmscontroller.EffectiveSpecsConfigMapNameOf(lmsMoodle.Spec.TargetNamespace, lmsMoodle.Status.Names.Base)
+	if err := c.Get(ctx, types.NamespacedName{Name: configMapName, Namespace: namespace}, configMap); err != nil {
+		if errors.IsNotFound(err) || errors.IsForbidden(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return configMap.Data, nil
+}
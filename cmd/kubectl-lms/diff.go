@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	lmscontroller "github.com/krestomatio/lms-moodle-operator/internal/controller/lms"
+)
+
+// diffTemplate prints what would change in LMSMoodle resources if its template were
+// replaced by the one in a file. Both sides are rendered with the reconciler merge code and
+// the operator naming, propagation and namespace policies
+func diffTemplate(ctx context.Context, c client.Client, args []string, out io.Writer) error {
+	var templateFile string
+	fs := flag.NewFlagSet("diff-template", flag.ContinueOnError)
+	fs.StringVar(&templateFile, "template", "", "The LMSMoodleTemplate yaml file to compare with.")
+	policyFlags := bindPolicyFlags(fs)
+	name, err := nameArg(fs, args)
+	if err != nil {
+		return err
+	}
+	if templateFile == "" {
+		return fmt.Errorf("diff-template: --template is required")
+	}
+
+	lmsMoodle := &unstructured.Unstructured{}
+	lmsMoodle.SetGroupVersionKind(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, lmsMoodle); err != nil {
+		return err
+	}
+	templateName, _, _ := unstructured.NestedString(lmsMoodle.Object, "spec", "lmsMoodleTemplateName")
+	currentTemplate := &unstructured.Unstructured{}
+	currentTemplate.SetGroupVersionKind(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleTemplate"))
+	if err := c.Get(ctx, types.NamespacedName{Name: templateName}, currentTemplate); err != nil {
+		return err
+	}
+
+	templateYaml, err := os.ReadFile(templateFile)
+	if err != nil {
+		return err
+	}
+	newTemplate := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(templateYaml, &newTemplate.Object); err != nil {
+		return fmt.Errorf("unable to decode %s: %w", templateFile, err)
+	}
+	if newTemplate.GroupVersionKind() != lmsv1alpha1.GroupVersion.WithKind("LMSMoodleTemplate") {
+		return fmt.Errorf("%s is not a LMSMoodleTemplate", templateFile)
+	}
+	// compare as if it were the template in use
+	newTemplate.SetName(templateName)
+
+	namingPolicy, propagationPolicy, namespacePolicy, err := policyFlags.policies(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	currentObjs, err := renderMaps(ctx, lmsMoodle, currentTemplate, namingPolicy, propagationPolicy, namespacePolicy)
+	if err != nil {
+		return err
+	}
+	newObjs, err := renderMaps(ctx, lmsMoodle, newTemplate, namingPolicy, propagationPolicy, namespacePolicy)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, key := range sortedKeys(mergeKeys(currentObjs, newObjs)) {
+		currentObj, currentFound := currentObjs[key]
+		newObj, newFound := newObjs[key]
+		switch {
+		case !currentFound:
+			fmt.Fprintf(out, "+ %s (created)\n", key)
+		case !newFound:
+			fmt.Fprintf(out, "- %s (deleted)\n", key)
+		default:
+			currentFields, newFields := map[string]string{}, map[string]string{}
+			flatten(currentFields, "", currentObj)
+			flatten(newFields, "", newObj)
+			var lines []string
+			for _, path := range sortedKeys(mergeKeys(currentFields, newFields)) {
+				currentValue, currentValueFound := currentFields[path]
+				newValue, newValueFound := newFields[path]
+				switch {
+				case !currentValueFound:
+					lines = append(lines, fmt.Sprintf("  + %s: %s", path, newValue))
+				case !newValueFound:
+					lines = append(lines, fmt.Sprintf("  - %s: %s", path, currentValue))
+				case currentValue != newValue:
+					lines = append(lines, fmt.Sprintf("  ~ %s: %s -> %s", path, currentValue, newValue))
+				}
+			}
+			if len(lines) == 0 {
+				continue
+			}
+			fmt.Fprintf(out, "~ %s\n", key)
+			for _, line := range lines {
+				fmt.Fprintln(out, line)
+			}
+		}
+		changed = true
+	}
+	if !changed {
+		fmt.Fprintf(out, "lmsmoodle/%s: no changes\n", name)
+	}
+	return nil
+}
+
+// renderMaps renders LMSMoodle resources with a template and policies, by kind and name
+func renderMaps(ctx context.Context, lmsMoodle *unstructured.Unstructured, lmsMoodleTemplate *unstructured.Unstructured, namingPolicy lmscontroller.NamingPolicy, propagationPolicy lmscontroller.PropagationPolicy, namespacePolicy lmscontroller.NamespacePolicy) (map[string]map[string]interface{}, error) {
+	objs, err := lmscontroller.Render(ctx, lmsMoodle, []*unstructured.Unstructured{lmsMoodleTemplate},
+		namingPolicy, propagationPolicy, namespacePolicy, lmscontroller.MoodleGVK, lmscontroller.NfsGVK, lmscontroller.KeydbGVK, lmscontroller.PostgresGVK)
+	if err != nil {
+		return nil, err
+	}
+	objMaps := make(map[string]map[string]interface{}, len(objs))
+	for _, obj := range objs {
+		objMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		key := obj.GetObjectKind().GroupVersionKind().Kind + "/" + obj.GetName()
+		objMaps[key] = objMap
+	}
+	return objMaps, nil
+}
+
+// flatten sets fields of a nested map by dot separated path, with values formatted
+func flatten(fields map[string]string, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flatten(fields, path, nested)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			flatten(fields, fmt.Sprintf("%s[%d]", prefix, i), nested)
+		}
+	case string:
+		fields[prefix] = fmt.Sprintf("%q", v)
+	default:
+		fields[prefix] = fmt.Sprintf("%v", v)
+	}
+}
+
+func mergeKeys[V any](a, b map[string]V) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for key := range a {
+		keys[key] = struct{}{}
+	}
+	for key := range b {
+		keys[key] = struct{}{}
+	}
+	return keys
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	lmscontroller "github.com/krestomatio/lms-moodle-operator/internal/controller/lms"
+)
+
+var _ = Describe("diff-template", func() {
+	ctx := context.TODO()
+
+	operatorDeployment := func(args ...string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "lms-moodle-operator-controller-manager",
+				Namespace: defaultOperatorNamespace,
+				Labels:    map[string]string{"control-plane": "controller-manager"},
+			},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: operatorContainerName, Command: []string{"/manager"}, Args: args}},
+			}}},
+		}
+	}
+
+	lmsMoodleTemplate := func(fullname string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "lms.krestomat.io/v1alpha1",
+			"kind":       "LMSMoodleTemplate",
+			"metadata":   map[string]interface{}{"name": "template"},
+			"spec": map[string]interface{}{
+				"moodleSpec": map[string]interface{}{"moodleNewInstanceFullname": fullname},
+			},
+		}}
+	}
+
+	newClient := func(objs ...client.Object) client.Client {
+		lmsMoodle := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "lms.krestomat.io/v1alpha1",
+			"kind":       "LMSMoodle",
+			"metadata":   map[string]interface{}{"name": "site"},
+			"spec":       map[string]interface{}{"lmsMoodleTemplateName": "template"},
+		}}
+		return fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(append(objs, lmsMoodle, lmsMoodleTemplate("Current"))...).Build()
+	}
+
+	templateFile := func() string {
+		templateYaml, err := yaml.Marshal(lmsMoodleTemplate("New").Object)
+		Expect(err).NotTo(HaveOccurred())
+		file := filepath.Join(GinkgoT().TempDir(), "template.yaml")
+		Expect(os.WriteFile(file, templateYaml, 0o600)).To(Succeed())
+		return file
+	}
+
+	It("should keep only policy flags of operator arguments", func() {
+		var namingPolicy lmscontroller.NamingPolicy
+		fs := flag.NewFlagSet("operator", flag.ContinueOnError)
+		lmscontroller.BindNamingPolicyFlags(fs, &namingPolicy)
+
+		args := policyArgs(fs, []string{"/manager", "--leader-elect", "--name-prefix", "acme-",
+			"--metrics-bind-address=:8443", "--name-hash-suffix", "--name-max-length=30", "--zap-devel"})
+		Expect(args).To(Equal([]string{"--name-prefix", "acme-", "--name-hash-suffix", "--name-max-length=30"}))
+	})
+
+	It("should read policies from the operator deployment", func() {
+		c := newClient(operatorDeployment("--leader-elect", "--name-prefix=acme-", "--name-max-length", "30",
+			"--propagate-label=prefix:example.com/=Namespace"))
+
+		namingPolicy, propagationPolicy, _, err := operatorPolicies(ctx, c, defaultOperatorNamespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(namingPolicy.Prefix).To(Equal("acme-"))
+		Expect(namingPolicy.MaxLength).To(Equal(30))
+		Expect(propagationPolicy.Labels).To(HaveLen(1))
+		Expect(propagationPolicy.Labels[0].Targets).To(Equal([]lmsv1alpha1.PropagationTarget{lmsv1alpha1.NamespacePropagationTarget}))
+	})
+
+	It("should fail if the operator deployment is not found", func() {
+		_, _, _, err := operatorPolicies(ctx, newClient(), "other")
+		Expect(err).To(MatchError(ContainSubstring("--operator-namespace")))
+	})
+
+	It("should render with the operator naming policy", func() {
+		c := newClient(operatorDeployment("--name-prefix=acme-"))
+
+		var out bytes.Buffer
+		Expect(diffTemplate(ctx, c, []string{"site", "--template", templateFile()}, &out)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("~ Moodle/acme-site\n"))
+		Expect(out.String()).To(ContainSubstring(`~ spec.moodleNewInstanceFullname: "Current" -> "New"`))
+	})
+
+	It("should render with policy flags, if set, instead of operator ones", func() {
+		c := newClient()
+
+		var out bytes.Buffer
+		Expect(diffTemplate(ctx, c, []string{"site", "--template", templateFile(), "--name-prefix", "team-"}, &out)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("~ Moodle/team-site\n"))
+	})
+})
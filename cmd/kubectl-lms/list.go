@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	lmscontroller "github.com/krestomatio/lms-moodle-operator/internal/controller/lms"
+)
+
+// list prints LMSMoodles with their state, usage, release and template
+func list(ctx context.Context, c client.Client, args []string, out io.Writer) error {
+	var selector string
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.StringVar(&selector, "l", "", "Label selector to filter LMSMoodles.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		return err
+	}
+
+	lmsMoodleList := &lmsv1alpha1.LMSMoodleList{}
+	if err := c.List(ctx, lmsMoodleList, client.MatchingLabelsSelector{Selector: labelSelector}); err != nil {
+		return err
+	}
+	sort.Slice(lmsMoodleList.Items, func(i, j int) bool {
+		return lmsMoodleList.Items[i].Name < lmsMoodleList.Items[j].Name
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATE\tUSERS\tGB\tRELEASE\tTEMPLATE\tAGE")
+	for _, lmsMoodle := range lmsMoodleList.Items {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			lmsMoodle.Name,
+			stateColumn(&lmsMoodle),
+			lmsMoodle.Status.RegisteredUsers,
+			valueOrNone(lmsMoodle.Status.StorageGb),
+			valueOrNone(lmsMoodle.Status.Release),
+			lmsMoodle.Spec.LMSMoodleTemplateName,
+			duration.HumanDuration(time.Since(lmsMoodle.CreationTimestamp.Time)),
+		)
+	}
+	return w.Flush()
+}
+
+// stateColumn returns LMSMoodle state, noting whether its reconciliation is paused
+func stateColumn(lmsMoodle *lmsv1alpha1.LMSMoodle) string {
+	state := valueOrNone(lmsMoodle.Status.State)
+	if lmsMoodle.IsPaused() && state != lmsv1alpha1.PausedState {
+		state += " (paused)"
+	}
+	if meta.IsStatusConditionTrue(lmsMoodle.Status.Conditions, lmscontroller.DegradedConditionType) {
+		state += " (degraded)"
+	}
+	return state
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+func indent(text string, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
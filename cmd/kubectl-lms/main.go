@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-lms is a kubectl plugin for day-to-day LMSMoodle operations.
+// Install it by placing the binary in PATH and run it as `kubectl lms`
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(lmsv1alpha1.AddToScheme(scheme))
+}
+
+// command is a kubectl lms subcommand
+type command struct {
+	usage string
+	run   func(ctx context.Context, c client.Client, args []string, out io.Writer) error
+}
+
+var commands = map[string]command{
+	"list":          {"list [-l selector]", list},
+	"describe":      {"describe NAME", describe},
+	"suspend":       {"suspend NAME", suspend},
+	"resume":        {"resume NAME", resume},
+	"pause":         {"pause NAME", pause},
+	"unpause":       {"unpause NAME", unpause},
+	"diff-template": {"diff-template NAME --template FILE [--operator-namespace NAMESPACE]", diffTemplate},
+	"wait":          {"wait NAME --for=ready|suspended [--timeout DURATION]", waitForState},
+}
+
+var commandOrder = []string{"list", "describe", "suspend", "resume", "pause", "unpause", "diff-template", "wait"}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: kubectl lms [--kubeconfig FILE] COMMAND")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(1)
+	}
+	cmd, found := commands[flag.Arg(0)]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(1)
+	}
+
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(ctrl.SetupSignalHandler())
+	defer cancel()
+	if err := cmd.run(ctx, c, flag.Args()[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		cancel()
+		os.Exit(1)
+	}
+}
+
+// nameArg parses subcommand flags and returns the LMSMoodle name argument
+func nameArg(fs *flag.FlagSet, args []string) (string, error) {
+	// allow flags after name, as kubectl does
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		if err := fs.Parse(args[1:]); err != nil {
+			return "", err
+		}
+		if fs.NArg() > 0 {
+			return "", fmt.Errorf("%s: unexpected arguments %v", fs.Name(), fs.Args())
+		}
+		return args[0], nil
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%s: a LMSMoodle name is required", fs.Name())
+	}
+	return fs.Arg(0), nil
+}
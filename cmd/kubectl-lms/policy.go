@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lmscontroller "github.com/krestomatio/lms-moodle-operator/internal/controller/lms"
+)
+
+const (
+	// defaultOperatorNamespace is the namespace the operator is deployed to by default
+	defaultOperatorNamespace = "lms-moodle-operator-system"
+	// operatorContainerName is the name of the operator container in its deployment
+	operatorContainerName = "manager"
+)
+
+// operatorSelector selects the operator deployment
+var operatorSelector = client.MatchingLabels{"control-plane": "controller-manager"}
+
+// policyFlags are the naming, propagation and namespace policy flags of a subcommand. Unless any of
+// them is set, policies are the ones the operator runs with
+type policyFlags struct {
+	operatorNamespace string
+	namingPolicy      lmscontroller.NamingPolicy
+	propagationPolicy lmscontroller.PropagationPolicy
+	namespacePolicy   lmscontroller.NamespacePolicy
+	fs                *flag.FlagSet
+}
+
+// bindPolicyFlags binds the operator namespace flag and the operator policy flags
+func bindPolicyFlags(fs *flag.FlagSet) *policyFlags {
+	p := &policyFlags{fs: fs}
+	fs.StringVar(&p.operatorNamespace, "operator-namespace", defaultOperatorNamespace,
+		"The namespace of the operator deployment, whose naming, propagation and namespace policies are used, "+
+			"unless policy flags are set.")
+	lmscontroller.BindNamingPolicyFlags(fs, &p.namingPolicy)
+	lmscontroller.BindPropagationPolicyFlags(fs, &p.propagationPolicy)
+	lmscontroller.BindNamespacePolicyFlags(fs, &p.namespacePolicy)
+	return p
+}
+
+// policies returns the policies set by flags, if any, or else the operator ones
+func (p *policyFlags) policies(ctx context.Context, c client.Client) (lmscontroller.NamingPolicy, lmscontroller.PropagationPolicy, lmscontroller.NamespacePolicy, error) {
+	policyFlagSet := false
+	p.fs.Visit(func(f *flag.Flag) {
+		if isPolicyFlag(f.Name) {
+			policyFlagSet = true
+		}
+	})
+	if policyFlagSet {
+		return p.namingPolicy, p.propagationPolicy, p.namespacePolicy, nil
+	}
+	return operatorPolicies(ctx, c, p.operatorNamespace)
+}
+
+// operatorPolicies returns the policies the operator deployment in a namespace runs with,
+// parsed from its container arguments
+func operatorPolicies(ctx context.Context, c client.Client, namespace string) (lmscontroller.NamingPolicy, lmscontroller.PropagationPolicy, lmscontroller.NamespacePolicy, error) {
+	var namingPolicy lmscontroller.NamingPolicy
+	var propagationPolicy lmscontroller.PropagationPolicy
+	var namespacePolicy lmscontroller.NamespacePolicy
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace(namespace), operatorSelector); err != nil {
+		return namingPolicy, propagationPolicy, namespacePolicy, fmt.Errorf("unable to read operator policies: %w", err)
+	}
+	for _, deployment := range deployments.Items {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name != operatorContainerName {
+				continue
+			}
+			fs := flag.NewFlagSet("operator", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			lmscontroller.BindNamingPolicyFlags(fs, &namingPolicy)
+			lmscontroller.BindPropagationPolicyFlags(fs, &propagationPolicy)
+			lmscontroller.BindNamespacePolicyFlags(fs, &namespacePolicy)
+			if err := fs.Parse(policyArgs(fs, append(container.Command, container.Args...))); err != nil {
+				return namingPolicy, propagationPolicy, namespacePolicy, fmt.Errorf("unable to parse operator policies: %w", err)
+			}
+			return namingPolicy, propagationPolicy, namespacePolicy, nil
+		}
+	}
+	return namingPolicy, propagationPolicy, namespacePolicy, fmt.Errorf("operator deployment not found in namespace %q, "+
+		"set --operator-namespace or policy flags", namespace)
+}
+
+// isPolicyFlag whether a flag name is one of the naming, propagation or namespace policy ones
+func isPolicyFlag(name string) bool {
+	return strings.HasPrefix(name, "name-") || strings.HasPrefix(name, "propagate-") ||
+		name == "secret-namespace" || strings.HasPrefix(name, "target-namespace")
+}
+
+// policyArgs returns the arguments of flags defined in a flag set, leaving out others,
+// such as the rest of operator flags
+func policyArgs(fs *flag.FlagSet, args []string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+		kept = append(kept, args[i])
+		if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); hasValue || (ok && boolFlag.IsBoolFlag()) {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			kept = append(kept, args[i])
+		}
+	}
+	return kept
+}
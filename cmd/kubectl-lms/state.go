@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+// suspend sets LMSMoodle desired state as suspended
+func suspend(ctx context.Context, c client.Client, args []string, out io.Writer) error {
+	return setDesiredState(ctx, c, "suspend", args, out, lmsv1alpha1.SuspendedState)
+}
+
+// resume sets LMSMoodle desired state as ready
+func resume(ctx context.Context, c client.Client, args []string, out io.Writer) error {
+	return setDesiredState(ctx, c, "resume", args, out, lmsv1alpha1.ReadyState)
+}
+
+// pause sets LMSMoodle paused annotation, so that it is not reconciled
+func pause(ctx context.Context, c client.Client, args []string, out io.Writer) error {
+	return setPaused(ctx, c, "pause", args, out, true)
+}
+
+// unpause removes LMSMoodle paused annotation, resuming its reconciliation
+func unpause(ctx context.Context, c client.Client, args []string, out io.Writer) error {
+	return setPaused(ctx, c, "unpause", args, out, false)
+}
+
+func setDesiredState(ctx context.Context, c client.Client, cmd string, args []string, out io.Writer, desiredState string) error {
+	name, err := nameArg(flag.NewFlagSet(cmd, flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	patch := []byte(fmt.Sprintf(`{"spec":{"desiredState":%q}}`, desiredState))
+	if err := patchLMSMoodle(ctx, c, name, patch); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "lmsmoodle/%s desired state set to %s\n", name, desiredState)
+	return nil
+}
+
+func setPaused(ctx context.Context, c client.Client, cmd string, args []string, out io.Writer, paused bool) error {
+	name, err := nameArg(flag.NewFlagSet(cmd, flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, lmsv1alpha1.PausedAnnotation))
+	if paused {
+		patch = []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, lmsv1alpha1.PausedAnnotation))
+	}
+	if err := patchLMSMoodle(ctx, c, name, patch); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "lmsmoodle/%s %sd\n", name, cmd)
+	return nil
+}
+
+// patchLMSMoodle merge patches a LMSMoodle
+func patchLMSMoodle(ctx context.Context, c client.Client, name string, patch []byte) error {
+	lmsMoodle := &lmsv1alpha1.LMSMoodle{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, lmsMoodle); err != nil {
+		return err
+	}
+	return c.Patch(ctx, lmsMoodle, client.RawPatch(types.MergePatchType, patch))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+// WaitPollInterval interval between LMSMoodle state checks while waiting
+const WaitPollInterval time.Duration = 5 * time.Second
+
+// waitFor LMSMoodle states that can be waited for, by --for value
+var waitFor = map[string]string{
+	"ready":     lmsv1alpha1.ReadyState,
+	"suspended": lmsv1alpha1.SuspendedState,
+}
+
+// waitForState waits until LMSMoodle reaches a state, once its current generation is observed
+func waitForState(ctx context.Context, c client.Client, args []string, out io.Writer) error {
+	var forValue string
+	var timeout time.Duration
+	fs := flag.NewFlagSet("wait", flag.ContinueOnError)
+	fs.StringVar(&forValue, "for", "ready", "The state to wait for: ready or suspended.")
+	fs.DurationVar(&timeout, "timeout", 30*time.Minute, "The time to wait before giving up.")
+	name, err := nameArg(fs, args)
+	if err != nil {
+		return err
+	}
+	state, found := waitFor[strings.ToLower(forValue)]
+	if !found {
+		return fmt.Errorf("wait: unsupported --for value %q, use ready or suspended", forValue)
+	}
+
+	lmsMoodle := &lmsv1alpha1.LMSMoodle{}
+	err = wait.PollUntilContextTimeout(ctx, WaitPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, lmsMoodle); err != nil {
+			return false, err
+		}
+		// states of previous generations are stale, such as a failure already fixed
+		if lmsMoodle.Status.ObservedGeneration != lmsMoodle.Generation {
+			return false, nil
+		}
+		if lmsMoodle.Status.State == lmsv1alpha1.FailedState {
+			return false, fmt.Errorf("lmsmoodle/%s failed", name)
+		}
+		return lmsMoodle.Status.State == state, nil
+	})
+	if err != nil {
+		if wait.Interrupted(err) && ctx.Err() == nil {
+			return fmt.Errorf("timed out waiting for lmsmoodle/%s to be %s, current state: %s", name, state, valueOrNone(lmsMoodle.Status.State))
+		}
+		return err
+	}
+	fmt.Fprintf(out, "lmsmoodle/%s condition met\n", name)
+	return nil
+}
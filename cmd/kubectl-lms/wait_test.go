@@ -0,0 +1,61 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("wait", func() {
+	ctx := context.TODO()
+
+	newClient := func(state string, observedGeneration int64) client.Client {
+		lmsMoodle := &lmsv1alpha1.LMSMoodle{
+			ObjectMeta: metav1.ObjectMeta{Name: "site", Generation: 2},
+			Status:     lmsv1alpha1.LMSMoodleStatus{State: state, ObservedGeneration: observedGeneration},
+		}
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(lmsMoodle).Build()
+	}
+
+	It("should stop once the current generation is ready", func() {
+		var out bytes.Buffer
+		Expect(waitForState(ctx, newClient(lmsv1alpha1.ReadyState, 2), []string{"site"}, &out)).To(Succeed())
+		Expect(out.String()).To(Equal("lmsmoodle/site condition met\n"))
+	})
+
+	It("should fail once the current generation failed", func() {
+		var out bytes.Buffer
+		err := waitForState(ctx, newClient(lmsv1alpha1.FailedState, 2), []string{"site"}, &out)
+		Expect(err).To(MatchError("lmsmoodle/site failed"))
+	})
+
+	It("should keep waiting while a failure is of a previous generation", func() {
+		var out bytes.Buffer
+		err := waitForState(ctx, newClient(lmsv1alpha1.FailedState, 1), []string{"site", "--timeout", "10ms"}, &out)
+		Expect(err).To(MatchError(ContainSubstring("timed out waiting for lmsmoodle/site")))
+	})
+})
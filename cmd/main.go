@@ -17,17 +17,24 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -35,37 +42,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/yaml"
 
 	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
 	lmscontroller "github.com/krestomatio/lms-moodle-operator/internal/controller/lms"
+	"github.com/krestomatio/lms-moodle-operator/internal/notifier"
 	// +kubebuilder:scaffold:imports
 )
 
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
-
-	// types from ansible operators
-	moodleGvk = schema.GroupVersionKind{
-		Group:   "m4e.krestomat.io",
-		Version: "v1alpha1",
-		Kind:    "Moodle",
-	}
-	nfsGvk = schema.GroupVersionKind{
-		Group:   "nfs.krestomat.io",
-		Version: "v1alpha1",
-		Kind:    "Ganesha",
-	}
-	keydbGvk = schema.GroupVersionKind{
-		Group:   "keydb.krestomat.io",
-		Version: "v1alpha1",
-		Kind:    "Keydb",
-	}
-	postgresGvk = schema.GroupVersionKind{
-		Group:   "postgres.krestomat.io",
-		Version: "v1alpha1",
-		Kind:    "Postgres",
-	}
 )
 
 func init() {
@@ -76,11 +63,25 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := render(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var notifierNamespace string
+	var notifierAllowedHosts stringSliceFlag
+	var notifierHostPolicy notifier.HostPolicy
+	var namingPolicy lmscontroller.NamingPolicy
+	var propagationPolicy lmscontroller.PropagationPolicy
+	var namespacePolicy lmscontroller.NamespacePolicy
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -92,6 +93,16 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&notifierNamespace, "notifier-namespace", notifier.OperatorNamespace(),
+		"The namespace where the notifier persists pending events and reads signing secrets from. The notifier is disabled if empty.")
+	flag.Var(&notifierAllowedHosts, "notifier-allowed-host",
+		"A host notifier endpoints can be at, such as hooks.example.com, or *.example.com for its subdomains. "+
+			"It can be set multiple times. Any host is allowed if not set.")
+	flag.BoolVar(&notifierHostPolicy.AllowPrivateAddresses, "notifier-allow-private-addresses", false,
+		"If set, notifier endpoints can be at loopback, private or link-local addresses, such as those of cluster services.")
+	lmscontroller.BindNamingPolicyFlags(flag.CommandLine, &namingPolicy)
+	lmscontroller.BindPropagationPolicyFlags(flag.CommandLine, &propagationPolicy)
+	lmscontroller.BindNamespacePolicyFlags(flag.CommandLine, &namespacePolicy)
 	opts := zap.Options{
 		Development: true,
 	}
@@ -167,24 +178,53 @@ func main() {
 		os.Exit(1)
 	}
 
+	var lmsMoodleNotifier *notifier.Notifier
+	if notifierNamespace != "" {
+		notifierHostPolicy.AllowedHosts = notifierAllowedHosts
+		lmsMoodleNotifier = notifier.New(mgr.GetClient(), mgr.GetAPIReader(), notifierNamespace, notifierHostPolicy)
+		if err := mgr.Add(lmsMoodleNotifier); err != nil {
+			setupLog.Error(err, "unable to set up notifier")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("notifier disabled, no namespace to persist pending events")
+	}
+
+	namespacePolicy.OperatorNamespace = notifier.OperatorNamespace()
 	if err = (&lmscontroller.LMSMoodleReconciler{
-		Client:      mgr.GetClient(),
-		Scheme:      mgr.GetScheme(),
-		MoodleGVK:   moodleGvk,
-		NfsGVK:      nfsGvk,
-		KeydbGVK:    keydbGvk,
-		PostgresGVK: postgresGvk,
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		APIReader:         mgr.GetAPIReader(),
+		Recorder:          lmscontroller.NewDeduplicatingEventRecorder(mgr.GetEventRecorderFor("lmsmoodle-controller"), lmscontroller.EventDeduplicationWindow),
+		Notifier:          lmsMoodleNotifier,
+		MoodleGVK:         lmscontroller.MoodleGVK,
+		NfsGVK:            lmscontroller.NfsGVK,
+		KeydbGVK:          lmscontroller.KeydbGVK,
+		PostgresGVK:       lmscontroller.PostgresGVK,
+		NamingPolicy:      namingPolicy,
+		PropagationPolicy: propagationPolicy,
+		NamespacePolicy:   namespacePolicy,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "LMSMoodle")
 		os.Exit(1)
 	}
 	if err = (&lmscontroller.LMSMoodleTemplateReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: lmscontroller.NewDeduplicatingEventRecorder(mgr.GetEventRecorderFor("lmsmoodletemplate-controller"), lmscontroller.EventDeduplicationWindow),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "LMSMoodleTemplate")
 		os.Exit(1)
 	}
+	if err = (&lmscontroller.LMSMoodleClaimReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
+		Recorder:  lmscontroller.NewDeduplicatingEventRecorder(mgr.GetEventRecorderFor("lmsmoodleclaim-controller"), lmscontroller.EventDeduplicationWindow),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "LMSMoodleClaim")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -202,3 +242,103 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// stringSliceFlag is a flag that can be set multiple times
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// render prints, as yaml, the resources the reconciler would create for a LMSMoodle
+func render(args []string, out io.Writer) error {
+	var lmsMoodleFile string
+	var lmsMoodleTemplateFiles stringSliceFlag
+	var namingPolicy lmscontroller.NamingPolicy
+	var propagationPolicy lmscontroller.PropagationPolicy
+	var namespacePolicy lmscontroller.NamespacePolicy
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.StringVar(&lmsMoodleFile, "lmsmoodle", "", "The LMSMoodle yaml file to render.")
+	fs.Var(&lmsMoodleTemplateFiles, "template",
+		"A yaml file with LMSMoodleTemplates. It can be set multiple times. "+
+			"The template referenced by the LMSMoodle is used.")
+	lmscontroller.BindNamingPolicyFlags(fs, &namingPolicy)
+	lmscontroller.BindPropagationPolicyFlags(fs, &propagationPolicy)
+	lmscontroller.BindNamespacePolicyFlags(fs, &namespacePolicy)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if lmsMoodleFile == "" || len(lmsMoodleTemplateFiles) == 0 {
+		fs.Usage()
+		return errors.New("render requires --lmsmoodle and --template")
+	}
+
+	lmsMoodles, err := readObjects(lmsMoodleFile, "LMSMoodle")
+	if err != nil {
+		return err
+	}
+	if len(lmsMoodles) != 1 {
+		return fmt.Errorf("expected one LMSMoodle in %s, found %d", lmsMoodleFile, len(lmsMoodles))
+	}
+	var lmsMoodleTemplates []*unstructured.Unstructured
+	for _, lmsMoodleTemplateFile := range lmsMoodleTemplateFiles {
+		objs, err := readObjects(lmsMoodleTemplateFile, "LMSMoodleTemplate")
+		if err != nil {
+			return err
+		}
+		lmsMoodleTemplates = append(lmsMoodleTemplates, objs...)
+	}
+
+	objs, err := lmscontroller.Render(context.Background(), lmsMoodles[0], lmsMoodleTemplates, namingPolicy, propagationPolicy, namespacePolicy, lmscontroller.MoodleGVK, lmscontroller.NfsGVK, lmscontroller.KeydbGVK, lmscontroller.PostgresGVK)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		objYaml, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", objYaml); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readObjects reads objects of a lms kind from a yaml file, with one or more documents
+func readObjects(fileName string, kind string) ([]*unstructured.Unstructured, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var objs []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(file, 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, fmt.Errorf("unable to decode %s: %w", fileName, err)
+		}
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+		// decode as the API server does, with integers as int64
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("unable to decode %s: %w", fileName, err)
+		}
+		if obj.GroupVersionKind() != lmsv1alpha1.GroupVersion.WithKind(kind) {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+}
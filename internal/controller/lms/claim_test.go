@@ -0,0 +1,280 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+// applyAsUpdate turns server-side apply patches into creates or updates, since the fake
+// client does not support them. Managed fields are kept, as they are not applied
+func applyAsUpdate(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Patch(ctx, obj, patch, opts...)
+	}
+	live := newUnstructuredObject(obj.GetObjectKind().GroupVersionKind())
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), live); errors.IsNotFound(err) {
+		return c.Create(ctx, obj)
+	} else if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(live.GetResourceVersion())
+	if obj.GetManagedFields() == nil {
+		obj.SetManagedFields(live.GetManagedFields())
+	}
+	return c.Update(ctx, obj)
+}
+
+var _ = Describe("LMSMoodleClaim bind", func() {
+	const claimUID = "claim-uid"
+	const lmsMoodleName = LMSMoodleClaimNamePrefix + claimUID
+	var c client.Client
+	var r *LMSMoodleClaimReconciler
+
+	newTemplate := func(reclaimPolicy string) *unstructured.Unstructured {
+		lmsMoodleTemplate := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleTemplate"))
+		lmsMoodleTemplate.SetName("template")
+		lmsMoodleTemplate.Object["spec"] = map[string]interface{}{
+			"claims": map[string]interface{}{
+				"allowed":          true,
+				"allowedOverrides": []interface{}{"host"},
+				"reclaimPolicy":    reclaimPolicy,
+			},
+		}
+		return lmsMoodleTemplate
+	}
+
+	newClaim := func(host string, status map[string]interface{}) *unstructured.Unstructured {
+		lmsMoodleClaim := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleClaim"))
+		lmsMoodleClaim.SetName("claim")
+		lmsMoodleClaim.SetNamespace("team")
+		lmsMoodleClaim.SetUID(claimUID)
+		lmsMoodleClaim.Object["spec"] = map[string]interface{}{
+			"lmsMoodleTemplateName": "template",
+			"agreeLicense":          true,
+			"adminMail":             "admin@example.com",
+			"adminPassHash":         "hash",
+		}
+		if host != "" {
+			lmsMoodleClaim.Object["spec"].(map[string]interface{})["overrides"] = map[string]interface{}{"host": host}
+		}
+		if status != nil {
+			lmsMoodleClaim.SetFinalizers([]string{LMSMoodleClaimFinalizer})
+			lmsMoodleClaim.Object["status"] = status
+		}
+		return lmsMoodleClaim
+	}
+
+	newLMSMoodle := func(name, claimUID, host string) *unstructured.Unstructured {
+		lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		lmsMoodle.SetName(name)
+		if claimUID != "" {
+			lmsMoodle.SetAnnotations(map[string]string{
+				lmsv1alpha1.ClaimAnnotation:    "team/claim",
+				lmsv1alpha1.ClaimUIDAnnotation: claimUID,
+			})
+		}
+		lmsMoodle.Object["spec"] = map[string]interface{}{
+			"lmsMoodleTemplateName": "template",
+			"moodleSpec":            map[string]interface{}{"moodleHost": host},
+		}
+		return lmsMoodle
+	}
+
+	newClient := func(objs ...client.Object) client.WithWatch {
+		return fake.NewClientBuilder().WithScheme(newDependantsScheme()).WithObjects(objs...).
+			WithStatusSubresource(&lmsv1alpha1.LMSMoodleClaim{}, &lmsv1alpha1.LMSMoodle{}).
+			WithIndex(&lmsv1alpha1.LMSMoodle{}, LMSMoodleHostIndex, lmsMoodleHostIndexer).
+			WithInterceptorFuncs(interceptor.Funcs{Patch: applyAsUpdate}).Build()
+	}
+
+	newReconciler := func(apiReader client.Reader, objs ...client.Object) {
+		c = newClient(objs...)
+		r = &LMSMoodleClaimReconciler{
+			Client:    c,
+			Scheme:    c.Scheme(),
+			APIReader: apiReader,
+			Recorder:  &record.FakeRecorder{},
+		}
+	}
+
+	reconcileClaim := func() *unstructured.Unstructured {
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "team", Name: "claim"}})
+		Expect(err).NotTo(HaveOccurred())
+		lmsMoodleClaim := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleClaim"))
+		err = c.Get(ctx, types.NamespacedName{Namespace: "team", Name: "claim"}, lmsMoodleClaim)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return lmsMoodleClaim
+	}
+
+	condition := func(obj *unstructured.Unstructured, conditionType string) map[string]interface{} {
+		condition, found, err := getConditionByType(obj, conditionType)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		return condition
+	}
+
+	boundStatus := map[string]interface{}{
+		"phase":         lmsv1alpha1.BoundClaimPhase,
+		"lmsMoodleName": lmsMoodleName,
+		"reclaimPolicy": lmsv1alpha1.DeleteReclaimPolicy,
+	}
+
+	It("should bind a claim to a LMSMoodle created from it", func() {
+		newReconciler(nil, newTemplate(""), newClaim("site.example.com", nil))
+
+		lmsMoodleClaim := reconcileClaim()
+		Expect(lmsMoodleClaim.GetFinalizers()).To(ContainElement(LMSMoodleClaimFinalizer))
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("phase", lmsv1alpha1.BoundClaimPhase))
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("lmsMoodleName", lmsMoodleName))
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("reclaimPolicy", lmsv1alpha1.DeleteReclaimPolicy))
+		Expect(condition(lmsMoodleClaim, AcceptedConditionType)).To(HaveKeyWithValue("status", "True"))
+		Expect(condition(lmsMoodleClaim, BoundConditionType)).To(HaveKeyWithValue("status", "True"))
+
+		lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		Expect(c.Get(ctx, types.NamespacedName{Name: lmsMoodleName}, lmsMoodle)).To(Succeed())
+		Expect(lmsMoodle.GetAnnotations()).To(HaveKeyWithValue(lmsv1alpha1.ClaimAnnotation, "team/claim"))
+		Expect(lmsMoodle.GetAnnotations()).To(HaveKeyWithValue(lmsv1alpha1.ClaimUIDAnnotation, claimUID))
+		host, _, _ := unstructured.NestedString(lmsMoodle.Object, "spec", "moodleSpec", "moodleHost")
+		Expect(host).To(Equal("site.example.com"))
+		adminMail, _, _ := unstructured.NestedString(lmsMoodle.Object, "spec", "moodleSpec", "moodleNewInstanceAdminmail")
+		Expect(adminMail).To(Equal("admin@example.com"))
+	})
+
+	It("should mirror claimed LMSMoodle status", func() {
+		lmsMoodle := newLMSMoodle(lmsMoodleName, claimUID, "")
+		lmsMoodle.Object["status"] = map[string]interface{}{
+			"state": "Ready",
+			"url":   "https://site.example.com",
+			"conditions": []interface{}{map[string]interface{}{
+				"type":               ReadyConditionType,
+				"status":             "True",
+				"reason":             "Successful",
+				"message":            "Ready",
+				"lastTransitionTime": "2026-01-01T00:00:00Z",
+			}},
+		}
+		newReconciler(nil, newTemplate(""), newClaim("", boundStatus), lmsMoodle)
+
+		lmsMoodleClaim := reconcileClaim()
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("phase", lmsv1alpha1.BoundClaimPhase))
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("state", "Ready"))
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("url", "https://site.example.com"))
+		Expect(lmsMoodleClaim.Object["status"]).NotTo(HaveKey("storageGb"))
+		Expect(condition(lmsMoodleClaim, ReadyConditionType)).To(HaveKeyWithValue("status", "True"))
+		Expect(condition(lmsMoodleClaim, ReadyConditionType)).To(HaveKeyWithValue("reason", "Successful"))
+	})
+
+	It("should delete claimed LMSMoodle along with the claim, by default", func() {
+		newReconciler(nil, newTemplate(""), newClaim("", boundStatus), newLMSMoodle(lmsMoodleName, claimUID, ""))
+		Expect(c.Delete(ctx, newClaim("", nil))).To(Succeed())
+
+		Expect(reconcileClaim()).To(BeNil())
+		lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		Expect(errors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: lmsMoodleName}, lmsMoodle))).To(BeTrue())
+	})
+
+	It("should release claimed LMSMoodle when the claim is deleted, if retained", func() {
+		retainStatus := map[string]interface{}{
+			"phase":         lmsv1alpha1.BoundClaimPhase,
+			"lmsMoodleName": lmsMoodleName,
+			"reclaimPolicy": lmsv1alpha1.RetainReclaimPolicy,
+		}
+		newReconciler(nil, newTemplate(lmsv1alpha1.RetainReclaimPolicy), newClaim("", retainStatus), newLMSMoodle(lmsMoodleName, claimUID, ""))
+		Expect(c.Delete(ctx, newClaim("", nil))).To(Succeed())
+
+		Expect(reconcileClaim()).To(BeNil())
+		lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		Expect(c.Get(ctx, types.NamespacedName{Name: lmsMoodleName}, lmsMoodle)).To(Succeed())
+		Expect(lmsMoodle.GetAnnotations()).NotTo(HaveKey(lmsv1alpha1.ClaimAnnotation))
+		Expect(lmsMoodle.GetAnnotations()).NotTo(HaveKey(lmsv1alpha1.ClaimUIDAnnotation))
+	})
+
+	It("should lose a bound claim whose LMSMoodle no longer exists", func() {
+		newReconciler(newClient(), newTemplate(""), newClaim("", boundStatus))
+
+		lmsMoodleClaim := reconcileClaim()
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("phase", lmsv1alpha1.LostClaimPhase))
+		Expect(condition(lmsMoodleClaim, BoundConditionType)).To(HaveKeyWithValue("reason", "Lost"))
+		Expect(condition(lmsMoodleClaim, ReadyConditionType)).To(HaveKeyWithValue("status", "False"))
+		lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		Expect(errors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: lmsMoodleName}, lmsMoodle))).To(BeTrue())
+	})
+
+	It("should keep a claim bound when its LMSMoodle is not cached yet", func() {
+		apiReader := newClient(newLMSMoodle(lmsMoodleName, claimUID, ""))
+		newReconciler(apiReader, newTemplate(""), newClaim("", boundStatus))
+
+		lmsMoodleClaim := reconcileClaim()
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("phase", lmsv1alpha1.BoundClaimPhase))
+		Expect(condition(lmsMoodleClaim, BoundConditionType)).To(HaveKeyWithValue("status", "True"))
+	})
+
+	It("should lose a claim whose LMSMoodle is bound to another claim", func() {
+		newReconciler(nil, newTemplate(""), newClaim("", boundStatus), newLMSMoodle(lmsMoodleName, "other-uid", ""))
+
+		lmsMoodleClaim := reconcileClaim()
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("phase", lmsv1alpha1.LostClaimPhase))
+	})
+
+	It("should not accept a claim whose host is in use by another LMSMoodle", func() {
+		newReconciler(nil, newTemplate(""), newClaim("site.example.com", nil), newLMSMoodle("other", "", "site.example.com"))
+
+		lmsMoodleClaim := reconcileClaim()
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("phase", lmsv1alpha1.PendingClaimPhase))
+		Expect(condition(lmsMoodleClaim, AcceptedConditionType)).To(HaveKeyWithValue("status", "False"))
+		Expect(condition(lmsMoodleClaim, AcceptedConditionType)).To(HaveKeyWithValue("reason", "HostInUse"))
+		lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		Expect(errors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: lmsMoodleName}, lmsMoodle))).To(BeTrue())
+	})
+
+	It("should not accept a claim whose host is in use by a LMSMoodle not cached yet", func() {
+		apiReader := newClient(newLMSMoodle("other", "", "site.example.com"))
+		newReconciler(apiReader, newTemplate(""), newClaim("site.example.com", nil))
+
+		lmsMoodleClaim := reconcileClaim()
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("phase", lmsv1alpha1.PendingClaimPhase))
+		Expect(condition(lmsMoodleClaim, AcceptedConditionType)).To(HaveKeyWithValue("reason", "HostInUse"))
+		lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		Expect(errors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: lmsMoodleName}, lmsMoodle))).To(BeTrue())
+	})
+
+	It("should accept a claim whose host is in use by its own LMSMoodle", func() {
+		newReconciler(nil, newTemplate(""), newClaim("site.example.com", boundStatus), newLMSMoodle(lmsMoodleName, claimUID, "site.example.com"))
+
+		lmsMoodleClaim := reconcileClaim()
+		Expect(lmsMoodleClaim.Object["status"]).To(HaveKeyWithValue("phase", lmsv1alpha1.BoundClaimPhase))
+		Expect(condition(lmsMoodleClaim, AcceptedConditionType)).To(HaveKeyWithValue("status", "True"))
+	})
+})
@@ -2,6 +2,7 @@ package lms
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
@@ -11,11 +12,19 @@ import (
 )
 
 const (
-	ReadyConditionType         string = "Ready"
-	MoodleReadyConditionType   string = "MoodleReady"
-	NfsReadyConditionType      string = "NfsReady"
-	KeydbReadyConditionType    string = "KeydbReady"
-	PostgresReadyConditionType string = "PostgresReady"
+	ReadyConditionType            string = "Ready"
+	MoodleReadyConditionType      string = "MoodleReady"
+	NfsReadyConditionType         string = "NfsReady"
+	KeydbReadyConditionType       string = "KeydbReady"
+	PostgresReadyConditionType    string = "PostgresReady"
+	ComponentStatesConditionType  string = "ComponentStatesValid"
+	AvailableConditionType        string = "Available"
+	ProgressingConditionType      string = "Progressing"
+	DegradedConditionType         string = "Degraded"
+	PausedConditionType           string = "Paused"
+	DriftedConditionType          string = "Drifted"
+	QuotaExceededConditionType    string = "QuotaExceeded"
+	OverridesAllowedConditionType string = "OverridesAllowed"
 )
 
 // FindConditionUnstructuredByType returns first Condition with given conditionType
@@ -134,6 +143,156 @@ func (r *LMSMoodleReconciler) SetConditionFromDependantByType(ctx context.Contex
 	return changed, dependantConditionStatus
 }
 
+// SetStandardConditions set Available, Progressing and Degraded conditions from
+// dependants ready status and whether the combined specs have been rolled out
+// It returns a bool flag if any condition changed, and any error
+func (r *LMSMoodleReconciler) SetStandardConditions(ctx context.Context, statusState string) (changed bool, err error) {
+	moodleReady, _ := getReadyStatus(ctx, r.lmsMoodleCtx.moodle)
+
+	// dependants expected to be ready but not ready
+	var notReadyDependants []string
+	for _, dependant := range []struct {
+		name         string
+		present      bool
+		desiredState string
+		obj          *unstructured.Unstructured
+	}{
+		{"Postgres", r.lmsMoodleCtx.hasPostgres, r.lmsMoodleCtx.postgresDesiredState, r.lmsMoodleCtx.postgres},
+		{"Keydb", r.lmsMoodleCtx.hasKeydb, r.lmsMoodleCtx.keydbDesiredState, r.lmsMoodleCtx.keydb},
+		{"Nfs", r.lmsMoodleCtx.hasNfs, r.lmsMoodleCtx.nfsDesiredState, r.lmsMoodleCtx.nfs},
+	} {
+		if !dependant.present || dependant.desiredState != lmsv1alpha1.ReadyState {
+			continue
+		}
+		if ready, _ := getReadyStatus(ctx, dependant.obj); !ready {
+			notReadyDependants = append(notReadyDependants, dependant.name)
+		}
+	}
+
+	// Available: Moodle is serving
+	availableCondition := map[string]interface{}{
+		"type":    AvailableConditionType,
+		"status":  "True",
+		"reason":  "MoodleReady",
+		"message": "Moodle is available",
+	}
+	if !moodleReady {
+		availableCondition["status"] = "False"
+		availableCondition["reason"] = notAvailableReason(statusState)
+		availableCondition["message"] = "Moodle is not available"
+	}
+
+	// Degraded: LMSMoodle failed or Moodle is serving while some dependant is not ready
+	degradedCondition := map[string]interface{}{
+		"type":    DegradedConditionType,
+		"status":  "False",
+		"reason":  "AsExpected",
+		"message": "LMSMoodle is not degraded",
+	}
+	if statusState == lmsv1alpha1.FailedState {
+		degradedCondition["status"] = "True"
+		degradedCondition["reason"] = r.lmsMoodleCtx.failedReason
+		degradedCondition["message"] = r.lmsMoodleCtx.failedMessage
+	} else if moodleReady && len(notReadyDependants) > 0 {
+		degradedCondition["status"] = "True"
+		degradedCondition["reason"] = "DependantNotReady"
+		degradedCondition["message"] = strings.Join(notReadyDependants, ", ") + " not ready"
+	}
+
+	// Progressing: combined specs not rolled out yet
+	specHash := r.combinedSpecsHash()
+	appliedSpecHash, _, _ := unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "appliedSpecHash")
+	progressingCondition := map[string]interface{}{
+		"type":    ProgressingConditionType,
+		"status":  "True",
+		"reason":  "Reconciling",
+		"message": "LMSMoodle is " + statusState,
+	}
+	switch statusState {
+	case lmsv1alpha1.ReadyState, lmsv1alpha1.SuspendedState, lmsv1alpha1.PartiallySuspendedState:
+		progressingCondition["status"] = "False"
+		progressingCondition["reason"] = "RolloutComplete"
+		progressingCondition["message"] = "LMSMoodle spec has been rolled out"
+		if appliedSpecHash != specHash {
+			if err := unstructured.SetNestedField(r.lmsMoodleCtx.lmsMoodle.Object, specHash, "status", "appliedSpecHash"); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+	case lmsv1alpha1.FailedState, lmsv1alpha1.TerminatingState, lmsv1alpha1.TerminatedState, lmsv1alpha1.PausedState:
+		progressingCondition["status"] = "False"
+		progressingCondition["reason"] = statusState
+	default:
+		if appliedSpecHash != specHash {
+			progressingCondition["reason"] = "SpecChanged"
+			progressingCondition["message"] = "LMSMoodle spec is being rolled out"
+		}
+	}
+
+	// Paused: LMSMoodle reconciliation is paused, by spec or annotation
+	pausedCondition := map[string]interface{}{
+		"type":    PausedConditionType,
+		"status":  "False",
+		"reason":  "Reconciling",
+		"message": "LMSMoodle is being reconciled",
+	}
+	if r.lmsMoodleCtx.paused {
+		pausedCondition["status"] = "True"
+		pausedCondition["reason"] = lmsv1alpha1.PausedState
+		pausedCondition["message"] = "LMSMoodle reconciliation is paused, dependants are not applied, created nor deleted"
+	}
+
+	conditions := []map[string]interface{}{availableCondition, progressingCondition, degradedCondition, pausedCondition}
+
+	// Drifted: dependants changed by other field managers, once checked. Removed when not
+	// checked, such as while suspended, since it would be stale, unless paused
+	if r.lmsMoodleCtx.driftChecked {
+		conditions = append(conditions, r.getDriftedCondition())
+	} else if !r.lmsMoodleCtx.paused {
+		removed, err := RemoveCondition(r.lmsMoodleCtx.lmsMoodle, DriftedConditionType)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || removed
+	}
+
+	// conditions from combined specs are kept as they were while paused, since specs are not combined
+	if !r.lmsMoodleCtx.paused {
+		// QuotaExceeded: combined specs exceed namespace quota, when set
+		if !r.lmsMoodleCtx.namespaceQuotaOmit {
+			conditions = append(conditions, r.getQuotaExceededCondition())
+		}
+
+		// OverridesAllowed: LMSMoodle overrides allowed by template, once restricted
+		if _, found, _ := getConditionByType(r.lmsMoodleCtx.lmsMoodle, OverridesAllowedConditionType); found || r.lmsMoodleCtx.overridesRestricted {
+			conditions = append(conditions, r.getOverridesAllowedCondition())
+		}
+	}
+
+	for _, condition := range conditions {
+		conditionChanged, err := SetCondition(r.lmsMoodleCtx.lmsMoodle, condition)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || conditionChanged
+	}
+
+	return changed, nil
+}
+
+// notAvailableReason returns the reason of a false available condition for a state, since
+// transient states, such as PostgresCreating, or an empty state are not valid reasons
+func notAvailableReason(statusState string) string {
+	switch statusState {
+	case lmsv1alpha1.SuspendedState, lmsv1alpha1.PartiallySuspendedState:
+		return lmsv1alpha1.SuspendedState
+	case lmsv1alpha1.FailedState, lmsv1alpha1.TerminatingState, lmsv1alpha1.PausedState:
+		return statusState
+	default:
+		return "MoodleNotReady"
+	}
+}
+
 // getConditionByType returns a condition by type from a unstructure object,
 // a bool flag which indicates whether condition exists, and
 // any error getting the condition
@@ -154,6 +313,31 @@ func getConditionByType(objU *unstructured.Unstructured, conditionType string) (
 	return condition, conditionFound, nil
 }
 
+// RemoveCondition removes a condition by type, if present
+// It returns a bool flag if condition was removed, and any error
+func RemoveCondition(unstructuredObj *unstructured.Unstructured, conditionType string) (bool, error) {
+	conditions, _, err := unstructured.NestedSlice(unstructuredObj.Object, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+
+	keptConditions := make([]interface{}, 0, len(conditions))
+	for _, item := range conditions {
+		if conditionObj, ok := item.(map[string]interface{}); ok && conditionObj["type"] == conditionType {
+			continue
+		}
+		keptConditions = append(keptConditions, item)
+	}
+	if len(keptConditions) == len(conditions) {
+		return false, nil
+	}
+
+	if err := unstructured.SetNestedSlice(unstructuredObj.Object, keptConditions, "status", "conditions"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // SetCondition update or append a condition if needed
 // It returns a bool flag if condition was appended or updated, and
 // any error
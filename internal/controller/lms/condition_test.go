@@ -0,0 +1,42 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Standard conditions", func() {
+	Context("When Moodle is not available", func() {
+		It("should map states to fixed reasons", func() {
+			Expect(notAvailableReason(lmsv1alpha1.SuspendedState)).To(Equal("Suspended"))
+			Expect(notAvailableReason(lmsv1alpha1.PartiallySuspendedState)).To(Equal("Suspended"))
+			Expect(notAvailableReason(lmsv1alpha1.FailedState)).To(Equal("Failed"))
+			Expect(notAvailableReason(lmsv1alpha1.TerminatingState)).To(Equal("Terminating"))
+			Expect(notAvailableReason(lmsv1alpha1.PausedState)).To(Equal("Paused"))
+		})
+
+		It("should not use transient or empty states as reasons", func() {
+			Expect(notAvailableReason("")).To(Equal("MoodleNotReady"))
+			Expect(notAvailableReason("PostgresCreating")).To(Equal("MoodleNotReady"))
+			Expect(notAvailableReason("SuspendingMoodleSuspended")).To(Equal("MoodleNotReady"))
+		})
+	})
+})
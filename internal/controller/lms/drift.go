@@ -0,0 +1,327 @@
+package lms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+const (
+	// DriftedMessageMaxFields maximum number of drifted fields listed in Drifted condition message
+	DriftedMessageMaxFields int = 10
+)
+
+// driftedField is a dependant spec field whose live value differs from the combined spec,
+// owned by field managers other than the operator
+type driftedField struct {
+	component    string
+	path         []string
+	managers     []string
+	liveValue    interface{}
+	desiredFound bool
+}
+
+// driftDependant is a present dependant checked for drift
+type driftDependant struct {
+	component    string
+	specKey      string
+	obj          *unstructured.Unstructured
+	combinedSpec map[string]interface{}
+}
+
+// ReconcileDrift compares live dependants with their combined specs, before applying them.
+// Drifted fields are overwritten, reported only or adopted into LMSMoodle spec overrides,
+// as set by drift policy. Reported and adopted fields are left out of combined specs, so
+// that they are neither applied nor owned by the operator
+func (r *LMSMoodleReconciler) ReconcileDrift(ctx context.Context) error {
+	log := log.FromContext(ctx)
+
+	policy, ignoredManagers := r.getDriftPolicy()
+	dependants := []driftDependant{{"moodle", "moodleSpec", r.lmsMoodleCtx.moodle, r.lmsMoodleCtx.combinedMoodleSpec}}
+	if r.lmsMoodleCtx.hasPostgres {
+		dependants = append(dependants, driftDependant{"postgres", "postgresSpec", r.lmsMoodleCtx.postgres, r.lmsMoodleCtx.combinedPostgresSpec})
+	}
+	if r.lmsMoodleCtx.hasKeydb {
+		dependants = append(dependants, driftDependant{"keydb", "keydbSpec", r.lmsMoodleCtx.keydb, r.lmsMoodleCtx.combinedKeydbSpec})
+	}
+	if r.lmsMoodleCtx.hasNfs {
+		dependants = append(dependants, driftDependant{"nfs", "nfsSpec", r.lmsMoodleCtx.nfs, r.lmsMoodleCtx.combinedNfsSpec})
+	}
+
+	var driftedFields []driftedField
+	var previousSpecs map[string]map[string]interface{}
+	adoptedSpec := map[string]interface{}{}
+	for _, dependant := range dependants {
+		live := newUnstructuredObject(dependant.obj.GroupVersionKind())
+		if err := r.Get(ctx, client.ObjectKeyFromObject(dependant.obj), live); err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				continue
+			}
+			return err
+		}
+
+		dependantDriftedFields := getDriftedFields(dependant.component, live, dependant.combinedSpec, ignoredManagers)
+		if len(dependantDriftedFields) == 0 {
+			continue
+		}
+		driftedFields = append(driftedFields, dependantDriftedFields...)
+		log.Info("Dependant resource has drifted", "Dependant", live.GetKind(), "Fields", len(dependantDriftedFields), "Policy", policy)
+
+		switch policy {
+		case lmsv1alpha1.OverwriteDriftPolicy:
+			// remove fields no longer in combined spec, applied before. The rest are overwritten when applied
+			if previousSpecs == nil {
+				var err error
+				if previousSpecs, err = r.getPreviousEffectiveSpecs(ctx); err != nil {
+					return err
+				}
+			}
+			if err := r.removeDriftedFields(ctx, live, dependantDriftedFields, previousSpecs[dependant.component]); err != nil {
+				return err
+			}
+		default:
+			// keep live values, leaving them out of combined spec
+			for _, field := range dependantDriftedFields {
+				unstructured.RemoveNestedField(dependant.combinedSpec, field.path...)
+				if policy == lmsv1alpha1.AdoptDriftPolicy {
+					if err := unstructured.SetNestedField(adoptedSpec, field.liveValue, append([]string{dependant.specKey}, field.path...)...); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	if len(adoptedSpec) > 0 {
+		if err := r.adoptDriftedFields(ctx, adoptedSpec); err != nil {
+			return err
+		}
+	}
+
+	r.lmsMoodleCtx.driftChecked = true
+	r.lmsMoodleCtx.driftPolicy = policy
+	r.lmsMoodleCtx.driftedFields = driftedFields
+	if len(driftedFields) > 0 {
+		r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, corev1.EventTypeWarning, DriftedEventReason, driftedMessage(driftedFields))
+	}
+
+	return nil
+}
+
+// getDriftPolicy returns LMSMoodle drift policy, overriding LMSMoodleTemplate one,
+// and field managers ignored by any of them
+func (r *LMSMoodleReconciler) getDriftPolicy() (policy string, ignoredManagers map[string]bool) {
+	policy = lmsv1alpha1.OverwriteDriftPolicy
+	ignoredManagers = map[string]bool{OPERATORNAME: true}
+	for _, spec := range []map[string]interface{}{r.lmsMoodleCtx.lmsMoodleTemplateSpec, r.lmsMoodleCtx.spec} {
+		if specPolicy, found, _ := unstructured.NestedString(spec, "drift", "policy"); found && specPolicy != "" {
+			policy = specPolicy
+		}
+		specIgnoredManagers, _, _ := unstructured.NestedStringSlice(spec, "drift", "ignoredManagers")
+		for _, manager := range specIgnoredManagers {
+			ignoredManagers[manager] = true
+		}
+	}
+	return policy, ignoredManagers
+}
+
+// getDriftedFields returns live spec fields owned by not ignored field managers,
+// with values other than the ones in combined spec
+func getDriftedFields(component string, live *unstructured.Unstructured, combinedSpec map[string]interface{}, ignoredManagers map[string]bool) []driftedField {
+	liveSpec, _, _ := unstructured.NestedMap(live.Object, "spec")
+
+	// spec field paths by field manager owning them
+	managersByPath := map[string][]string{}
+	paths := map[string][]string{}
+	for _, managedFields := range live.GetManagedFields() {
+		if ignoredManagers[managedFields.Manager] || managedFields.Subresource != "" || managedFields.FieldsV1 == nil {
+			continue
+		}
+		fieldsV1 := map[string]interface{}{}
+		if err := json.Unmarshal(managedFields.FieldsV1.Raw, &fieldsV1); err != nil {
+			continue
+		}
+		specFields, _ := fieldsV1["f:spec"].(map[string]interface{})
+		for _, path := range managedFieldPaths(specFields, nil) {
+			key := strings.Join(path, ".")
+			paths[key] = path
+			managersByPath[key] = append(managersByPath[key], managedFields.Manager)
+		}
+	}
+
+	keys := make([]string, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var driftedFields []driftedField
+	for _, key := range keys {
+		liveValue, liveFound, _ := unstructured.NestedFieldCopy(liveSpec, paths[key]...)
+		if !liveFound {
+			continue
+		}
+		desiredValue, desiredFound, _ := unstructured.NestedFieldCopy(combinedSpec, paths[key]...)
+		if desiredFound && reflect.DeepEqual(liveValue, desiredValue) {
+			continue
+		}
+		driftedFields = append(driftedFields, driftedField{
+			component:    component,
+			path:         paths[key],
+			managers:     managersByPath[key],
+			liveValue:    liveValue,
+			desiredFound: desiredFound,
+		})
+	}
+	return driftedFields
+}
+
+// managedFieldPaths returns paths of leaf fields in managed fields (FieldsV1 format).
+// Lists and their items are handled as a whole field
+func managedFieldPaths(fields map[string]interface{}, prefix []string) [][]string {
+	var paths [][]string
+	for key, value := range fields {
+		if !strings.HasPrefix(key, "f:") {
+			continue
+		}
+		path := append(append([]string{}, prefix...), strings.TrimPrefix(key, "f:"))
+		children, _ := value.(map[string]interface{})
+		if childPaths := managedFieldPaths(children, path); len(childPaths) > 0 {
+			paths = append(paths, childPaths...)
+		} else {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// removeDriftedFields removes drifted fields not in combined spec from a live dependant, only if
+// they were in its previous combined spec. Fields never applied by the operator are kept
+func (r *LMSMoodleReconciler) removeDriftedFields(ctx context.Context, live *unstructured.Unstructured, driftedFields []driftedField, previousSpec map[string]interface{}) error {
+	removedSpec := map[string]interface{}{}
+	for _, field := range driftedFields {
+		if field.desiredFound {
+			continue
+		}
+		if _, previousFound, _ := unstructured.NestedFieldNoCopy(previousSpec, field.path...); !previousFound {
+			continue
+		}
+		if err := unstructured.SetNestedField(removedSpec, nil, field.path...); err != nil {
+			return err
+		}
+	}
+	if len(removedSpec) == 0 {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"spec": removedSpec})
+	if err != nil {
+		return err
+	}
+	return r.Patch(ctx, live, client.RawPatch(types.MergePatchType, patch), client.FieldOwner(OPERATORNAME))
+}
+
+// getPreviousEffectiveSpecs returns combined specs by component, as published during previous
+// reconcile in effective specs ConfigMap, if any
+func (r *LMSMoodleReconciler) getPreviousEffectiveSpecs(ctx context.Context) (map[string]map[string]interface{}, error) {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	previousSpecs := map[string]map[string]interface{}{}
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: r.effectiveSpecsConfigMapName(), Namespace: r.lmsMoodleCtx.namespaceName}, configMap); err != nil {
+		return previousSpecs, client.IgnoreNotFound(err)
+	}
+	for key, specYaml := range configMap.Data {
+		component, isSpec := strings.CutSuffix(key, ".yaml")
+		if !isSpec || strings.HasSuffix(component, ".provenance") {
+			continue
+		}
+		spec := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(specYaml), &spec); err != nil {
+			log.FromContext(ctx).Error(err, "Ignoring unreadable previous effective spec", "Component", component)
+			continue
+		}
+		previousSpecs[component] = spec
+	}
+	return previousSpecs, nil
+}
+
+// adoptDriftedFields sets drifted field values as LMSMoodle spec overrides
+func (r *LMSMoodleReconciler) adoptDriftedFields(ctx context.Context, adoptedSpec map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{"spec": adoptedSpec})
+	if err != nil {
+		return err
+	}
+
+	// patch a copy, keeping pending status changes
+	lmsMoodle := r.lmsMoodleCtx.lmsMoodle.DeepCopy()
+	if err := r.Patch(ctx, lmsMoodle, client.RawPatch(types.MergePatchType, patch), client.FieldOwner(OPERATORNAME)); err != nil {
+		return err
+	}
+	r.lmsMoodleCtx.lmsMoodle.SetResourceVersion(lmsMoodle.GetResourceVersion())
+
+	// adopted fields are now overrides
+	for specKey := range adoptedSpec {
+		spec, _, _ := unstructured.NestedMap(lmsMoodle.Object, "spec", specKey)
+		if err := unstructured.SetNestedMap(r.lmsMoodleCtx.spec, spec, specKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getDriftedCondition returns Drifted condition from drifted fields and drift policy
+func (r *LMSMoodleReconciler) getDriftedCondition() map[string]interface{} {
+	if len(r.lmsMoodleCtx.driftedFields) == 0 {
+		return map[string]interface{}{
+			"type":    DriftedConditionType,
+			"status":  "False",
+			"reason":  "NoDrift",
+			"message": "Dependants match their combined specs",
+		}
+	}
+
+	reason := "Overwritten"
+	switch r.lmsMoodleCtx.driftPolicy {
+	case lmsv1alpha1.ReportDriftPolicy:
+		reason = "Reported"
+	case lmsv1alpha1.AdoptDriftPolicy:
+		reason = "Adopted"
+	}
+	return map[string]interface{}{
+		"type":    DriftedConditionType,
+		"status":  "True",
+		"reason":  reason,
+		"message": driftedMessage(r.lmsMoodleCtx.driftedFields),
+	}
+}
+
+// driftedMessage lists drifted fields and the field managers owning them
+func driftedMessage(driftedFields []driftedField) string {
+	var fields []string
+	for i, field := range driftedFields {
+		if i == DriftedMessageMaxFields {
+			fields = append(fields, fmt.Sprintf("and %d more", len(driftedFields)-i))
+			break
+		}
+		managers := append([]string{}, field.managers...)
+		sort.Strings(managers)
+		fields = append(fields, fmt.Sprintf("%s %s (%s)", field.component, strings.Join(field.path, "."), strings.Join(managers, ", ")))
+	}
+	return "Drifted fields: " + strings.Join(fields, "; ")
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"sort"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Drift", func() {
+	managedFields := func(manager string, subresource string, fieldsV1 string) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{
+			Manager:     manager,
+			Operation:   metav1.ManagedFieldsOperationUpdate,
+			Subresource: subresource,
+			FieldsType:  "FieldsV1",
+			FieldsV1:    &metav1.FieldsV1{Raw: []byte(fieldsV1)},
+		}
+	}
+
+	liveMoodle := func() *unstructured.Unstructured {
+		live := newUnstructuredObject(MoodleGVK)
+		live.SetName("lms-site")
+		live.SetNamespace("lms-site")
+		live.Object["spec"] = map[string]interface{}{
+			"moodleHost":  "site.example.com",
+			"moodleSize":  int64(3),
+			"extraConfig": "manual",
+			"legacy":      "applied before",
+		}
+		live.SetManagedFields([]metav1.ManagedFieldsEntry{
+			managedFields(OPERATORNAME, "", `{"f:spec":{"f:moodleHost":{}}}`),
+			managedFields("kubectl-edit", "", `{"f:spec":{"f:moodleHost":{},"f:moodleSize":{},"f:extraConfig":{},"f:legacy":{}}}`),
+			managedFields("ansible-operator", "status", `{"f:status":{"f:ready":{}}}`),
+		})
+		return live
+	}
+
+	fieldKeys := func(fields []driftedField) []string {
+		var keys []string
+		for _, field := range fields {
+			keys = append(keys, strings.Join(field.path, "."))
+		}
+		return keys
+	}
+
+	Context("When listing managed field paths", func() {
+		It("should return leaf fields, with lists as a whole", func() {
+			paths := managedFieldPaths(map[string]interface{}{
+				"f:moodleHost": map[string]interface{}{},
+				"f:routineStatusCrNotify": map[string]interface{}{
+					"f:url":        map[string]interface{}{},
+					"f:statusCode": map[string]interface{}{},
+				},
+				"f:nginxNetpolIngressPeers": map[string]interface{}{
+					".":       map[string]interface{}{},
+					"k:{...}": map[string]interface{}{},
+				},
+			}, []string{"spec"})
+
+			var keys []string
+			for _, path := range paths {
+				keys = append(keys, strings.Join(path, "."))
+			}
+			sort.Strings(keys)
+			Expect(keys).To(Equal([]string{
+				"spec.moodleHost",
+				"spec.nginxNetpolIngressPeers",
+				"spec.routineStatusCrNotify.statusCode",
+				"spec.routineStatusCrNotify.url",
+			}))
+		})
+	})
+
+	Context("When comparing a live dependant with its combined spec", func() {
+		It("should return fields of other managers differing from combined spec", func() {
+			combinedSpec := map[string]interface{}{"moodleHost": "site.example.com", "moodleSize": int64(1)}
+
+			driftedFields := getDriftedFields("moodle", liveMoodle(), combinedSpec, map[string]bool{OPERATORNAME: true})
+			Expect(fieldKeys(driftedFields)).To(Equal([]string{"extraConfig", "legacy", "moodleSize"}))
+			Expect(driftedFields[2].managers).To(Equal([]string{"kubectl-edit"}))
+			Expect(driftedFields[2].liveValue).To(Equal(int64(3)))
+			Expect(driftedFields[2].desiredFound).To(BeTrue())
+			Expect(driftedFields[0].desiredFound).To(BeFalse())
+		})
+
+		It("should not return fields of ignored managers", func() {
+			driftedFields := getDriftedFields("moodle", liveMoodle(), map[string]interface{}{}, map[string]bool{OPERATORNAME: true, "kubectl-edit": true})
+			Expect(driftedFields).To(BeEmpty())
+		})
+	})
+
+	Context("When reconciling drift", func() {
+		var c client.Client
+		var r *LMSMoodleReconciler
+
+		BeforeEach(func() {
+			effectiveSpecs := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: EffectiveSpecsConfigMapName, Namespace: "lms-site"},
+				Data: map[string]string{
+					"moodle.yaml":            "legacy: applied before\nmoodleHost: site.example.com\n",
+					"moodle.provenance.yaml": "legacy: template\n",
+				},
+			}
+			c = fake.NewClientBuilder().WithScheme(newDependantsScheme()).WithObjects(liveMoodle(), effectiveSpecs).Build()
+			r = &LMSMoodleReconciler{Client: c, Recorder: &record.FakeRecorder{}}
+			r.lmsMoodleCtx.lmsMoodle = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+			r.lmsMoodleCtx.lmsMoodleTemplateSpec = map[string]interface{}{}
+			r.lmsMoodleCtx.spec = map[string]interface{}{}
+			r.lmsMoodleCtx.namespaceName = "lms-site"
+			r.lmsMoodleCtx.moodle = newUnstructuredObject(MoodleGVK)
+			r.lmsMoodleCtx.moodle.SetName("lms-site")
+			r.lmsMoodleCtx.moodle.SetNamespace("lms-site")
+			r.lmsMoodleCtx.combinedMoodleSpec = map[string]interface{}{"moodleHost": "site.example.com", "moodleSize": int64(1)}
+		})
+
+		getLiveSpec := func() map[string]interface{} {
+			live := newUnstructuredObject(MoodleGVK)
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(r.lmsMoodleCtx.moodle), live)).To(Succeed())
+			spec, _, _ := unstructured.NestedMap(live.Object, "spec")
+			return spec
+		}
+
+		It("should overwrite drift by default", func() {
+			Expect(r.ReconcileDrift(ctx)).To(Succeed())
+			Expect(r.lmsMoodleCtx.driftPolicy).To(Equal(lmsv1alpha1.OverwriteDriftPolicy))
+			Expect(fieldKeys(r.lmsMoodleCtx.driftedFields)).To(Equal([]string{"extraConfig", "legacy", "moodleSize"}))
+			Expect(r.lmsMoodleCtx.combinedMoodleSpec).To(HaveKeyWithValue("moodleSize", int64(1)))
+		})
+
+		It("should leave drifted fields out of combined spec when reporting", func() {
+			r.lmsMoodleCtx.lmsMoodleTemplateSpec = map[string]interface{}{"drift": map[string]interface{}{"policy": lmsv1alpha1.ReportDriftPolicy}}
+
+			Expect(r.ReconcileDrift(ctx)).To(Succeed())
+			Expect(r.lmsMoodleCtx.driftPolicy).To(Equal(lmsv1alpha1.ReportDriftPolicy))
+			Expect(fieldKeys(r.lmsMoodleCtx.driftedFields)).To(Equal([]string{"extraConfig", "legacy", "moodleSize"}))
+			Expect(r.lmsMoodleCtx.combinedMoodleSpec).To(Equal(map[string]interface{}{"moodleHost": "site.example.com"}))
+			Expect(getLiveSpec()).To(HaveLen(4))
+		})
+
+		It("should only remove fields applied before when overwriting", func() {
+			r.lmsMoodleCtx.spec = map[string]interface{}{"drift": map[string]interface{}{"policy": lmsv1alpha1.OverwriteDriftPolicy}}
+
+			Expect(r.ReconcileDrift(ctx)).To(Succeed())
+			Expect(r.lmsMoodleCtx.combinedMoodleSpec).To(HaveKeyWithValue("moodleSize", int64(1)))
+			liveSpec := getLiveSpec()
+			Expect(liveSpec).NotTo(HaveKey("legacy"))
+			Expect(liveSpec).To(HaveKeyWithValue("extraConfig", "manual"))
+		})
+
+		It("should clear Drifted condition when drift is not checked", func() {
+			r.lmsMoodleCtx.driftedFields = []driftedField{{component: "moodle", path: []string{"moodleSize"}}}
+			_, err := SetCondition(r.lmsMoodleCtx.lmsMoodle, r.getDriftedCondition())
+			Expect(err).NotTo(HaveOccurred())
+
+			r.lmsMoodleCtx.namespaceQuotaOmit = true
+			changed, err := r.SetStandardConditions(ctx, lmsv1alpha1.SuspendedState)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			_, found, _ := getConditionByType(r.lmsMoodleCtx.lmsMoodle, DriftedConditionType)
+			Expect(found).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,206 @@
+package lms
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// EffectiveSpecsConfigMapName is the name of the ConfigMap in LMSMoodle namespace
+	// holding combined dependant specs. In a target namespace, it is prefixed by
+	// LMSMoodle base name, since the namespace may be shared
+	EffectiveSpecsConfigMapName string = "lmsmoodle-effective-specs"
+	// RedactedValue replaces secret values in published specs
+	RedactedValue string = "<redacted>"
+	// TemplateProvenance field value comes from LMSMoodleTemplate
+	TemplateProvenance string = "Template"
+	// SiteProvenance field value comes from LMSMoodle override
+	SiteProvenance string = "Site"
+	// OperatorProvenance field value is an operator default or injected by it
+	OperatorProvenance string = "Operator"
+)
+
+var (
+	// secretFieldRegexp matches spec fields holding secret values
+	secretFieldRegexp = regexp.MustCompile(`(?i)(pass|secret|token|jwt)`)
+	// secretReferenceFieldRegexp matches spec fields naming where a secret is, instead of holding it
+	secretReferenceFieldRegexp = regexp.MustCompile(`(EnvName|Ref|MetaName)$`)
+)
+
+// effectiveSpec is a combined dependant spec and its sources
+type effectiveSpec struct {
+	component    string
+	specKey      string
+	combinedSpec map[string]interface{}
+}
+
+// getEffectiveSpecs returns combined specs of present components
+func (r *LMSMoodleReconciler) getEffectiveSpecs() []effectiveSpec {
+	effectiveSpecs := []effectiveSpec{{"moodle", "moodleSpec", r.lmsMoodleCtx.combinedMoodleSpec}}
+	if r.lmsMoodleCtx.hasPostgres {
+		effectiveSpecs = append(effectiveSpecs, effectiveSpec{"postgres", "postgresSpec", r.lmsMoodleCtx.combinedPostgresSpec})
+	}
+	if r.lmsMoodleCtx.hasKeydb {
+		effectiveSpecs = append(effectiveSpecs, effectiveSpec{"keydb", "keydbSpec", r.lmsMoodleCtx.combinedKeydbSpec})
+	}
+	if r.lmsMoodleCtx.hasNfs {
+		effectiveSpecs = append(effectiveSpecs, effectiveSpec{"nfs", "nfsSpec", r.lmsMoodleCtx.combinedNfsSpec})
+	}
+	return effectiveSpecs
+}
+
+// getEffectiveSpecsStatus returns hash and number of fields by provenance of each combined spec
+func (r *LMSMoodleReconciler) getEffectiveSpecsStatus() map[string]interface{} {
+	effectiveSpecsStatus := map[string]interface{}{}
+	for _, effectiveSpec := range r.getEffectiveSpecs() {
+		fields := map[string]int64{}
+		for _, provenance := range r.getProvenance(effectiveSpec) {
+			fields[provenance]++
+		}
+
+		effectiveSpecStatus := map[string]interface{}{"hash": specHash(effectiveSpec.combinedSpec)}
+		for provenance, statusField := range map[string]string{SiteProvenance: "siteFields", TemplateProvenance: "templateFields", OperatorProvenance: "operatorFields"} {
+			if fields[provenance] > 0 {
+				effectiveSpecStatus[statusField] = fields[provenance]
+			}
+		}
+		effectiveSpecsStatus[effectiveSpec.component] = effectiveSpecStatus
+	}
+	return effectiveSpecsStatus
+}
+
+// getProvenance returns where each field of a combined spec comes from, by field path
+func (r *LMSMoodleReconciler) getProvenance(effectiveSpec effectiveSpec) map[string]string {
+	templateSpec, _, _ := unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodleTemplateSpec, effectiveSpec.specKey)
+	siteSpec, _, _ := unstructured.NestedMap(r.lmsMoodleCtx.spec, effectiveSpec.specKey)
+
+	provenance := map[string]string{}
+	setProvenance(provenance, "", effectiveSpec.combinedSpec, templateSpec, siteSpec)
+	return provenance
+}
+
+// ReconcileEffectiveSpecs publishes combined dependant specs, with secrets redacted, and
+// the provenance of each of their fields in a ConfigMap in LMSMoodle namespace
+func (r *LMSMoodleReconciler) ReconcileEffectiveSpecs(ctx context.Context) error {
+	log := log.FromContext(ctx)
+
+	data := map[string]string{}
+	for _, effectiveSpec := range r.getEffectiveSpecs() {
+		specYaml, err := yaml.Marshal(redactSpec(effectiveSpec.combinedSpec, false))
+		if err != nil {
+			return err
+		}
+		data[effectiveSpec.component+".yaml"] = string(specYaml)
+
+		provenanceYaml, err := yaml.Marshal(r.getProvenance(effectiveSpec))
+		if err != nil {
+			return err
+		}
+		data[effectiveSpec.component+".provenance.yaml"] = string(provenanceYaml)
+	}
+
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: r.effectiveSpecsConfigMapName(), Namespace: r.lmsMoodleCtx.namespaceName},
+		Data:       data,
+	}
+
+	log.V(1).Info("Publishing effective specs")
+	return r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, configMap)
+}
+
+// effectiveSpecsConfigMapName returns name of the ConfigMap holding combined dependant specs
+func (r *LMSMoodleReconciler) effectiveSpecsConfigMapName() string {
+	return EffectiveSpecsConfigMapNameOf(r.lmsMoodleCtx.targetNamespace, r.lmsMoodleCtx.networkPolicyBaseName)
+}
+
+// EffectiveSpecsConfigMapNameOf returns name of the ConfigMap holding combined dependant specs
+// of a LMSMoodle, given its target namespace, if any, and base name of its dependants
+func EffectiveSpecsConfigMapNameOf(targetNamespace string, baseName string) string {
+	if targetNamespace != "" {
+		return baseName + "-" + EffectiveSpecsConfigMapName
+	}
+	return EffectiveSpecsConfigMapName
+}
+
+// SetStatusEffectiveSpecs set status effective specs, if they differ
+// It returns a bool flag if effective specs were updated, and
+// any error
+func SetStatusEffectiveSpecs(objU *unstructured.Unstructured, effectiveSpecs map[string]interface{}) (bool, error) {
+	return setNestedFieldIfChanged(objU, effectiveSpecs, "status", "effectiveSpecs")
+}
+
+// setProvenance sets where each combined spec field comes from, by field path:
+// site if it equals site override, template if it equals template value, operator otherwise.
+// Maps are walked down to their fields, other values are a single field
+func setProvenance(provenance map[string]string, path string, combined interface{}, template interface{}, site interface{}) {
+	combinedMap, isMap := combined.(map[string]interface{})
+	if !isMap || len(combinedMap) == 0 {
+		switch {
+		case site != nil && reflect.DeepEqual(combined, site):
+			provenance[path] = SiteProvenance
+		case template != nil && reflect.DeepEqual(combined, template):
+			provenance[path] = TemplateProvenance
+		default:
+			provenance[path] = OperatorProvenance
+		}
+		return
+	}
+
+	templateMap, _ := template.(map[string]interface{})
+	siteMap, _ := site.(map[string]interface{})
+	keys := make([]string, 0, len(combinedMap))
+	for key := range combinedMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		setProvenance(provenance, fieldPath, combinedMap[key], templateMap[key], siteMap[key])
+	}
+}
+
+// redactSpec returns a copy of a spec with secret values redacted: values of fields
+// named like a secret and every header value, walking down maps and lists
+func redactSpec(spec map[string]interface{}, redactAll bool) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(spec))
+	for key, value := range spec {
+		redactField := redactAll || (secretFieldRegexp.MatchString(key) && !secretReferenceFieldRegexp.MatchString(key))
+		redacted[key] = redactValue(value, redactField || key == "headers")
+	}
+	return redacted
+}
+
+// redactValue returns a copy of a spec value, redacted if redactAll. Otherwise, fields
+// of maps, also within lists, are redacted as in redactSpec
+func redactValue(value interface{}, redactAll bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return redactSpec(v, redactAll)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = redactValue(item, redactAll)
+		}
+		return items
+	case nil:
+		return nil
+	default:
+		if redactAll {
+			return RedactedValue
+		}
+		return runtime.DeepCopyJSONValue(value)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Effective specs", func() {
+	Context("When redacting specs", func() {
+		It("should redact secret fields, also within lists", func() {
+			spec := map[string]interface{}{
+				"moodleNewInstanceFullname": "Demo",
+				"moodleNewAdminpassHash":    "hash",
+				"jwtSecretRef":              map[string]interface{}{"name": "notify", "key": "jwtSecret"},
+				"extraUsers": []interface{}{
+					map[string]interface{}{"name": "api", "password": "s3cr3t"},
+				},
+				"tokens": []interface{}{"a", "b"},
+				"routineStatusCrNotify": map[string]interface{}{
+					"headers": map[string]interface{}{"X-Tenant": "demo"},
+				},
+			}
+
+			Expect(redactSpec(spec, false)).To(Equal(map[string]interface{}{
+				"moodleNewInstanceFullname": "Demo",
+				"moodleNewAdminpassHash":    RedactedValue,
+				"jwtSecretRef":              map[string]interface{}{"name": "notify", "key": "jwtSecret"},
+				"extraUsers": []interface{}{
+					map[string]interface{}{"name": "api", "password": RedactedValue},
+				},
+				"tokens": []interface{}{RedactedValue, RedactedValue},
+				"routineStatusCrNotify": map[string]interface{}{
+					"headers": map[string]interface{}{"X-Tenant": RedactedValue},
+				},
+			}))
+			Expect(spec["extraUsers"].([]interface{})[0].(map[string]interface{})["password"]).To(Equal("s3cr3t"))
+		})
+	})
+
+	Context("When setting provenance", func() {
+		It("should tell site, template and operator fields apart, only counting them in status", func() {
+			r := &LMSMoodleReconciler{}
+			r.lmsMoodleCtx.lmsMoodleTemplateSpec = map[string]interface{}{
+				"moodleSpec": map[string]interface{}{"moodleHost": "template.example.com", "moodleSize": int64(1)},
+			}
+			r.lmsMoodleCtx.spec = map[string]interface{}{
+				"moodleSpec": map[string]interface{}{"moodleHost": "site.example.com"},
+			}
+			moodleSpec := effectiveSpec{"moodle", "moodleSpec", map[string]interface{}{
+				"moodleHost": "site.example.com",
+				"moodleSize": int64(1),
+				"routineStatusCrNotify": map[string]interface{}{
+					"uuid": "site",
+				},
+			}}
+
+			Expect(r.getProvenance(moodleSpec)).To(Equal(map[string]string{
+				"moodleHost":                 SiteProvenance,
+				"moodleSize":                 TemplateProvenance,
+				"routineStatusCrNotify.uuid": OperatorProvenance,
+			}))
+
+			r.lmsMoodleCtx.combinedMoodleSpec = moodleSpec.combinedSpec
+			Expect(r.getEffectiveSpecsStatus()).To(Equal(map[string]interface{}{
+				"moodle": map[string]interface{}{
+					"hash":           specHash(moodleSpec.combinedSpec),
+					"siteFields":     int64(1),
+					"templateFields": int64(1),
+					"operatorFields": int64(1),
+				},
+			}))
+		})
+	})
+})
@@ -0,0 +1,159 @@
+package lms
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// EventDeduplicationWindow is the time an identical event is not emitted again
+	EventDeduplicationWindow time.Duration = 10 * time.Minute
+)
+
+// Event reasons
+const (
+	StateChangedEventReason              string = "StateChanged"
+	CreatedEventReason                   string = "Created"
+	DeletedEventReason                   string = "Deleted"
+	ApplyFailedEventReason               string = "ApplyFailed"
+	FinalizingEventReason                string = "Finalizing"
+	FinalizedEventReason                 string = "Finalized"
+	TemplateNotFoundEventReason          string = "TemplateNotFound"
+	TemplateInUseEventReason             string = "TemplateInUse"
+	DriftedEventReason                   string = "Drifted"
+	NameCollisionEventReason             string = "NameCollision"
+	TargetNamespaceNotFoundEventReason   string = "TargetNamespaceNotFound"
+	TargetNamespaceNotAllowedEventReason string = "TargetNamespaceNotAllowed"
+	BoundEventReason                     string = "Bound"
+	ReleasedEventReason                  string = "Released"
+	ClaimNotAcceptedEventReason          string = "ClaimNotAccepted"
+	ClaimLostEventReason                 string = "ClaimLost"
+	OverridesNotAllowedEventReason       string = "OverridesNotAllowed"
+)
+
+// DeduplicatingEventRecorder wraps an event recorder, skipping identical events
+// for the same object emitted within a window, so requeue loops do not flood etcd
+type DeduplicatingEventRecorder struct {
+	record.EventRecorder
+	window time.Duration
+	mu     sync.Mutex
+	// emitted holds when each event was last emitted, by object UID
+	emitted map[types.UID]map[string]time.Time
+}
+
+// NewDeduplicatingEventRecorder returns an event recorder deduplicating events within a window
+func NewDeduplicatingEventRecorder(recorder record.EventRecorder, window time.Duration) *DeduplicatingEventRecorder {
+	return &DeduplicatingEventRecorder{
+		EventRecorder: recorder,
+		window:        window,
+		emitted:       map[types.UID]map[string]time.Time{},
+	}
+}
+
+// Forget forgets events emitted for an object, once it is finalized
+func (d *DeduplicatingEventRecorder) Forget(object client.Object) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.emitted, object.GetUID())
+}
+
+// forgetEvents forgets events emitted for an object, if the recorder deduplicates them
+func forgetEvents(recorder record.EventRecorder, object client.Object) {
+	if deduplicatingRecorder, ok := recorder.(*DeduplicatingEventRecorder); ok {
+		deduplicatingRecorder.Forget(object)
+	}
+}
+
+// Event emits an event, unless it was already emitted within the window
+func (d *DeduplicatingEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if d.isDuplicated(object, eventtype, reason, message) {
+		return
+	}
+	d.EventRecorder.Event(object, eventtype, reason, message)
+}
+
+// Eventf emits a formatted event, unless it was already emitted within the window
+func (d *DeduplicatingEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	d.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf emits an annotated event, unless it was already emitted within the window
+func (d *DeduplicatingEventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if d.isDuplicated(object, eventtype, reason, message) {
+		return
+	}
+	d.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+}
+
+// isDuplicated returns whether an identical event was emitted for the same object within
+// the window, remembering it otherwise. Events of objects with no UID are not deduplicated
+func (d *DeduplicatingEventRecorder) isDuplicated(object runtime.Object, eventtype, reason, message string) bool {
+	obj, ok := object.(client.Object)
+	if !ok || obj.GetUID() == "" {
+		return false
+	}
+	key := eventtype + "/" + reason + "/" + message
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	emitted, found := d.emitted[obj.GetUID()]
+	if !found {
+		emitted = map[string]time.Time{}
+		d.emitted[obj.GetUID()] = emitted
+	}
+
+	now := time.Now()
+	if emittedAt, found := emitted[key]; found && now.Sub(emittedAt) < d.window {
+		return true
+	}
+
+	// prune expired events of the object
+	for emittedKey, emittedAt := range emitted {
+		if now.Sub(emittedAt) >= d.window {
+			delete(emitted, emittedKey)
+		}
+	}
+	emitted[key] = now
+
+	return false
+}
+
+// objectKind returns the kind of an object, from the scheme if not set
+func objectKind(scheme *runtime.Scheme, obj client.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	if gvk, err := apiutil.GVKForObject(obj, scheme); err == nil {
+		return gvk.Kind
+	}
+	return "resource"
+}
+
+// stateEventType returns the event type for a state
+func stateEventType(state string) string {
+	if state == lmsv1alpha1.FailedState || strings.HasSuffix(state, "Failed") || strings.HasSuffix(state, "Error") {
+		return corev1.EventTypeWarning
+	}
+	return corev1.EventTypeNormal
+}
+
+// stateChangedMessage returns the message of a state transition event
+func stateChangedMessage(previousState string, state string) string {
+	if previousState == "" {
+		return fmt.Sprintf("State set to '%s'", state)
+	}
+	return fmt.Sprintf("State changed from '%s' to '%s'", previousState, state)
+}
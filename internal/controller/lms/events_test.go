@@ -0,0 +1,74 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Deduplicating event recorder", func() {
+	var fakeRecorder *record.FakeRecorder
+	var recorder *DeduplicatingEventRecorder
+
+	BeforeEach(func() {
+		fakeRecorder = record.NewFakeRecorder(10)
+		recorder = NewDeduplicatingEventRecorder(fakeRecorder, EventDeduplicationWindow)
+	})
+
+	lmsMoodleWithUID := func(uid string) *lmsv1alpha1.LMSMoodle {
+		lmsMoodle := &lmsv1alpha1.LMSMoodle{}
+		lmsMoodle.SetUID(types.UID(uid))
+		return lmsMoodle
+	}
+
+	It("should emit identical events of the same object once within the window", func() {
+		siteA := lmsMoodleWithUID("a")
+		recorder.Event(siteA, corev1.EventTypeNormal, CreatedEventReason, "Created Moodle 'a'")
+		recorder.Eventf(siteA, corev1.EventTypeNormal, CreatedEventReason, "Created Moodle '%s'", "a")
+		recorder.Event(siteA, corev1.EventTypeNormal, DeletedEventReason, "Deleted Moodle 'a'")
+		recorder.Event(lmsMoodleWithUID("b"), corev1.EventTypeNormal, CreatedEventReason, "Created Moodle 'a'")
+		Expect(fakeRecorder.Events).To(HaveLen(3))
+	})
+
+	It("should emit events again once the object is forgotten", func() {
+		siteA := lmsMoodleWithUID("a")
+		recorder.Event(siteA, corev1.EventTypeNormal, CreatedEventReason, "Created Moodle 'a'")
+		recorder.Event(lmsMoodleWithUID("b"), corev1.EventTypeNormal, CreatedEventReason, "Created Moodle 'b'")
+		Expect(recorder.emitted).To(HaveLen(2))
+
+		forgetEvents(recorder, siteA)
+		Expect(recorder.emitted).To(HaveLen(1))
+		recorder.Event(siteA, corev1.EventTypeNormal, CreatedEventReason, "Created Moodle 'a'")
+		Expect(fakeRecorder.Events).To(HaveLen(3))
+	})
+
+	It("should prune expired events of an object", func() {
+		recorder = NewDeduplicatingEventRecorder(fakeRecorder, 0)
+		siteA := lmsMoodleWithUID("a")
+		recorder.Event(siteA, corev1.EventTypeNormal, CreatedEventReason, "Created Moodle 'a'")
+		recorder.Event(siteA, corev1.EventTypeNormal, DeletedEventReason, "Deleted Moodle 'a'")
+		Expect(recorder.emitted[siteA.GetUID()]).To(HaveLen(1))
+		Expect(fakeRecorder.Events).To(HaveLen(2))
+	})
+})
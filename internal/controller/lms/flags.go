@@ -0,0 +1,114 @@
+package lms
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+// BindNamingPolicyFlags binds flags of the naming policy of namespace and dependant resources
+func BindNamingPolicyFlags(fs *flag.FlagSet, namingPolicy *NamingPolicy) {
+	fs.StringVar(&namingPolicy.Prefix, "name-prefix", DefaultNamingPolicy.Prefix,
+		"The prefix of namespace and dependant resource names, unless the LMSMoodle name already has it.")
+	fs.IntVar(&namingPolicy.MaxLength, "name-max-length", DefaultNamingPolicy.MaxLength,
+		"The maximum length of dependant resource names. Longer ones are truncated. Zero disables truncation.")
+	fs.BoolVar(&namingPolicy.HashSuffix, "name-hash-suffix", DefaultNamingPolicy.HashSuffix,
+		"If set, truncated names end with a hash of the untruncated name, avoiding collisions. "+
+			"Names already in use, kept in LMSMoodle status, are not changed.")
+}
+
+// stringsFlag is a flag of strings that can be set multiple times
+type stringsFlag struct {
+	values *[]string
+}
+
+func (f stringsFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f stringsFlag) Set(value string) error {
+	*f.values = append(*f.values, value)
+	return nil
+}
+
+// labelSelectorFlag is a flag of a label selector
+type labelSelectorFlag struct {
+	selector *labels.Selector
+}
+
+func (f labelSelectorFlag) String() string {
+	if f.selector == nil || *f.selector == nil {
+		return ""
+	}
+	return (*f.selector).String()
+}
+
+func (f labelSelectorFlag) Set(value string) error {
+	if value == "" {
+		return fmt.Errorf("empty label selector")
+	}
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return err
+	}
+	*f.selector = selector
+	return nil
+}
+
+// BindNamespacePolicyFlags binds flags of the namespace policy of LMSMoodles
+func BindNamespacePolicyFlags(fs *flag.FlagSet, namespacePolicy *NamespacePolicy) {
+	fs.Var(stringsFlag{&namespacePolicy.SecretNamespaces}, "secret-namespace",
+		"A namespace where secrets referenced by LMSMoodles, such as notify secrets, can be read from, "+
+			"besides the namespace created for each LMSMoodle. It can be set multiple times. "+
+			"Secrets in target namespaces can only be read if they are set.")
+	fs.Var(stringsFlag{&namespacePolicy.TargetNamespaces}, "target-namespace",
+		"A namespace LMSMoodles can set as target namespace, deploying dependants in it. "+
+			"It can be set multiple times. No target namespace is allowed by default.")
+	fs.Var(labelSelectorFlag{&namespacePolicy.TargetNamespaceSelector}, "target-namespace-selector",
+		"A label selector of namespaces LMSMoodles can set as target namespace, such as lms.krestomat.io/target=true. "+
+			"System namespaces and the operator namespace are never allowed.")
+}
+
+// propagationRulesFlag is a flag of propagation rules that can be set multiple times
+type propagationRulesFlag struct {
+	rules *[]lmsv1alpha1.PropagationRule
+}
+
+func (f propagationRulesFlag) String() string {
+	if f.rules == nil {
+		return ""
+	}
+	var rules []string
+	for _, rule := range *f.rules {
+		rules = append(rules, fmt.Sprintf("%+v", rule))
+	}
+	return strings.Join(rules, ";")
+}
+
+func (f propagationRulesFlag) Set(value string) error {
+	rule, err := ParsePropagationRule(value)
+	if err != nil {
+		return err
+	}
+	*f.rules = append(*f.rules, rule)
+	return nil
+}
+
+// BindPropagationPolicyFlags binds flags of the propagation policy of labels and annotations
+func BindPropagationPolicyFlags(fs *flag.FlagSet, propagationPolicy *PropagationPolicy) {
+	fs.Var(propagationRulesFlag{&propagationPolicy.Labels}, "propagate-label",
+		"A rule propagating label keys to targets, such as prefix:example.com/=Namespace,Moodle,Pods or "+
+			"regex:^team$=LMSMoodle. Rules starting with ! exclude keys. For each target, the first rule "+
+			"matching a key applies. It can be set multiple times, replacing default rules. "+
+			"Targets: LMSMoodle, from its template, Namespace, Moodle, Postgres, Nfs, Keydb and Pods.")
+	fs.Var(propagationRulesFlag{&propagationPolicy.Annotations}, "propagate-annotation",
+		"A rule propagating annotation keys to targets, as --propagate-label does, except to Pods. "+
+			"Annotations do not propagate by default.")
+}
@@ -0,0 +1,29 @@
+package lms
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// types from ansible operators
+var (
+	MoodleGVK = schema.GroupVersionKind{
+		Group:   "m4e.krestomat.io",
+		Version: "v1alpha1",
+		Kind:    "Moodle",
+	}
+	NfsGVK = schema.GroupVersionKind{
+		Group:   "nfs.krestomat.io",
+		Version: "v1alpha1",
+		Kind:    "Ganesha",
+	}
+	KeydbGVK = schema.GroupVersionKind{
+		Group:   "keydb.krestomat.io",
+		Version: "v1alpha1",
+		Kind:    "Keydb",
+	}
+	PostgresGVK = schema.GroupVersionKind{
+		Group:   "postgres.krestomat.io",
+		Version: "v1alpha1",
+		Kind:    "Postgres",
+	}
+)
@@ -0,0 +1,189 @@
+package lms
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// StateHistoryDefaultLimit of transitions kept in LMSMoodle status
+	StateHistoryDefaultLimit int64 = 10
+	// StateHistoryConfigMapDefaultLimit of transitions kept in the history ConfigMap
+	StateHistoryConfigMapDefaultLimit int64 = 500
+	// StateHistoryConfigMapMaxBytes is the size the history ConfigMap data is kept under
+	StateHistoryConfigMapMaxBytes int = 512 * 1024
+	// StateHistoryConfigMapName is the name of the history ConfigMap in LMSMoodle namespace.
+	// In a target namespace, it is prefixed by LMSMoodle base name, since the namespace may be shared
+	StateHistoryConfigMapName string = "lmsmoodle-state-history"
+	// StateHistoryConfigMapKey is the ConfigMap key holding transitions as a JSON list
+	StateHistoryConfigMapKey string = "history.json"
+)
+
+// AppendStateHistory appends a transition to a settled state to LMSMoodle status history, keeping
+// the last ones, and mirrors it to the history ConfigMap, if enabled. Intermediate states, such as
+// PostgresCreating, are not recorded, so previous state is the last one recorded
+func (r *LMSMoodleReconciler) AppendStateHistory(ctx context.Context, state string, transitionTime time.Time) error {
+	log := log.FromContext(ctx)
+
+	history, _, _ := unstructured.NestedSlice(r.lmsMoodleCtx.lmsMoodle.Object, "status", "history")
+	var previousState string
+	if len(history) > 0 {
+		previousState, _, _ = unstructured.NestedString(history[len(history)-1].(map[string]interface{}), "state")
+	}
+	if !isHistoryState(state) || state == previousState {
+		return nil
+	}
+
+	transition := map[string]interface{}{
+		"state":     state,
+		"reason":    r.stateTransitionReason(state),
+		"timestamp": transitionTime.UTC().Format(time.RFC3339),
+	}
+	if previousState != "" {
+		transition["previousState"] = previousState
+	}
+	if r.lmsMoodleCtx.lmsMoodleTemplate != nil && r.lmsMoodleCtx.lmsMoodleTemplate.GetName() != "" {
+		transition["templateName"] = r.lmsMoodleCtx.lmsMoodleTemplate.GetName()
+		transition["templateGeneration"] = r.lmsMoodleCtx.lmsMoodleTemplate.GetGeneration()
+	}
+
+	history = lastItems(append(history, transition), r.getStateHistoryLimit("limit", StateHistoryDefaultLimit))
+	if err := unstructured.SetNestedSlice(r.lmsMoodleCtx.lmsMoodle.Object, history, "status", "history"); err != nil {
+		return err
+	}
+
+	// mirror to ConfigMap, while namespace is not being removed, unless paused
+	if r.getStateHistoryConfigMapEnabled() && !r.lmsMoodleCtx.markedToBeDeleted && !r.lmsMoodleCtx.paused {
+		if err := r.mirrorStateHistory(ctx, transition); err != nil {
+			// history in status is kept anyway
+			log.Error(err, "Unable to mirror state history to ConfigMap")
+		}
+	}
+
+	return nil
+}
+
+// isHistoryState returns whether a state is recorded in history: a settled state, or
+// one set on failure, pause or once terminated
+func isHistoryState(state string) bool {
+	switch state {
+	case lmsv1alpha1.FailedState, lmsv1alpha1.PausedState, lmsv1alpha1.TerminatedState:
+		return true
+	}
+	return isSettledState(state)
+}
+
+// stateTransitionReason returns why LMSMoodle state changed
+func (r *LMSMoodleReconciler) stateTransitionReason(state string) string {
+	switch {
+	case state == lmsv1alpha1.FailedState && r.lmsMoodleCtx.failedReason != "":
+		return r.lmsMoodleCtx.failedReason
+	case r.lmsMoodleCtx.markedToBeDeleted:
+		return "Deleted"
+	case state == lmsv1alpha1.PausedState:
+		return "Paused"
+	case state == lmsv1alpha1.SuspendedState || state == lmsv1alpha1.PartiallySuspendedState:
+		return "DesiredStateChanged"
+	}
+
+	observedGeneration, _, _ := unstructured.NestedInt64(r.lmsMoodleCtx.lmsMoodle.Object, "status", "observedGeneration")
+	if observedGeneration != r.lmsMoodleCtx.lmsMoodle.GetGeneration() {
+		return "SpecChanged"
+	}
+
+	return "ComponentStatusChanged"
+}
+
+// mirrorStateHistory appends a state transition to the history ConfigMap in LMSMoodle namespace,
+// keeping the last ones under its size limit
+func (r *LMSMoodleReconciler) mirrorStateHistory(ctx context.Context, transition map[string]interface{}) error {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	// read current history, if any
+	var history []interface{}
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: r.stateHistoryConfigMapName(), Namespace: r.lmsMoodleCtx.namespaceName}, configMap); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if historyJson, found := configMap.Data[StateHistoryConfigMapKey]; found {
+		if err := json.Unmarshal([]byte(historyJson), &history); err != nil {
+			log.FromContext(ctx).Error(err, "Dropping unreadable state history ConfigMap")
+			history = nil
+		}
+	}
+
+	history = lastItems(append(history, transition), r.getStateHistoryLimit("configMapLimit", StateHistoryConfigMapDefaultLimit))
+	historyJson, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	for len(historyJson) > StateHistoryConfigMapMaxBytes && len(history) > 1 {
+		history = history[1:]
+		if historyJson, err = json.Marshal(history); err != nil {
+			return err
+		}
+	}
+
+	configMap = &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: r.stateHistoryConfigMapName(), Namespace: r.lmsMoodleCtx.namespaceName},
+		Data:       map[string]string{StateHistoryConfigMapKey: string(historyJson)},
+	}
+
+	return r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, configMap)
+}
+
+// stateHistoryConfigMapName returns name of the history ConfigMap
+func (r *LMSMoodleReconciler) stateHistoryConfigMapName() string {
+	return StateHistoryConfigMapNameOf(r.lmsMoodleCtx.targetNamespace, r.lmsMoodleCtx.networkPolicyBaseName)
+}
+
+// StateHistoryConfigMapNameOf returns name of the history ConfigMap of a LMSMoodle, given
+// its target namespace, if any, and base name of its dependants
+func StateHistoryConfigMapNameOf(targetNamespace string, baseName string) string {
+	if targetNamespace != "" {
+		return baseName + "-" + StateHistoryConfigMapName
+	}
+	return StateHistoryConfigMapName
+}
+
+// getStateHistoryConfigMapEnabled returns whether state history is mirrored to a ConfigMap,
+// from LMSMoodle spec first, then from its template
+func (r *LMSMoodleReconciler) getStateHistoryConfigMapEnabled() bool {
+	for _, spec := range []map[string]interface{}{r.lmsMoodleCtx.spec, r.lmsMoodleCtx.lmsMoodleTemplateSpec} {
+		if enabled, found, _ := unstructured.NestedBool(spec, "stateHistory", "configMap"); found {
+			return enabled
+		}
+	}
+	return false
+}
+
+// getStateHistoryLimit returns a state history limit, from LMSMoodle spec first,
+// then from its template
+func (r *LMSMoodleReconciler) getStateHistoryLimit(field string, defaultLimit int64) int64 {
+	for _, spec := range []map[string]interface{}{r.lmsMoodleCtx.spec, r.lmsMoodleCtx.lmsMoodleTemplateSpec} {
+		if limit, found, _ := unstructured.NestedInt64(spec, "stateHistory", field); found && limit > 0 {
+			return limit
+		}
+	}
+	return defaultLimit
+}
+
+// lastItems returns the last items of a list, up to limit
+func lastItems(items []interface{}, limit int64) []interface{} {
+	if int64(len(items)) <= limit {
+		return items
+	}
+	return items[int64(len(items))-limit:]
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("State history", func() {
+	var r *LMSMoodleReconciler
+	now := time.Now()
+
+	BeforeEach(func() {
+		r = &LMSMoodleReconciler{}
+		r.lmsMoodleCtx.lmsMoodle = &unstructured.Unstructured{Object: map[string]interface{}{}}
+	})
+
+	recordedStates := func() (states []string, previousStates []string) {
+		history, _, _ := unstructured.NestedSlice(r.lmsMoodleCtx.lmsMoodle.Object, "status", "history")
+		for _, transition := range history {
+			state, _, _ := unstructured.NestedString(transition.(map[string]interface{}), "state")
+			previousState, _, _ := unstructured.NestedString(transition.(map[string]interface{}), "previousState")
+			states = append(states, state)
+			previousStates = append(previousStates, previousState)
+		}
+		return states, previousStates
+	}
+
+	It("should only record settled states, from the last one recorded", func() {
+		for _, state := range []string{"PostgresCreating", lmsv1alpha1.ReadyState, "SuspendingMoodleReady", lmsv1alpha1.SuspendedState, "MoodleCreating", lmsv1alpha1.ReadyState} {
+			Expect(r.AppendStateHistory(ctx, state, now)).To(Succeed())
+		}
+
+		states, previousStates := recordedStates()
+		Expect(states).To(Equal([]string{lmsv1alpha1.ReadyState, lmsv1alpha1.SuspendedState, lmsv1alpha1.ReadyState}))
+		Expect(previousStates).To(Equal([]string{"", lmsv1alpha1.ReadyState, lmsv1alpha1.SuspendedState}))
+	})
+
+	It("should not record the same state again after intermediate ones", func() {
+		for _, state := range []string{lmsv1alpha1.ReadyState, "MoodleUpgrading", lmsv1alpha1.ReadyState, lmsv1alpha1.FailedState, lmsv1alpha1.TerminatingState, lmsv1alpha1.TerminatedState} {
+			Expect(r.AppendStateHistory(ctx, state, now)).To(Succeed())
+		}
+
+		states, _ := recordedStates()
+		Expect(states).To(Equal([]string{lmsv1alpha1.ReadyState, lmsv1alpha1.FailedState, lmsv1alpha1.TerminatedState}))
+	})
+
+	It("should prefix the history ConfigMap name by base name in a target namespace", func() {
+		Expect(StateHistoryConfigMapNameOf("", "lms-site")).To(Equal(StateHistoryConfigMapName))
+		Expect(StateHistoryConfigMapNameOf("shared", "lms-site")).To(Equal("lms-site-" + StateHistoryConfigMapName))
+	})
+})
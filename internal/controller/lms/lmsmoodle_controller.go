@@ -19,16 +19,19 @@ package lms
 import (
 	"context"
 	"fmt"
-	"strings"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -39,6 +42,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	"github.com/krestomatio/lms-moodle-operator/internal/notifier"
 )
 
 const (
@@ -46,6 +50,13 @@ const (
 	LMSMoodleFinalizer         string = "lms.krestomat.io/finalizer"
 	LMSMoodleTemplateNameIndex string = "spec.lmsMoodleTemplate"
 	TruncateCharactersInName   int    = 17
+	// ReadinessRequeueBaseDelay initial delay when waiting for a dependant to be ready
+	ReadinessRequeueBaseDelay time.Duration = 5 * time.Second
+	// ReadinessRequeueMaxDelay maximum delay when waiting for a dependant to be ready
+	ReadinessRequeueMaxDelay time.Duration = 5 * time.Minute
+	// NotifySecretName is the name of the secret in LMSMoodle namespace holding status
+	// notify values copied from secrets. In a target namespace, it is prefixed by LMSMoodle base name
+	NotifySecretName string = "lmsmoodle-notify"
 )
 
 type LMSMoodleReconcilerContext struct {
@@ -53,6 +64,7 @@ type LMSMoodleReconcilerContext struct {
 	hasKeydb                           bool
 	hasPostgres                        bool
 	markedToBeDeleted                  bool
+	paused                             bool
 	moodleSpecFound                    bool
 	nfsSpecFound                       bool
 	keydbSpecFound                     bool
@@ -63,7 +75,14 @@ type LMSMoodleReconcilerContext struct {
 	name                               string
 	lmsMoodleTemplateName              string
 	desiredState                       string
+	moodleDesiredState                 string
+	moodleCronDesiredState             string
+	nfsDesiredState                    string
+	keydbDesiredState                  string
+	postgresDesiredState               string
 	namespaceName                      string
+	targetNamespace                    string
+	unsetFields                        []string
 	networkPolicyBaseName              string
 	moodleName                         string
 	nfsName                            string
@@ -92,6 +111,25 @@ type LMSMoodleReconcilerContext struct {
 	namespace                          *corev1.Namespace
 	lmsMoodleNetpolOmit                bool
 	lmsMoodleDefaultNetpol             *networkingv1.NetworkPolicy
+	namespaceQuotaOmit                 bool
+	namespaceQuota                     *corev1.ResourceQuota
+	namespaceQuotaApplied              bool
+	quotaExceeded                      []string
+	namespaceLimitRangeOmit            bool
+	namespaceLimitRange                *corev1.LimitRange
+	namespaceLimitRangeApplied         bool
+	notifySecret                       *corev1.Secret
+	requeueAfter                       time.Duration
+	failedReason                       string
+	failedMessage                      string
+	timelineUpdated                    bool
+	driftChecked                       bool
+	driftPolicy                        string
+	driftedFields                      []driftedField
+	overridesRestricted                bool
+	overridesRejected                  bool
+	notAllowedOverrides                []notAllowedOverride
+	propagationPolicy                  *compiledPropagationPolicy
 }
 
 type LMSMoodleTemplateNotFoundError struct {
@@ -106,8 +144,16 @@ func (f *LMSMoodleTemplateNotFoundError) Error() string {
 type LMSMoodleReconciler struct {
 	client.Client
 	Scheme                                   *runtime.Scheme
+	APIReader                                client.Reader
+	Recorder                                 record.EventRecorder
+	Notifier                                 *notifier.Notifier
 	MoodleGVK, NfsGVK, KeydbGVK, PostgresGVK schema.GroupVersionKind
+	NamingPolicy                             NamingPolicy
+	PropagationPolicy                        PropagationPolicy
+	NamespacePolicy                          NamespacePolicy
 	lmsMoodleCtx                             LMSMoodleReconcilerContext
+	// readinessAttempts counts attempts waiting for each lms moodle component to be ready
+	readinessAttempts sync.Map
 }
 
 // +kubebuilder:rbac:groups=lms.krestomat.io,resources=lmsmoodles,verbs=get;list;watch;create;update;patch;delete
@@ -117,8 +163,12 @@ type LMSMoodleReconciler struct {
 // +kubebuilder:rbac:groups=nfs.krestomat.io,resources=ganeshas,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=keydb.krestomat.io,resources=keydbs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=postgres.krestomat.io,resources=postgres,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create;patch
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=resourcequotas;limitranges,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;create;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create;patch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -135,12 +185,29 @@ func (r *LMSMoodleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	// Vars
 	r.lmsMoodleCtx.name = req.Name
+	r.lmsMoodleCtx.requeueAfter = 0
+	r.lmsMoodleCtx.failedReason = ""
+	r.lmsMoodleCtx.failedMessage = ""
+	r.lmsMoodleCtx.timelineUpdated = false
+	r.lmsMoodleCtx.driftChecked = false
+	r.lmsMoodleCtx.driftPolicy = ""
+	r.lmsMoodleCtx.driftedFields = nil
+	r.lmsMoodleCtx.propagationPolicy = nil
 
 	// Prepare resource, saved any error for later
 	if err := r.reconcilePrepare(ctx); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Pause logic
+	if r.lmsMoodleCtx.paused {
+		if requeue, err := r.reconcilePaused(ctx); err != nil {
+			return ctrl.Result{}, err
+		} else {
+			return ctrl.Result{Requeue: requeue}, nil
+		}
+	}
+
 	// Finalize logic
 	if finalized, requeue, err := r.reconcileFinalize(ctx); err != nil {
 		return ctrl.Result{}, err
@@ -148,6 +215,12 @@ func (r *LMSMoodleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{Requeue: requeue}, nil
 	}
 
+	// Rejected overrides, dependants are left as they are until they are removed
+	if r.lmsMoodleCtx.overridesRejected {
+		_, err := r.updateLMSMoodleStatus(ctx)
+		return ctrl.Result{}, err
+	}
+
 	// Suspend logic
 	if r.lmsMoodleCtx.desiredState == lmsv1alpha1.SuspendedState {
 		if requeue, err := r.reconcileSuspend(ctx); err != nil {
@@ -160,6 +233,8 @@ func (r *LMSMoodleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// Present resources
 	if requeue, err := r.reconcilePresent(ctx); err != nil {
 		return ctrl.Result{}, err
+	} else if requeue && r.lmsMoodleCtx.requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: r.lmsMoodleCtx.requeueAfter}, nil
 	} else {
 		return ctrl.Result{Requeue: requeue}, nil
 	}
@@ -170,14 +245,101 @@ func (r *LMSMoodleReconciler) reconcilePrepare(ctx context.Context) error {
 	log := log.FromContext(ctx)
 	log.V(1).Info("Reconcile set")
 
-	// set base name for dependant resources
-	baseName := truncate(LMSMoodleNamePrefix+r.lmsMoodleCtx.name, TruncateCharactersInName)
-	baseNamespace := LMSMoodleNamePrefix + r.lmsMoodleCtx.name
-	// if lmsMoodle name already include the prefix, do not use it
-	if hasPrefix := strings.HasPrefix(r.lmsMoodleCtx.name, LMSMoodleNamePrefix); hasPrefix {
-		baseName = truncate(r.lmsMoodleCtx.name, TruncateCharactersInName)
-		baseNamespace = r.lmsMoodleCtx.name
+	// Fetch LMSMoodle instance
+	r.lmsMoodleCtx.lmsMoodle = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+	if err := r.Get(ctx, types.NamespacedName{Name: r.lmsMoodleCtx.name}, r.lmsMoodleCtx.lmsMoodle); err != nil {
+		log.V(1).Info(err.Error())
+		if errors.IsNotFound(err) {
+			lmsMoodleMetrics.Forget(r.lmsMoodleCtx.name)
+			r.forgetReadinessAttempts(r.lmsMoodleCtx.name)
+		}
+		return err
+	} else {
+		// whether lmsMoodle is marked to be deleted
+		r.lmsMoodleCtx.markedToBeDeleted = r.lmsMoodleCtx.lmsMoodle.GetDeletionTimestamp() != nil
+	}
+	r.setLMSMoodleSpec()
+
+	// paused lmsMoodle is left untouched, without preparing anything to apply
+	if r.lmsMoodleCtx.paused {
+		r.preparePaused()
+		return nil
 	}
+
+	// Fetch lmsMoodleTemplate spec
+	r.lmsMoodleCtx.lmsMoodleTemplate = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleTemplate"))
+	if err := r.Get(ctx, types.NamespacedName{Name: r.lmsMoodleCtx.lmsMoodleTemplateName}, r.lmsMoodleCtx.lmsMoodleTemplate); err != nil {
+		log.Error(err, "LMSMoodleTemplate not found")
+		lmsMoodleTemplateNotFoundError := &LMSMoodleTemplateNotFoundError{r.lmsMoodleCtx.lmsMoodleTemplateName}
+		r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, corev1.EventTypeWarning, TemplateNotFoundEventReason, lmsMoodleTemplateNotFoundError.Error())
+		return lmsMoodleTemplateNotFoundError
+	}
+
+	return r.prepareSite(ctx, prepareSteps{
+		setSiteLabels:         r.setSiteLabels,
+		resolveDependantNames: r.resolveDependantNames,
+		getSecretKey:          r.getSecretKey,
+	})
+}
+
+// prepareSteps are the steps of prepareSite reaching the cluster, which Render replaces
+type prepareSteps struct {
+	// setSiteLabels sets lms moodle labels and annotations
+	setSiteLabels func(ctx context.Context) error
+	// resolveDependantNames sets names of namespace and dependant resources
+	resolveDependantNames func(ctx context.Context) error
+	// getSecretKey reads secret keys referenced in notify spec
+	getSecretKey func(ctx context.Context, secretKeySelector lmsv1alpha1.SecretKeySelector) (string, error)
+}
+
+// prepareSite combines lms moodle and lms moodle template specs into the resources to
+// apply, the same way for reconcile and Render. Should be used once lmsMoodle and
+// lmsMoodleTemplate are set
+func (r *LMSMoodleReconciler) prepareSite(ctx context.Context, steps prepareSteps) error {
+	log := log.FromContext(ctx)
+
+	r.setLMSMoodleTemplateSpec()
+
+	// leave out overrides not allowed by lmsMoodleTemplate
+	if err := r.applyOverridePolicy(); err != nil {
+		return err
+	}
+	if len(r.lmsMoodleCtx.notAllowedOverrides) > 0 {
+		notAllowedOverridesMessage := notAllowedOverridesMessage(r.lmsMoodleCtx.notAllowedOverrides, r.lmsMoodleCtx.overridesRejected)
+		log.Info("LMSMoodle overrides not allowed by LMSMoodleTemplate", "Overrides", len(r.lmsMoodleCtx.notAllowedOverrides), "Rejected", r.lmsMoodleCtx.overridesRejected)
+		r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, corev1.EventTypeWarning, OverridesNotAllowedEventReason, notAllowedOverridesMessage)
+	}
+
+	// set labels
+	if err := steps.setSiteLabels(ctx); err != nil {
+		return err
+	}
+
+	// set names of namespace and dependant resources
+	if err := steps.resolveDependantNames(ctx); err != nil {
+		log.Error(err, "Couldn't set dependant names")
+		if nameCollisionError, isNameCollision := err.(*NameCollisionError); isNameCollision {
+			r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, corev1.EventTypeWarning, NameCollisionEventReason, nameCollisionError.Error())
+		}
+		return err
+	}
+
+	// combine specs of dependant components
+	if err := r.prepareDependants(ctx); err != nil {
+		return err
+	}
+
+	// resolve secrets when it has to notify status to a url
+	if err := r.resolveNotifySecrets(ctx, steps.getSecretKey); err != nil {
+		log.Error(err, "Couldn't resolve status notify secrets")
+		return err
+	}
+
+	return nil
+}
+
+// setDependantNames sets names of namespace and dependant resources, from namespace and base names
+func (r *LMSMoodleReconciler) setDependantNames(baseNamespace string, baseName string) {
 	// set namespace name. It must start with an alphabetic character
 	r.lmsMoodleCtx.namespaceName = baseNamespace
 	// set network policy base name. It must start with an alphabetic character
@@ -201,48 +363,71 @@ func (r *LMSMoodleReconciler) reconcilePrepare(ctx context.Context) error {
 	// namespaces and names
 	r.lmsMoodleCtx.moodle.SetName(r.lmsMoodleCtx.moodleName)
 	r.lmsMoodleCtx.moodle.SetNamespace(r.lmsMoodleCtx.namespaceName)
+}
 
-	// Fetch LMSMoodle instance
-	r.lmsMoodleCtx.lmsMoodle = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
-	if err := r.Get(ctx, types.NamespacedName{Name: r.lmsMoodleCtx.name}, r.lmsMoodleCtx.lmsMoodle); err != nil {
-		log.V(1).Info(err.Error())
-		return err
-	} else {
-		// whether lmsMoodle is marked to be deleted
-		r.lmsMoodleCtx.markedToBeDeleted = r.lmsMoodleCtx.lmsMoodle.GetDeletionTimestamp() != nil
-	}
+// setLMSMoodleSpec reads lms moodle spec into context. Should be used once lmsMoodle is set
+func (r *LMSMoodleReconciler) setLMSMoodleSpec() {
 	r.lmsMoodleCtx.spec, _, _ = unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodle.UnstructuredContent(), "spec")
+	r.setLMSMoodleSpecFields()
+}
+
+// setLMSMoodleSpecFields reads lms moodle spec fields into context, from spec in context
+func (r *LMSMoodleReconciler) setLMSMoodleSpecFields() {
 	r.lmsMoodleCtx.moodleSpec, r.lmsMoodleCtx.moodleSpecFound, _ = unstructured.NestedMap(r.lmsMoodleCtx.spec, "moodleSpec")
 	r.lmsMoodleCtx.postgresSpec, r.lmsMoodleCtx.postgresSpecFound, _ = unstructured.NestedMap(r.lmsMoodleCtx.spec, "postgresSpec")
 	r.lmsMoodleCtx.nfsSpec, r.lmsMoodleCtx.nfsSpecFound, _ = unstructured.NestedMap(r.lmsMoodleCtx.spec, "nfsSpec")
 	r.lmsMoodleCtx.keydbSpec, r.lmsMoodleCtx.keydbSpecFound, _ = unstructured.NestedMap(r.lmsMoodleCtx.spec, "keydbSpec")
 	r.lmsMoodleCtx.lmsMoodleTemplateName, _, _ = unstructured.NestedString(r.lmsMoodleCtx.spec, "lmsMoodleTemplateName")
 	r.lmsMoodleCtx.lmsMoodleNetpolOmit, _, _ = unstructured.NestedBool(r.lmsMoodleCtx.spec, "lmsMoodleNetpolOmit")
+	r.lmsMoodleCtx.targetNamespace, _, _ = unstructured.NestedString(r.lmsMoodleCtx.spec, "targetNamespace")
+	r.lmsMoodleCtx.unsetFields, _, _ = unstructured.NestedStringSlice(r.lmsMoodleCtx.spec, "unsetFields")
 	r.lmsMoodleCtx.desiredState, _, _ = unstructured.NestedString(r.lmsMoodleCtx.spec, "desiredState")
+	paused, _, _ := unstructured.NestedBool(r.lmsMoodleCtx.spec, "paused")
+	r.lmsMoodleCtx.paused = paused || r.lmsMoodleCtx.lmsMoodle.GetAnnotations()[lmsv1alpha1.PausedAnnotation] == "true"
+}
 
-	// Fetch lmsMoodleTemplate spec
-	r.lmsMoodleCtx.lmsMoodleTemplate = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleTemplate"))
-	if err := r.Get(ctx, types.NamespacedName{Name: r.lmsMoodleCtx.lmsMoodleTemplateName}, r.lmsMoodleCtx.lmsMoodleTemplate); err != nil {
-		log.Error(err, "LMSMoodleTemplate not found")
-		return &LMSMoodleTemplateNotFoundError{r.lmsMoodleCtx.lmsMoodleTemplateName}
-	}
+// setLMSMoodleTemplateSpec reads lms moodle template spec into context. Should be used once lmsMoodleTemplate is set
+func (r *LMSMoodleReconciler) setLMSMoodleTemplateSpec() {
+	// compiled again from the template read
+	r.lmsMoodleCtx.propagationPolicy = nil
 	r.lmsMoodleCtx.lmsMoodleTemplateSpec, _, _ = unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodleTemplate.UnstructuredContent(), "spec")
 	r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec, _, _ = unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodleTemplateSpec, "moodleSpec")
+	if r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec == nil {
+		r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec = map[string]interface{}{}
+	}
 	r.lmsMoodleCtx.lmsMoodleTemplatePostgresSpec, r.lmsMoodleCtx.lmsMoodleTemplatePostgresSpecFound, _ = unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodleTemplateSpec, "postgresSpec")
 	r.lmsMoodleCtx.lmsMoodleTemplateNfsSpec, r.lmsMoodleCtx.lmsMoodleTemplateNfsSpecFound, _ = unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodleTemplateSpec, "nfsSpec")
 	r.lmsMoodleCtx.lmsMoodleTemplateKeydbSpec, r.lmsMoodleCtx.lmsMoodleTemplateKeydbSpecFound, _ = unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodleTemplateSpec, "keydbSpec")
+}
 
-	// set labels
-	if err := r.setSiteLabels(ctx); err != nil {
+// prepareDependants defines default network policy and combined specs and desired states
+// of dependant components. Should be used once lms moodle labels are set
+func (r *LMSMoodleReconciler) prepareDependants(ctx context.Context) error {
+	log := log.FromContext(ctx)
+
+	// set labels and annotations propagated from lms moodle
+	if err := r.setPropagatedMetadata(r.lmsMoodleCtx.postgres, lmsv1alpha1.PostgresPropagationTarget); err != nil {
+		return err
+	}
+	if err := r.setPropagatedMetadata(r.lmsMoodleCtx.nfs, lmsv1alpha1.NfsPropagationTarget); err != nil {
+		return err
+	}
+	if err := r.setPropagatedMetadata(r.lmsMoodleCtx.keydb, lmsv1alpha1.KeydbPropagationTarget); err != nil {
+		return err
+	}
+	if err := r.setPropagatedMetadata(r.lmsMoodleCtx.moodle, lmsv1alpha1.MoodlePropagationTarget); err != nil {
+		return err
+	}
+
+	// define namespace labels and annotations
+	if err := r.defineNamespace(); err != nil {
 		return err
 	}
-	r.lmsMoodleCtx.postgres.SetLabels(r.lmsMoodleCtx.lmsMoodle.GetLabels())
-	r.lmsMoodleCtx.nfs.SetLabels(r.lmsMoodleCtx.lmsMoodle.GetLabels())
-	r.lmsMoodleCtx.keydb.SetLabels(r.lmsMoodleCtx.lmsMoodle.GetLabels())
-	r.lmsMoodleCtx.moodle.SetLabels(r.lmsMoodleCtx.lmsMoodle.GetLabels())
 
 	// define default network policy
-	r.defineLMSMoodleDefaultNetpol()
+	if err := r.defineLMSMoodleDefaultNetpol(); err != nil {
+		return err
+	}
 
 	// whether LMSMoodle has dependant components
 	if err := r.postgresSpec(); err != nil {
@@ -260,6 +445,19 @@ func (r *LMSMoodleReconciler) reconcilePrepare(ctx context.Context) error {
 		return err
 	}
 
+	// define namespace quota and limit range, from combined specs
+	if err := r.defineNamespaceQuota(); err != nil {
+		return err
+	}
+	if err := r.defineNamespaceLimitRange(); err != nil {
+		return err
+	}
+
+	// desired state of each component
+	if err := r.setComponentDesiredStates(ctx); err != nil {
+		return err
+	}
+
 	// set UUID when it has to notify status to a url
 	if err := r.setNotifyUUID(); err != nil {
 		log.Error(err, "Couldn't add status uuid")
@@ -296,17 +494,28 @@ func (r *LMSMoodleReconciler) reconcileFinalize(ctx context.Context) (finalized
 				return false, false, err
 			}
 		}
-		// Finalized
+		// Finalized, metrics and events are no longer kept
+		lmsMoodleMetrics.Forget(r.lmsMoodleCtx.name)
+		forgetEvents(r.Recorder, r.lmsMoodleCtx.lmsMoodle)
+		r.forgetReadinessAttempts(r.lmsMoodleCtx.name)
 		return true, false, nil
 	}
 	// Add finalizer for this CR
+	if err := r.addFinalizer(ctx); err != nil {
+		return false, false, err
+	}
+	return false, false, nil
+}
+
+// addFinalizer adds the finalizer to lmsMoodle, if not already there
+func (r *LMSMoodleReconciler) addFinalizer(ctx context.Context) error {
 	if !controllerutil.ContainsFinalizer(r.lmsMoodleCtx.lmsMoodle, LMSMoodleFinalizer) {
 		controllerutil.AddFinalizer(r.lmsMoodleCtx.lmsMoodle, LMSMoodleFinalizer)
 		if err := r.Update(ctx, r.lmsMoodleCtx.lmsMoodle); err != nil {
-			return false, false, err
+			return err
 		}
 	}
-	return false, false, nil
+	return nil
 }
 
 // reconcileSuspend take care of suspend state
@@ -314,93 +523,148 @@ func (r *LMSMoodleReconciler) reconcileSuspend(ctx context.Context) (requeue boo
 	log := log.FromContext(ctx)
 	log.V(1).Info("Reconcile persist")
 
-	// Save Moodle spec
-	r.lmsMoodleCtx.moodle.Object["spec"] = r.lmsMoodleCtx.combinedMoodleSpec
-	// Set suspended
-	if err := unstructured.SetNestedField(r.lmsMoodleCtx.combinedMoodleSpec, "suspended", "cr_state"); err != nil {
+	// Suspend Moodle
+	if suspended, err := r.reconcileSuspendDependant(ctx, r.lmsMoodleCtx.moodle, r.lmsMoodleCtx.combinedMoodleSpec, r.SetMoodleReadyCondition); err != nil {
 		return false, err
-	}
-	// Update lmsMoodle status about Moodle
-	r.SetMoodleReadyCondition(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.moodle)
-	// Apply Moodle resource
-	if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.moodle); err != nil {
-		return false, err
-	}
-	// Whether moodle is suspended
-	if suspended := r.isDependantSuspended(ctx, r.lmsMoodleCtx.moodle); !suspended {
+	} else if !suspended {
 		log.Info("Moodle resource is being suspended")
 		_, err := r.updateLMSMoodleStatus(ctx)
 		return true, err
 	}
 
-	// Save Keydb spec
-	if r.lmsMoodleCtx.hasKeydb {
-		r.lmsMoodleCtx.keydb.Object["spec"] = r.lmsMoodleCtx.combinedKeydbSpec
-		// Set suspended
-		if err := unstructured.SetNestedField(r.lmsMoodleCtx.combinedKeydbSpec, "suspended", "cr_state"); err != nil {
-			return false, err
-		}
-		// Update LMSMoodle status about Keydb
-		r.SetKeydbReadyCondition(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.keydb)
-		// Apply Keydb resource
-		if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.keydb); err != nil {
+	// Suspend dependant components, once Moodle is suspended
+	if requeue, err := r.reconcileSuspendDependants(ctx); err != nil || requeue {
+		return requeue, err
+	}
+
+	// lmsMoodle is suspended
+	return r.updateLMSMoodleStatus(ctx)
+}
+
+// reconcileSuspendDependants suspends Keydb, NFS Ganesha and Postgres, in that order,
+// whenever their desired state is suspended. Moodle must be already suspended
+func (r *LMSMoodleReconciler) reconcileSuspendDependants(ctx context.Context) (requeue bool, err error) {
+	log := log.FromContext(ctx)
+
+	// Suspend Keydb
+	if r.lmsMoodleCtx.hasKeydb && r.lmsMoodleCtx.keydbDesiredState == lmsv1alpha1.SuspendedState {
+		if suspended, err := r.reconcileSuspendDependant(ctx, r.lmsMoodleCtx.keydb, r.lmsMoodleCtx.combinedKeydbSpec, r.SetKeydbReadyCondition); err != nil {
 			return false, err
-		}
-		// Whether keydb is suspended
-		if suspended := r.isDependantSuspended(ctx, r.lmsMoodleCtx.keydb); !suspended {
+		} else if !suspended {
 			log.Info("Keydb resource is being suspended")
 			_, err := r.updateLMSMoodleStatus(ctx)
 			return true, err
 		}
 	}
 
-	// Save NFS Ganesha server spec
-	if r.lmsMoodleCtx.hasNfs {
-		// Save NFS Ganesha server spec
-		r.lmsMoodleCtx.nfs.Object["spec"] = r.lmsMoodleCtx.combinedNfsSpec
-		// Set suspended
-		if err := unstructured.SetNestedField(r.lmsMoodleCtx.combinedNfsSpec, "suspended", "cr_state"); err != nil {
-			return false, err
-		}
-		// Update LMSMoodle status about NFS Ganesha
-		r.SetNfsReadyCondition(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.nfs)
-		// Apply NFS Ganesha server resource
-		if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.nfs); err != nil {
+	// Suspend NFS Ganesha server
+	if r.lmsMoodleCtx.hasNfs && r.lmsMoodleCtx.nfsDesiredState == lmsv1alpha1.SuspendedState {
+		if suspended, err := r.reconcileSuspendDependant(ctx, r.lmsMoodleCtx.nfs, r.lmsMoodleCtx.combinedNfsSpec, r.SetNfsReadyCondition); err != nil {
 			return false, err
-		}
-		// Whether nfs is suspended
-		if suspended := r.isDependantSuspended(ctx, r.lmsMoodleCtx.nfs); !suspended {
+		} else if !suspended {
 			log.Info("Nfs resource is being suspended")
 			_, err := r.updateLMSMoodleStatus(ctx)
 			return true, err
 		}
 	}
 
-	// Save Postgres spec
-	if r.lmsMoodleCtx.hasPostgres {
-		r.lmsMoodleCtx.postgres.Object["spec"] = r.lmsMoodleCtx.combinedPostgresSpec
-		// Set suspended
-		if err := unstructured.SetNestedField(r.lmsMoodleCtx.combinedPostgresSpec, "suspended", "cr_state"); err != nil {
+	// Suspend Postgres
+	if r.lmsMoodleCtx.hasPostgres && r.lmsMoodleCtx.postgresDesiredState == lmsv1alpha1.SuspendedState {
+		if suspended, err := r.reconcileSuspendDependant(ctx, r.lmsMoodleCtx.postgres, r.lmsMoodleCtx.combinedPostgresSpec, r.SetPostgresReadyCondition); err != nil {
 			return false, err
-		}
-		// Update LMSMoodle status about Postgres
-		r.SetPostgresReadyCondition(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.postgres)
-		// Apply Postgres resource
-		if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.postgres); err != nil {
-			return false, err
-		}
-		// Whether postgres is suspended
-		if suspended := r.isDependantSuspended(ctx, r.lmsMoodleCtx.postgres); !suspended {
+		} else if !suspended {
 			log.Info("Postgres resource is being suspended")
 			_, err := r.updateLMSMoodleStatus(ctx)
 			return true, err
 		}
 	}
 
-	// lmsMoodle is suspended
+	return false, nil
+}
+
+// preparePaused sets names of dependant resources kept in lmsMoodle status, if any, so that
+// they are read as they are. Neither template nor combined specs are needed. Which dependants
+// are present is known once read
+func (r *LMSMoodleReconciler) preparePaused() {
+	namespaceName, baseName, _ := r.statusNames()
+	r.setDependantNames(namespaceName, baseName)
+	for _, dependant := range []*unstructured.Unstructured{r.lmsMoodleCtx.postgres, r.lmsMoodleCtx.nfs, r.lmsMoodleCtx.keydb} {
+		dependant.SetName(baseName)
+		dependant.SetNamespace(namespaceName)
+	}
+	r.lmsMoodleCtx.hasPostgres, r.lmsMoodleCtx.hasKeydb, r.lmsMoodleCtx.hasNfs = false, false, false
+	r.lmsMoodleCtx.postgresDesiredState, r.lmsMoodleCtx.keydbDesiredState, r.lmsMoodleCtx.nfsDesiredState = "", "", ""
+	r.lmsMoodleCtx.moodleDesiredState = ""
+	r.lmsMoodleCtx.overridesRejected = false
+	// template is not read, so that it is not required while paused
+	r.lmsMoodleCtx.lmsMoodleTemplate = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleTemplate"))
+	r.lmsMoodleCtx.lmsMoodleTemplateSpec = nil
+}
+
+// reconcilePaused only reports status of dependant resources as they are, without
+// applying, creating or deleting any of them, nor notifying state changes
+func (r *LMSMoodleReconciler) reconcilePaused(ctx context.Context) (requeue bool, err error) {
+	log := log.FromContext(ctx)
+	log.Info("LMSMoodle reconciliation is paused")
+
+	// a paused lmsMoodle may be created or unpaused later, so it is finalized anyway
+	if !r.lmsMoodleCtx.markedToBeDeleted {
+		if err := r.addFinalizer(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	// dependants only exist once their names are kept in status
+	if _, _, found := r.statusNames(); found {
+		for _, dependant := range []struct {
+			present           *bool
+			obj               *unstructured.Unstructured
+			setReadyCondition func(context.Context, *unstructured.Unstructured, *unstructured.Unstructured) (bool, bool)
+		}{
+			{&r.lmsMoodleCtx.hasPostgres, r.lmsMoodleCtx.postgres, r.SetPostgresReadyCondition},
+			{&r.lmsMoodleCtx.hasKeydb, r.lmsMoodleCtx.keydb, r.SetKeydbReadyCondition},
+			{&r.lmsMoodleCtx.hasNfs, r.lmsMoodleCtx.nfs, r.SetNfsReadyCondition},
+			{nil, r.lmsMoodleCtx.moodle, r.SetMoodleReadyCondition},
+		} {
+			// Read dependant, as it is, to report its status
+			if err := r.Get(ctx, client.ObjectKeyFromObject(dependant.obj), dependant.obj); err != nil {
+				if !errors.IsNotFound(err) {
+					return false, err
+				}
+				// moodle is always reported
+				if dependant.present != nil {
+					continue
+				}
+			}
+			if dependant.present != nil {
+				*dependant.present = true
+			}
+			dependant.setReadyCondition(ctx, r.lmsMoodleCtx.lmsMoodle, dependant.obj)
+		}
+	}
+
 	return r.updateLMSMoodleStatus(ctx)
 }
 
+// reconcileSuspendDependant applies a dependant resource with its state set as suspended
+// It returns whether the dependant has been suspended
+func (r *LMSMoodleReconciler) reconcileSuspendDependant(ctx context.Context, dependantObj *unstructured.Unstructured, dependantSpec map[string]interface{}, setReadyCondition func(context.Context, *unstructured.Unstructured, *unstructured.Unstructured) (bool, bool)) (suspended bool, err error) {
+	// Save dependant spec
+	dependantObj.Object["spec"] = dependantSpec
+	// Set suspended
+	if err := unstructured.SetNestedField(dependantSpec, "suspended", "cr_state"); err != nil {
+		return false, err
+	}
+	// Update LMSMoodle status about dependant
+	setReadyCondition(ctx, r.lmsMoodleCtx.lmsMoodle, dependantObj)
+	// Apply dependant resource
+	if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, dependantObj); err != nil {
+		return false, err
+	}
+	// Whether dependant is suspended
+	return r.isDependantSuspended(ctx, dependantObj), nil
+}
+
 // reconcilePresent take care of present state
 func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue bool, err error) {
 	log := log.FromContext(ctx)
@@ -412,8 +676,36 @@ func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue boo
 	keydbReady := !r.lmsMoodleCtx.hasKeydb
 	postgresReady := !r.lmsMoodleCtx.hasPostgres
 
-	// Create namespace
-	if err := r.ReconcileCreate(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.namespace); err != nil {
+	// Time provisioning, upgrade or resume
+	if err := r.StartTimelineOperation(ctx); err != nil {
+		return false, err
+	}
+
+	// Apply namespace, keeping its labels and annotations up to date. A target
+	// namespace is only checked, since it is not managed by the operator
+	if r.lmsMoodleCtx.targetNamespace != "" {
+		if err := r.reconcileTargetNamespace(ctx); err != nil {
+			return false, err
+		}
+	} else if err := r.reconcileNamespace(ctx); err != nil {
+		return false, err
+	}
+	if err := r.MarkTimelineNamespace(r.lmsMoodleCtx.namespace); err != nil {
+		return false, err
+	}
+
+	// Handle changes made to dependants by other field managers
+	if err := r.ReconcileDrift(ctx); err != nil {
+		return false, err
+	}
+
+	// Publish combined dependant specs
+	if err := r.ReconcileEffectiveSpecs(ctx); err != nil {
+		return false, err
+	}
+
+	// Keep status notify secret values, referenced by Moodle spec
+	if err := r.reconcileNotifySecret(ctx); err != nil {
 		return false, err
 	}
 
@@ -423,13 +715,18 @@ func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue boo
 			return false, err
 		}
 	} else {
-		if err := r.ReconcileCreate(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.lmsMoodleDefaultNetpol); err != nil {
+		if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.lmsMoodleDefaultNetpol); err != nil {
 			return false, err
 		}
 	}
 
+	// Whether namespace resource quota and limit range should be present
+	if err := r.reconcileNamespaceQuota(ctx); err != nil {
+		return false, err
+	}
+
 	// Save Postgres spec
-	if r.lmsMoodleCtx.hasPostgres {
+	if r.lmsMoodleCtx.hasPostgres && r.lmsMoodleCtx.postgresDesiredState == lmsv1alpha1.ReadyState {
 		r.lmsMoodleCtx.postgres.Object["spec"] = r.lmsMoodleCtx.combinedPostgresSpec
 		// Update LMSMoodle status about Postgres
 		r.SetPostgresReadyCondition(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.postgres)
@@ -437,6 +734,9 @@ func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue boo
 		if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.postgres); err != nil {
 			return false, err
 		}
+		if err := r.MarkTimelineDependant(ctx, "postgres", r.lmsMoodleCtx.postgres); err != nil {
+			return false, err
+		}
 		// check if postgres ready
 		if postgresReady, err = getReadyStatus(ctx, r.lmsMoodleCtx.postgres); err != nil {
 			return false, err
@@ -444,7 +744,7 @@ func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue boo
 	}
 
 	// Save Keydb spec
-	if r.lmsMoodleCtx.hasKeydb {
+	if r.lmsMoodleCtx.hasKeydb && r.lmsMoodleCtx.keydbDesiredState == lmsv1alpha1.ReadyState {
 		r.lmsMoodleCtx.keydb.Object["spec"] = r.lmsMoodleCtx.combinedKeydbSpec
 		// Update LMSMoodle status about Keydb
 		r.SetKeydbReadyCondition(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.keydb)
@@ -452,6 +752,9 @@ func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue boo
 		if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.keydb); err != nil {
 			return false, err
 		}
+		if err := r.MarkTimelineDependant(ctx, "keydb", r.lmsMoodleCtx.keydb); err != nil {
+			return false, err
+		}
 		// check if keydb ready
 		if keydbReady, err = getReadyStatus(ctx, r.lmsMoodleCtx.keydb); err != nil {
 			return false, err
@@ -459,7 +762,7 @@ func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue boo
 	}
 
 	// Save NFS Ganesha server spec
-	if r.lmsMoodleCtx.hasNfs {
+	if r.lmsMoodleCtx.hasNfs && r.lmsMoodleCtx.nfsDesiredState == lmsv1alpha1.ReadyState {
 		// Save NFS Ganesha server spec
 		r.lmsMoodleCtx.nfs.Object["spec"] = r.lmsMoodleCtx.combinedNfsSpec
 		// Update LMSMoodle status about NFS Ganesha
@@ -468,27 +771,49 @@ func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue boo
 		if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.nfs); err != nil {
 			return false, err
 		}
+		if err := r.MarkTimelineDependant(ctx, "nfs", r.lmsMoodleCtx.nfs); err != nil {
+			return false, err
+		}
 		// check if nfs ready
 		if nfsReady, err = getReadyStatus(ctx, r.lmsMoodleCtx.nfs); err != nil {
 			return false, err
 		}
 	}
 
+	// Suspend Moodle, if requested, before any of its dependant components
+	if r.lmsMoodleCtx.moodleDesiredState == lmsv1alpha1.SuspendedState {
+		if suspended, err := r.reconcileSuspendDependant(ctx, r.lmsMoodleCtx.moodle, r.lmsMoodleCtx.combinedMoodleSpec, r.SetMoodleReadyCondition); err != nil {
+			return false, err
+		} else if !suspended {
+			log.Info("Moodle resource is being suspended")
+			_, err := r.updateLMSMoodleStatus(ctx)
+			return true, err
+		}
+
+		// Suspend dependant components, once Moodle is suspended
+		if requeue, err := r.reconcileSuspendDependants(ctx); err != nil || requeue {
+			return requeue, err
+		}
+
+		// lmsMoodle is partially suspended
+		return r.updateLMSMoodleStatus(ctx)
+	}
+
 	// Wait for postgres to be ready; otherwise requeue
 	if !postgresReady {
 		log.Info("Postgres is not ready, requeueing...", "Postgres.Name", r.lmsMoodleCtx.postgres.GetName())
-		return r.updateLMSMoodleStatus(ctx)
+		return r.waitForDependantReady(ctx, r.lmsMoodleCtx.postgres, "Postgres")
 	}
 	// Wait for Keydb to be ready; otherwise requeue
 	if !keydbReady {
 		log.Info("Keydb is not ready, requeueing...", "Keydb.Name", r.lmsMoodleCtx.keydb.GetName())
-		return r.updateLMSMoodleStatus(ctx)
+		return r.waitForDependantReady(ctx, r.lmsMoodleCtx.keydb, "Keydb")
 	}
 	// Wait for NFS Ganesha to be ready; otherwise requeue
 	// NFS Ganesha server must be ready in order to mount its export as pvc
 	if !nfsReady {
 		log.Info("(NFS) Ganesha server is not ready, requeueing...", "Ganesha.Name", r.lmsMoodleCtx.nfs.GetName())
-		return r.updateLMSMoodleStatus(ctx)
+		return r.waitForDependantReady(ctx, r.lmsMoodleCtx.nfs, "Nfs")
 	}
 
 	// Save Moodle spec
@@ -499,6 +824,9 @@ func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue boo
 	if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.moodle); err != nil {
 		return false, err
 	}
+	if err := r.MarkTimelineDependant(ctx, "moodle", r.lmsMoodleCtx.moodle); err != nil {
+		return false, err
+	}
 	// check if moodle ready
 	if moodleReady, err = getReadyStatus(ctx, r.lmsMoodleCtx.moodle); err != nil {
 		return false, err
@@ -506,10 +834,11 @@ func (r *LMSMoodleReconciler) reconcilePresent(ctx context.Context) (requeue boo
 	// Wait for Moodle to be ready; otherwise requeue
 	if !moodleReady {
 		log.Info("Moodle is not ready, requeueing...", "Moodle.Name", r.lmsMoodleCtx.moodle.GetName())
-		return r.updateLMSMoodleStatus(ctx)
+		return r.waitForDependantReady(ctx, r.lmsMoodleCtx.moodle, "Moodle")
 	}
 
-	// lmsMoodle is ready
+	// lmsMoodle is ready, maybe with some of its components suspended
+	r.forgetReadinessAttempts(r.lmsMoodleCtx.name)
 	return r.updateLMSMoodleStatus(ctx)
 }
 
@@ -538,13 +867,17 @@ func (r *LMSMoodleReconciler) lmsMoodlesByLMSMoodleTemplate(ctx context.Context,
 		return []reconcile.Request{}
 	}
 
-	reconcileRequests := make([]reconcile.Request, len(SiteList.Items))
-	for i, lmsmoodle := range SiteList.Items {
-		reconcileRequests[i] = reconcile.Request{
+	reconcileRequests := make([]reconcile.Request, 0, len(SiteList.Items))
+	for _, lmsmoodle := range SiteList.Items {
+		// paused lmsmoodles pick template changes up once unpaused
+		if lmsmoodle.IsPaused() {
+			continue
+		}
+		reconcileRequests = append(reconcileRequests, reconcile.Request{
 			NamespacedName: types.NamespacedName{
 				Name: lmsmoodle.Name,
 			},
-		}
+		})
 	}
 	return reconcileRequests
 }
@@ -0,0 +1,619 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+const (
+	LMSMoodleClaimFinalizer string = "lms.krestomat.io/finalizer"
+	// LMSMoodleClaimFieldManager applies claimed LMSMoodles, apart from fields set by the operator
+	LMSMoodleClaimFieldManager string = OPERATORNAME + "-claim"
+	// LMSMoodleClaimNamePrefix prefix of claimed LMSMoodle names, followed by claim uid
+	LMSMoodleClaimNamePrefix string = "claim-"
+	// LMSMoodleHostIndex indexes LMSMoodles by Moodle host, so that claimed hosts are unique
+	LMSMoodleHostIndex    string = "spec.moodleSpec.moodleHost"
+	BoundConditionType    string = "Bound"
+	AcceptedConditionType string = "Accepted"
+)
+
+// claimOverridePaths are LMSMoodle spec field paths of each claim override
+var claimOverridePaths = map[lmsv1alpha1.LMSMoodleClaimOverride][]string{
+	"desiredState": {"desiredState"},
+	"host":         {"moodleSpec", "moodleHost"},
+	"fullname":     {"moodleSpec", "moodleNewInstanceFullname"},
+	"shortname":    {"moodleSpec", "moodleNewInstanceShortname"},
+	"summary":      {"moodleSpec", "moodleNewInstanceSummary"},
+	"lang":         {"moodleSpec", "moodleNewInstanceLang"},
+	"storageSize":  {"moodleSpec", "moodlePvcDataSize"},
+}
+
+// claimRequiredPaths are LMSMoodle spec field paths set from every claim
+var claimRequiredPaths = [][]string{
+	{"moodleSpec", "moodleNewInstanceAgreeLicense"},
+	{"moodleSpec", "moodleNewInstanceAdminmail"},
+	{"moodleSpec", "moodleNewAdminpassHash"},
+}
+
+type LMSMoodleClaimReconcilerContext struct {
+	markedToBeDeleted bool
+	lmsMoodleClaim    *unstructured.Unstructured
+	lmsMoodleName     string
+	// lmsMoodle bound to the claim, nil if not present
+	lmsMoodle *unstructured.Unstructured
+	// lmsMoodleConflict whether a LMSMoodle by the claimed name is bound to another claim
+	lmsMoodleConflict bool
+}
+
+// LMSMoodleClaimNotAcceptedError is returned when a LMSMoodleTemplate does not allow a claim
+type LMSMoodleClaimNotAcceptedError struct {
+	Reason  string // condition reason
+	Message string // condition message
+}
+
+func (e *LMSMoodleClaimNotAcceptedError) Error() string {
+	return e.Message
+}
+
+// LMSMoodleClaimReconciler reconciles a LMSMoodleClaim object
+type LMSMoodleClaimReconciler struct {
+	client.Client
+	Scheme            *runtime.Scheme
+	APIReader         client.Reader
+	Recorder          record.EventRecorder
+	lmsMoodleClaimCtx LMSMoodleClaimReconcilerContext
+}
+
+// +kubebuilder:rbac:groups=lms.krestomat.io,resources=lmsmoodleclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=lms.krestomat.io,resources=lmsmoodleclaims/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=lms.krestomat.io,resources=lmsmoodleclaims/finalizers,verbs=update
+// +kubebuilder:rbac:groups=lms.krestomat.io,resources=lmsmoodles,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=lms.krestomat.io,resources=lmsmoodletemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile binds a LMSMoodleClaim to a LMSMoodle created from it, as allowed by
+// its LMSMoodleTemplate, mirroring LMSMoodle status back to the claim
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
+func (r *LMSMoodleClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Starting reconcile")
+
+	// Fetch LMSMoodleClaim instance
+	r.lmsMoodleClaimCtx = LMSMoodleClaimReconcilerContext{}
+	r.lmsMoodleClaimCtx.lmsMoodleClaim = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleClaim"))
+	if err := r.Get(ctx, req.NamespacedName, r.lmsMoodleClaimCtx.lmsMoodleClaim); err != nil {
+		log.V(1).Info(err.Error())
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// whether LMSMoodleClaim is marked to be deleted
+	r.lmsMoodleClaimCtx.markedToBeDeleted = r.lmsMoodleClaimCtx.lmsMoodleClaim.GetDeletionTimestamp() != nil
+
+	// Fetch claimed LMSMoodle, if any
+	if err := r.getClaimedLMSMoodle(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Finalize logic
+	if finalized, err := r.reconcileFinalize(ctx); err != nil {
+		return ctrl.Result{}, err
+	} else if finalized {
+		return ctrl.Result{}, nil
+	}
+
+	return ctrl.Result{}, r.reconcileBind(ctx)
+}
+
+// getClaimedLMSMoodle fetches LMSMoodle bound to the claim, by the name kept in status
+// or derived from claim uid. Once bound, a LMSMoodle not found in cache is confirmed to
+// be missing through the API reader, since cache may not have caught up with its creation
+func (r *LMSMoodleClaimReconciler) getClaimedLMSMoodle(ctx context.Context) error {
+	lmsMoodleClaim := r.lmsMoodleClaimCtx.lmsMoodleClaim
+	r.lmsMoodleClaimCtx.lmsMoodleName, _, _ = unstructured.NestedString(lmsMoodleClaim.Object, "status", "lmsMoodleName")
+	bound := r.lmsMoodleClaimCtx.lmsMoodleName != ""
+	if !bound {
+		r.lmsMoodleClaimCtx.lmsMoodleName = LMSMoodleClaimNamePrefix + string(lmsMoodleClaim.GetUID())
+	}
+
+	lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+	err := r.Get(ctx, types.NamespacedName{Name: r.lmsMoodleClaimCtx.lmsMoodleName}, lmsMoodle)
+	if errors.IsNotFound(err) && bound && r.APIReader != nil {
+		err = r.APIReader.Get(ctx, types.NamespacedName{Name: r.lmsMoodleClaimCtx.lmsMoodleName}, lmsMoodle)
+	}
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if lmsMoodle.GetAnnotations()[lmsv1alpha1.ClaimUIDAnnotation] != string(lmsMoodleClaim.GetUID()) {
+		r.lmsMoodleClaimCtx.lmsMoodleConflict = true
+		return nil
+	}
+	r.lmsMoodleClaimCtx.lmsMoodle = lmsMoodle
+	return nil
+}
+
+// reconcileFinalize configures finalizer
+func (r *LMSMoodleClaimReconciler) reconcileFinalize(ctx context.Context) (finalized bool, err error) {
+	log := log.FromContext(ctx)
+	log.V(1).Info("Reconcile finalizer")
+
+	// Check if LMSMoodleClaim instance is marked to be deleted, which is
+	// indicated by the deletion timestamp being set.
+	if r.lmsMoodleClaimCtx.markedToBeDeleted {
+		if controllerutil.ContainsFinalizer(r.lmsMoodleClaimCtx.lmsMoodleClaim, LMSMoodleClaimFinalizer) {
+			// Run finalization logic for LMSMoodleClaimFinalizer. If the
+			// finalization logic fails, don't remove the finalizer so
+			// that we can retry during the next reconciliation.
+			if err := r.finalizeLMSMoodleClaim(ctx); err != nil {
+				return false, err
+			}
+			// Remove LMSMoodleClaimFinalizer. Once all finalizers have been
+			// removed, the object will be deleted.
+			controllerutil.RemoveFinalizer(r.lmsMoodleClaimCtx.lmsMoodleClaim, LMSMoodleClaimFinalizer)
+			if err := r.Update(ctx, r.lmsMoodleClaimCtx.lmsMoodleClaim); err != nil {
+				return false, err
+			}
+		}
+		// Finalized, events are no longer kept
+		forgetEvents(r.Recorder, r.lmsMoodleClaimCtx.lmsMoodleClaim)
+		return true, nil
+	}
+	// Add finalizer for this CR
+	if !controllerutil.ContainsFinalizer(r.lmsMoodleClaimCtx.lmsMoodleClaim, LMSMoodleClaimFinalizer) {
+		controllerutil.AddFinalizer(r.lmsMoodleClaimCtx.lmsMoodleClaim, LMSMoodleClaimFinalizer)
+		if err := r.Update(ctx, r.lmsMoodleClaimCtx.lmsMoodleClaim); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// finalizeLMSMoodleClaim deletes or releases claimed LMSMoodle, as set by reclaim policy
+func (r *LMSMoodleClaimReconciler) finalizeLMSMoodleClaim(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Finalizing")
+
+	lmsMoodle := r.lmsMoodleClaimCtx.lmsMoodle
+	if lmsMoodle == nil {
+		return nil
+	}
+	claimKey := client.ObjectKeyFromObject(r.lmsMoodleClaimCtx.lmsMoodleClaim).String()
+
+	reclaimPolicy, _, _ := unstructured.NestedString(r.lmsMoodleClaimCtx.lmsMoodleClaim.Object, "status", "reclaimPolicy")
+	if reclaimPolicy == lmsv1alpha1.RetainReclaimPolicy {
+		log.Info("Releasing LMSMoodle", "LMSMoodle.Name", lmsMoodle.GetName())
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{
+					lmsv1alpha1.ClaimAnnotation:    nil,
+					lmsv1alpha1.ClaimUIDAnnotation: nil,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err := r.Patch(ctx, lmsMoodle, client.RawPatch(types.MergePatchType, patch)); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "LMSMoodle not released", "LMSMoodle.Name", lmsMoodle.GetName())
+			return err
+		}
+		r.Recorder.Eventf(lmsMoodle, corev1.EventTypeNormal, ReleasedEventReason, "Released from LMSMoodleClaim '%s'", claimKey)
+		return nil
+	}
+
+	log.Info("Deleting LMSMoodle", "LMSMoodle.Name", lmsMoodle.GetName())
+	if err := r.Delete(ctx, lmsMoodle); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "LMSMoodle not deleted", "LMSMoodle.Name", lmsMoodle.GetName())
+		return err
+	}
+	r.Recorder.Eventf(lmsMoodle, corev1.EventTypeNormal, DeletedEventReason, "Deleted along with LMSMoodleClaim '%s'", claimKey)
+	return nil
+}
+
+// reconcileBind applies claimed LMSMoodle, if the claim is accepted by its template, and
+// updates claim status. A claim whose LMSMoodle is deleted or bound to another claim is lost
+func (r *LMSMoodleClaimReconciler) reconcileBind(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.V(1).Info("Reconcile bind")
+
+	lmsMoodleClaim := r.lmsMoodleClaimCtx.lmsMoodleClaim
+	originalStatus, _, _ := unstructured.NestedFieldCopy(lmsMoodleClaim.Object, "status")
+	phase, _, _ := unstructured.NestedString(lmsMoodleClaim.Object, "status", "phase")
+
+	lmsMoodleTemplate, err := r.acceptLMSMoodleClaim(ctx)
+	var notAcceptedError *LMSMoodleClaimNotAcceptedError
+	if err != nil {
+		var isNotAccepted bool
+		if notAcceptedError, isNotAccepted = err.(*LMSMoodleClaimNotAcceptedError); !isNotAccepted {
+			return err
+		}
+		log.Info("LMSMoodleClaim not accepted", "Reason", notAcceptedError.Reason)
+		r.Recorder.Event(lmsMoodleClaim, corev1.EventTypeWarning, ClaimNotAcceptedEventReason, notAcceptedError.Error())
+		if _, err := SetCondition(lmsMoodleClaim, map[string]interface{}{
+			"type":    AcceptedConditionType,
+			"status":  "False",
+			"reason":  notAcceptedError.Reason,
+			"message": notAcceptedError.Message,
+		}); err != nil {
+			return err
+		}
+	} else if _, err := SetCondition(lmsMoodleClaim, map[string]interface{}{
+		"type":    AcceptedConditionType,
+		"status":  "True",
+		"reason":  "Accepted",
+		"message": "LMSMoodleTemplate allows the claim",
+	}); err != nil {
+		return err
+	}
+
+	switch {
+	case phase == lmsv1alpha1.LostClaimPhase:
+		// lost claims are not bound again
+	case r.lmsMoodleClaimCtx.lmsMoodleConflict:
+		phase = lmsv1alpha1.LostClaimPhase
+		r.Recorder.Eventf(lmsMoodleClaim, corev1.EventTypeWarning, ClaimLostEventReason, "LMSMoodle '%s' is bound to another claim", r.lmsMoodleClaimCtx.lmsMoodleName)
+	case r.lmsMoodleClaimCtx.lmsMoodle == nil && phase == lmsv1alpha1.BoundClaimPhase:
+		phase = lmsv1alpha1.LostClaimPhase
+		r.Recorder.Eventf(lmsMoodleClaim, corev1.EventTypeWarning, ClaimLostEventReason, "LMSMoodle '%s' no longer exists", r.lmsMoodleClaimCtx.lmsMoodleName)
+	case notAcceptedError != nil:
+		// bound LMSMoodle is kept as is, until the claim is accepted
+		if r.lmsMoodleClaimCtx.lmsMoodle == nil {
+			phase = lmsv1alpha1.PendingClaimPhase
+		}
+	default:
+		if err := r.applyClaimedLMSMoodle(ctx, lmsMoodleTemplate); err != nil {
+			return err
+		}
+		phase = lmsv1alpha1.BoundClaimPhase
+	}
+
+	if err := r.setLMSMoodleClaimStatus(phase); err != nil {
+		return err
+	}
+
+	status, _, _ := unstructured.NestedFieldNoCopy(lmsMoodleClaim.Object, "status")
+	if reflect.DeepEqual(originalStatus, status) {
+		log.V(1).Info("LMSMoodleClaim status not updated")
+		return nil
+	}
+	if err := r.Status().Update(ctx, lmsMoodleClaim); err != nil {
+		log.Error(err, "Unable to update LMSMoodleClaim '"+lmsMoodleClaim.GetName()+"' status")
+		return err
+	}
+	return nil
+}
+
+// acceptLMSMoodleClaim returns claim LMSMoodleTemplate, or a LMSMoodleClaimNotAcceptedError
+// if it does not exist, does not allow claims from claim namespace or some of its overrides,
+// or if the claimed host is in use by another LMSMoodle
+func (r *LMSMoodleClaimReconciler) acceptLMSMoodleClaim(ctx context.Context) (*unstructured.Unstructured, error) {
+	lmsMoodleClaim := r.lmsMoodleClaimCtx.lmsMoodleClaim
+	lmsMoodleTemplateName, _, _ := unstructured.NestedString(lmsMoodleClaim.Object, "spec", "lmsMoodleTemplateName")
+
+	lmsMoodleTemplate := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleTemplate"))
+	if err := r.Get(ctx, types.NamespacedName{Name: lmsMoodleTemplateName}, lmsMoodleTemplate); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, &LMSMoodleClaimNotAcceptedError{"TemplateNotFound", (&LMSMoodleTemplateNotFoundError{lmsMoodleTemplateName}).Error()}
+		}
+		return nil, err
+	}
+
+	claimPolicyU, _, _ := unstructured.NestedMap(lmsMoodleTemplate.Object, "spec", "claims")
+	claimPolicy := lmsv1alpha1.LMSMoodleClaimPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(claimPolicyU, &claimPolicy); err != nil {
+		return nil, err
+	}
+	if !claimPolicy.Allowed {
+		return nil, &LMSMoodleClaimNotAcceptedError{"TemplateNotAllowed", fmt.Sprintf("LMSMoodleTemplate '%s' does not allow claims", lmsMoodleTemplateName)}
+	}
+
+	// claim namespace
+	if claimPolicy.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(claimPolicy.NamespaceSelector)
+		if err != nil {
+			return nil, err
+		}
+		// namespaces are not cached
+		var reader client.Reader = r.Client
+		if r.APIReader != nil {
+			reader = r.APIReader
+		}
+		namespace := &corev1.Namespace{}
+		if err := reader.Get(ctx, types.NamespacedName{Name: lmsMoodleClaim.GetNamespace()}, namespace); err != nil {
+			return nil, err
+		}
+		if !selector.Matches(labels.Set(namespace.GetLabels())) {
+			return nil, &LMSMoodleClaimNotAcceptedError{"NamespaceNotAllowed", fmt.Sprintf("LMSMoodleTemplate '%s' does not allow claims from namespace '%s'", lmsMoodleTemplateName, lmsMoodleClaim.GetNamespace())}
+		}
+	}
+
+	// claim overrides
+	allowedOverrides := map[lmsv1alpha1.LMSMoodleClaimOverride]bool{}
+	for _, override := range claimPolicy.AllowedOverrides {
+		allowedOverrides[override] = true
+	}
+	var notAllowedOverrides []string
+	for override := range claimOverridePaths {
+		if value, _, _ := unstructured.NestedString(lmsMoodleClaim.Object, "spec", "overrides", string(override)); value != "" && !allowedOverrides[override] {
+			notAllowedOverrides = append(notAllowedOverrides, string(override))
+		}
+	}
+	if len(notAllowedOverrides) > 0 {
+		sort.Strings(notAllowedOverrides)
+		return nil, &LMSMoodleClaimNotAcceptedError{"OverrideNotAllowed", fmt.Sprintf("LMSMoodleTemplate '%s' does not allow overrides: %s", lmsMoodleTemplateName, strings.Join(notAllowedOverrides, ", "))}
+	}
+
+	// claimed host
+	if host, _, _ := unstructured.NestedString(lmsMoodleClaim.Object, "spec", "overrides", "host"); host != "" {
+		lmsMoodleList := &lmsv1alpha1.LMSMoodleList{}
+		if err := r.List(ctx, lmsMoodleList, client.MatchingFields{LMSMoodleHostIndex: host}); err != nil {
+			return nil, err
+		}
+		hostSet, err := r.checkHostInUse(lmsMoodleList, host)
+		if err != nil {
+			return nil, err
+		}
+		// LMSMoodles applied recently may not be cached yet, so a host about to be set
+		// is checked against the API too. Claims are reconciled one at a time, so no
+		// other claim sets it meanwhile
+		if !hostSet && r.APIReader != nil {
+			lmsMoodleList := &lmsv1alpha1.LMSMoodleList{}
+			if err := r.APIReader.List(ctx, lmsMoodleList); err != nil {
+				return nil, err
+			}
+			if _, err := r.checkHostInUse(lmsMoodleList, host); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return lmsMoodleTemplate, nil
+}
+
+// checkHostInUse returns a LMSMoodleClaimNotAcceptedError if any LMSMoodle in a list other
+// than the claimed one has a host, and whether the claimed one has it already
+func (r *LMSMoodleClaimReconciler) checkHostInUse(lmsMoodleList *lmsv1alpha1.LMSMoodleList, host string) (hostSet bool, err error) {
+	for _, lmsMoodle := range lmsMoodleList.Items {
+		if lmsMoodle.Spec.MoodleSpec.MoodleHost != host {
+			continue
+		}
+		if lmsMoodle.Name != r.lmsMoodleClaimCtx.lmsMoodleName {
+			return false, &LMSMoodleClaimNotAcceptedError{"HostInUse", fmt.Sprintf("Host '%s' is in use by LMSMoodle '%s'", host, lmsMoodle.Name)}
+		}
+		hostSet = true
+	}
+	return hostSet, nil
+}
+
+// applyClaimedLMSMoodle creates or updates claimed LMSMoodle from claim spec. Since it is
+// applied, overrides removed from the claim are removed from LMSMoodle too
+func (r *LMSMoodleClaimReconciler) applyClaimedLMSMoodle(ctx context.Context, lmsMoodleTemplate *unstructured.Unstructured) error {
+	log := log.FromContext(ctx)
+
+	lmsMoodleClaim := r.lmsMoodleClaimCtx.lmsMoodleClaim
+	claimSpec, _, _ := unstructured.NestedMap(lmsMoodleClaim.Object, "spec")
+	agreeLicense, _, _ := unstructured.NestedBool(claimSpec, "agreeLicense")
+	adminMail, _, _ := unstructured.NestedString(claimSpec, "adminMail")
+	adminPassHash, _, _ := unstructured.NestedString(claimSpec, "adminPassHash")
+
+	spec := map[string]interface{}{
+		"lmsMoodleTemplateName": lmsMoodleTemplate.GetName(),
+		"moodleSpec": map[string]interface{}{
+			"moodleNewInstanceAgreeLicense": agreeLicense,
+			"moodleNewInstanceAdminmail":    adminMail,
+			"moodleNewAdminpassHash":        adminPassHash,
+		},
+	}
+	for override, path := range claimOverridePaths {
+		if value, _, _ := unstructured.NestedString(claimSpec, "overrides", string(override)); value != "" {
+			if err := unstructured.SetNestedField(spec, value, path...); err != nil {
+				return err
+			}
+		}
+	}
+
+	lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+	lmsMoodle.SetName(r.lmsMoodleClaimCtx.lmsMoodleName)
+	lmsMoodle.SetAnnotations(map[string]string{
+		lmsv1alpha1.ClaimAnnotation:    client.ObjectKeyFromObject(lmsMoodleClaim).String(),
+		lmsv1alpha1.ClaimUIDAnnotation: string(lmsMoodleClaim.GetUID()),
+	})
+	lmsMoodle.Object["spec"] = spec
+
+	force := true
+	if err := r.Patch(ctx, lmsMoodle, client.Apply, &client.PatchOptions{Force: &force, FieldManager: LMSMoodleClaimFieldManager}); err != nil {
+		log.Error(err, "Failed to apply claimed LMSMoodle", "LMSMoodle.Name", lmsMoodle.GetName())
+		r.Recorder.Eventf(lmsMoodleClaim, corev1.EventTypeWarning, ApplyFailedEventReason, "Failed to apply LMSMoodle '%s': %s", lmsMoodle.GetName(), err.Error())
+		return err
+	}
+
+	if r.lmsMoodleClaimCtx.lmsMoodle == nil {
+		log.Info("LMSMoodleClaim bound", "LMSMoodle.Name", lmsMoodle.GetName())
+		r.Recorder.Eventf(lmsMoodleClaim, corev1.EventTypeNormal, BoundEventReason, "Bound to LMSMoodle '%s'", lmsMoodle.GetName())
+		// reclaim policy is set by the template when bound
+		reclaimPolicy, _, _ := unstructured.NestedString(lmsMoodleTemplate.Object, "spec", "claims", "reclaimPolicy")
+		if reclaimPolicy == "" {
+			reclaimPolicy = lmsv1alpha1.DeleteReclaimPolicy
+		}
+		if err := unstructured.SetNestedField(lmsMoodleClaim.Object, reclaimPolicy, "status", "reclaimPolicy"); err != nil {
+			return err
+		}
+	}
+	r.lmsMoodleClaimCtx.lmsMoodle = lmsMoodle
+	return nil
+}
+
+// setLMSMoodleClaimStatus sets claim phase, Bound condition and status mirrored from claimed LMSMoodle
+func (r *LMSMoodleClaimReconciler) setLMSMoodleClaimStatus(phase string) error {
+	lmsMoodleClaim := r.lmsMoodleClaimCtx.lmsMoodleClaim
+	lmsMoodle := r.lmsMoodleClaimCtx.lmsMoodle
+
+	boundCondition := map[string]interface{}{
+		"type":    BoundConditionType,
+		"status":  "True",
+		"reason":  "Bound",
+		"message": "Bound to LMSMoodle '" + r.lmsMoodleClaimCtx.lmsMoodleName + "'",
+	}
+	switch phase {
+	case lmsv1alpha1.PendingClaimPhase:
+		boundCondition["status"] = "False"
+		boundCondition["reason"] = "Pending"
+		boundCondition["message"] = "Waiting for the claim to be accepted"
+	case lmsv1alpha1.LostClaimPhase:
+		boundCondition["status"] = "False"
+		boundCondition["reason"] = "Lost"
+		boundCondition["message"] = "LMSMoodle '" + r.lmsMoodleClaimCtx.lmsMoodleName + "' no longer exists or is bound to another claim"
+		lmsMoodle = nil
+	}
+	if _, err := SetCondition(lmsMoodleClaim, boundCondition); err != nil {
+		return err
+	}
+
+	if err := unstructured.SetNestedField(lmsMoodleClaim.Object, phase, "status", "phase"); err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedField(lmsMoodleClaim.Object, lmsMoodleClaim.GetGeneration(), "status", "observedGeneration"); err != nil {
+		return err
+	}
+	if phase == lmsv1alpha1.PendingClaimPhase {
+		return nil
+	}
+	if err := unstructured.SetNestedField(lmsMoodleClaim.Object, r.lmsMoodleClaimCtx.lmsMoodleName, "status", "lmsMoodleName"); err != nil {
+		return err
+	}
+
+	// mirror claimed LMSMoodle status
+	for _, field := range []string{"state", "url", "storageGb", "release", "registeredUsers"} {
+		var value interface{}
+		if lmsMoodle != nil {
+			value, _, _ = unstructured.NestedFieldCopy(lmsMoodle.Object, "status", field)
+		}
+		if value == nil {
+			unstructured.RemoveNestedField(lmsMoodleClaim.Object, "status", field)
+			continue
+		}
+		if err := unstructured.SetNestedField(lmsMoodleClaim.Object, value, "status", field); err != nil {
+			return err
+		}
+	}
+	readyCondition := map[string]interface{}{
+		"type":    ReadyConditionType,
+		"status":  "Unknown",
+		"reason":  "Unknown",
+		"message": "LMSMoodle is not ready yet",
+	}
+	if lmsMoodle == nil {
+		readyCondition["status"] = "False"
+		readyCondition["reason"] = "Lost"
+		readyCondition["message"] = "LMSMoodle is lost"
+	} else if lmsMoodleReadyCondition, found, _ := getConditionByType(lmsMoodle, ReadyConditionType); found {
+		for _, key := range []string{"status", "reason", "message", "lastTransitionTime"} {
+			readyCondition[key] = lmsMoodleReadyCondition[key]
+		}
+	}
+	_, err := SetCondition(lmsMoodleClaim, readyCondition)
+	return err
+}
+
+// lmsMoodleClaimByLMSMoodle selects the claim a LMSMoodle is bound to
+// It returns a list of reconcile.Request
+func (r *LMSMoodleClaimReconciler) lmsMoodleClaimByLMSMoodle(ctx context.Context, lmsMoodle client.Object) []reconcile.Request {
+	claim := lmsMoodle.GetAnnotations()[lmsv1alpha1.ClaimAnnotation]
+	namespace, name, found := strings.Cut(claim, "/")
+	if !found {
+		return []reconcile.Request{}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}}
+}
+
+// lmsMoodleClaimsByLMSMoodleTemplate select claims using a lmsMoodleTemplate
+// It returns a list of reconcile.Request
+func (r *LMSMoodleClaimReconciler) lmsMoodleClaimsByLMSMoodleTemplate(ctx context.Context, lmsMoodleTemplate client.Object) []reconcile.Request {
+	claimList := &lmsv1alpha1.LMSMoodleClaimList{}
+
+	// Filter the list of claims by the ones using the lmsMoodleTemplate name
+	if err := r.Client.List(ctx, claimList, client.MatchingFields{LMSMoodleTemplateNameIndex: lmsMoodleTemplate.GetName()}); err != nil {
+		return []reconcile.Request{}
+	}
+
+	reconcileRequests := make([]reconcile.Request, 0, len(claimList.Items))
+	for _, claim := range claimList.Items {
+		reconcileRequests = append(reconcileRequests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+		})
+	}
+	return reconcileRequests
+}
+
+// lmsMoodleHostIndexer indexes a LMSMoodle by its Moodle host, if set
+func lmsMoodleHostIndexer(obj client.Object) []string {
+	moodleHost := obj.(*lmsv1alpha1.LMSMoodle).Spec.MoodleSpec.MoodleHost
+	if moodleHost == "" {
+		return nil
+	}
+	return []string{moodleHost}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LMSMoodleClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Add spec.moodleSpec.moodleHost index
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &lmsv1alpha1.LMSMoodle{}, LMSMoodleHostIndex, lmsMoodleHostIndexer); err != nil {
+		return err
+	}
+
+	// Add spec.lmsMoodleTemplate index
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &lmsv1alpha1.LMSMoodleClaim{}, LMSMoodleTemplateNameIndex, func(obj client.Object) []string {
+		lmsMoodleTemplateName := obj.(*lmsv1alpha1.LMSMoodleClaim).Spec.LMSMoodleTemplateName
+		if lmsMoodleTemplateName == "" {
+			return nil
+		}
+		return []string{lmsMoodleTemplateName}
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&lmsv1alpha1.LMSMoodleClaim{}).
+		Watches(&lmsv1alpha1.LMSMoodle{}, handler.EnqueueRequestsFromMapFunc(r.lmsMoodleClaimByLMSMoodle)).
+		Watches(&lmsv1alpha1.LMSMoodleTemplate{}, handler.EnqueueRequestsFromMapFunc(r.lmsMoodleClaimsByLMSMoodleTemplate)).
+		Complete(r)
+}
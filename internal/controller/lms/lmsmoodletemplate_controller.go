@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -54,12 +55,14 @@ func (f *LMSMoodleTemplateInUsedError) Error() string {
 type LMSMoodleTemplateReconciler struct {
 	client.Client
 	Scheme               *runtime.Scheme
+	Recorder             record.EventRecorder
 	lmsMoodleTemplateCtx LMSMoodleTemplateReconcilerContext
 }
 
 // +kubebuilder:rbac:groups=lms.krestomat.io,resources=lmsmoodletemplates,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=lms.krestomat.io,resources=lmsmoodletemplates/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=lms.krestomat.io,resources=lmsmoodletemplates/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -121,7 +124,8 @@ func (r *LMSMoodleTemplateReconciler) reconcileFinalize(ctx context.Context) (fi
 				return false, err
 			}
 		}
-		// Finalized
+		// Finalized, events are no longer kept
+		forgetEvents(r.Recorder, r.lmsMoodleTemplateCtx.lmsMoodleTemplate)
 		return true, nil
 	}
 	// Add finalizer for this CR
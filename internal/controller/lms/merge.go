@@ -0,0 +1,393 @@
+package lms
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+// Merge engine
+//
+// LMSMoodle component specs are merged into LMSMoodleTemplate ones field by field,
+// following the merge strategy of each field:
+//   - Maps are merged field by field, unless their strategy is Replace
+//   - Lists are replaced, unless their strategy is Append or MergeByKey. Appended items
+//     already in the template list are skipped. Items merged by key are merged field by
+//     field with the template item with the same keys, or appended if there is none
+//   - Strings with MergeByKey strategy are YAML maps, such as ingress annotations, merged
+//     key by key. A key set to null deletes it
+//   - Any other value is replaced, even if it is false, zero or empty
+//
+// A `$patch: delete` marker deletes a template value: a map field set to it, or a list
+// item with it, deleting template items with the same keys. A `$patch: replace` marker
+// in a map replaces the template map with the rest of the LMSMoodle one. Since typed
+// fields do not accept markers, LMSMoodle unsetFields set them for the fields listed.
+// Merge strategies are set by defaultMergeStrategies, overridden by template mergeStrategies
+
+const (
+	// PatchMarkerKey is the key of merge markers in LMSMoodle component spec maps and list items
+	PatchMarkerKey string = "$patch"
+	// DeletePatchMarker deletes a template value
+	DeletePatchMarker string = "delete"
+	// ReplacePatchMarker replaces a template map, instead of merging it field by field
+	ReplacePatchMarker string = "replace"
+)
+
+// fieldMergeStrategy is how a component spec field is merged
+type fieldMergeStrategy struct {
+	strategy string
+	keys     []string
+}
+
+// defaultMergeStrategies are merge strategies of component spec fields, by field name suffix
+var defaultMergeStrategies = []struct {
+	suffix string
+	fieldMergeStrategy
+}{
+	{"Tolerations", fieldMergeStrategy{lmsv1alpha1.MergeByKeyMergeStrategy, []string{"key", "effect"}}},
+	{"ExtraPorts", fieldMergeStrategy{lmsv1alpha1.MergeByKeyMergeStrategy, []string{"port", "protocol"}}},
+	{"Peers", fieldMergeStrategy{lmsv1alpha1.AppendMergeStrategy, nil}},
+	{"IngressAnnotations", fieldMergeStrategy{lmsv1alpha1.MergeByKeyMergeStrategy, nil}},
+	{"headers", fieldMergeStrategy{lmsv1alpha1.MergeByKeyMergeStrategy, []string{"name"}}},
+}
+
+// unsetFieldPattern matches an unset field path and its optional list item keys
+var unsetFieldPattern = regexp.MustCompile(`^([^\[]+)(?:\[(.*)\])?$`)
+
+// MergeError is returned when a LMSMoodle component spec field cannot be merged with template one
+type MergeError struct {
+	Path    string // field path
+	Message string // why it cannot be merged
+}
+
+func (e *MergeError) Error() string {
+	return fmt.Sprintf("cannot merge '%s': %s", e.Path, e.Message)
+}
+
+// specMerger merges component specs, with merge strategies by field path
+type specMerger struct {
+	strategies map[string]fieldMergeStrategy
+}
+
+// newSpecMerger returns a spec merger with template merge strategies
+func newSpecMerger(lmsMoodleTemplateSpec map[string]interface{}) (*specMerger, error) {
+	mergeStrategiesU, _, _ := unstructured.NestedSlice(lmsMoodleTemplateSpec, "mergeStrategies")
+	merger := &specMerger{strategies: map[string]fieldMergeStrategy{}}
+	for _, mergeStrategyU := range mergeStrategiesU {
+		mergeStrategyMap, isMap := mergeStrategyU.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		mergeStrategy := lmsv1alpha1.FieldMergeStrategy{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(mergeStrategyMap, &mergeStrategy); err != nil {
+			return nil, err
+		}
+		merger.strategies[mergeStrategy.Path] = fieldMergeStrategy{mergeStrategy.Strategy, mergeStrategy.Keys}
+	}
+	return merger, nil
+}
+
+// strategy returns merge strategy of a field, by path
+func (m *specMerger) strategy(path string) fieldMergeStrategy {
+	if strategy, found := m.strategies[path]; found {
+		return strategy
+	}
+	name := path[strings.LastIndex(path, ".")+1:]
+	for _, defaultStrategy := range defaultMergeStrategies {
+		if strings.HasSuffix(name, defaultStrategy.suffix) {
+			return defaultStrategy.fieldMergeStrategy
+		}
+	}
+	return fieldMergeStrategy{}
+}
+
+// mergeSpec returns a LMSMoodle component spec merged into template one, leaving both
+// untouched. Fields paths start with spec key, such as moodleSpec
+func (m *specMerger) mergeSpec(specKey string, templateSpec map[string]interface{}, spec map[string]interface{}) (map[string]interface{}, error) {
+	merged, err := m.mergeMaps(specKey, templateSpec, spec)
+	if err != nil {
+		return nil, err
+	}
+	if merged == nil {
+		merged = map[string]interface{}{}
+	}
+	return merged, nil
+}
+
+// mergeMaps returns a map merged into template one, field by field
+func (m *specMerger) mergeMaps(path string, template map[string]interface{}, value map[string]interface{}) (map[string]interface{}, error) {
+	if value[PatchMarkerKey] == ReplacePatchMarker {
+		return withoutPatchMarkers(value).(map[string]interface{}), nil
+	}
+
+	merged := runtime.DeepCopyJSON(template)
+	if merged == nil {
+		merged = map[string]interface{}{}
+	}
+	for key, fieldValue := range value {
+		if key == PatchMarkerKey {
+			continue
+		}
+		mergedValue, deleted, err := m.mergeValue(path+"."+key, merged[key], fieldValue)
+		if err != nil {
+			return nil, err
+		}
+		if deleted {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = mergedValue
+	}
+	return merged, nil
+}
+
+// mergeValue returns a value merged into template one, following its merge strategy, and
+// whether it deletes template value
+func (m *specMerger) mergeValue(path string, template interface{}, value interface{}) (merged interface{}, deleted bool, err error) {
+	if isDeletePatchMarker(value) {
+		return nil, true, nil
+	}
+
+	strategy := m.strategy(path)
+	if strategy.strategy == lmsv1alpha1.ReplaceMergeStrategy {
+		return withoutPatchMarkers(value), false, nil
+	}
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		if templateMap, isMap := template.(map[string]interface{}); isMap {
+			merged, err := m.mergeMaps(path, templateMap, typedValue)
+			return merged, false, err
+		}
+	case []interface{}:
+		templateItems, _ := template.([]interface{})
+		switch strategy.strategy {
+		case lmsv1alpha1.AppendMergeStrategy:
+			return appendItems(templateItems, typedValue), false, nil
+		case lmsv1alpha1.MergeByKeyMergeStrategy:
+			merged, err := m.mergeItemsByKey(path, strategy.keys, templateItems, typedValue)
+			return merged, false, err
+		}
+	case string:
+		if templateString, isString := template.(string); isString && strategy.strategy == lmsv1alpha1.MergeByKeyMergeStrategy {
+			merged, err := mergeYAMLMaps(path, templateString, typedValue)
+			return merged, false, err
+		}
+	}
+
+	return withoutPatchMarkers(value), false, nil
+}
+
+// appendItems returns items appended to template ones, skipping those already there.
+// Items with a delete marker delete template items equal to them
+func appendItems(templateItems []interface{}, items []interface{}) []interface{} {
+	merged := runtime.DeepCopyJSONValue(templateItems).([]interface{})
+	for _, item := range items {
+		if isDeletePatchMarker(item) {
+			merged = deleteItems(merged, withoutPatchMarkers(item), nil)
+			continue
+		}
+		if indexOfItem(merged, item, nil) < 0 {
+			merged = append(merged, withoutPatchMarkers(item))
+		}
+	}
+	return merged
+}
+
+// mergeItemsByKey returns items merged with template ones with the same keys, field by field,
+// appending those without one. Items with a delete marker delete template items with the same
+// keys. Items are compared as a whole, if there are no keys or they are not maps
+func (m *specMerger) mergeItemsByKey(path string, keys []string, templateItems []interface{}, items []interface{}) ([]interface{}, error) {
+	merged := runtime.DeepCopyJSONValue(templateItems).([]interface{})
+	for _, item := range items {
+		if isDeletePatchMarker(item) {
+			merged = deleteItems(merged, withoutPatchMarkers(item), keys)
+			continue
+		}
+		index := indexOfItem(merged, item, keys)
+		if index < 0 {
+			merged = append(merged, withoutPatchMarkers(item))
+			continue
+		}
+		mergedItem, _, err := m.mergeValue(path+"[]", merged[index], item)
+		if err != nil {
+			return nil, err
+		}
+		merged[index] = mergedItem
+	}
+	return merged, nil
+}
+
+// deleteItems returns items not matching a delete marker item by the keys it sets, any of
+// its fields if there are no keys, or as a whole if it is not a map
+func deleteItems(items []interface{}, deleteItem interface{}, keys []string) []interface{} {
+	if deleteMap, isMap := deleteItem.(map[string]interface{}); isMap {
+		var setKeys []string
+		for key := range deleteMap {
+			if len(keys) == 0 || slices.Contains(keys, key) {
+				setKeys = append(setKeys, key)
+			}
+		}
+		keys = setKeys
+	}
+
+	kept := []interface{}{}
+	for _, item := range items {
+		if !itemsMatch(item, deleteItem, keys) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// indexOfItem returns index of the item matching another one, -1 if none
+func indexOfItem(items []interface{}, item interface{}, keys []string) int {
+	for i := range items {
+		if itemsMatch(items[i], item, keys) {
+			return i
+		}
+	}
+	return -1
+}
+
+// itemsMatch whether two list items have the same keys, compared as strings so that
+// keys set by unset fields match numbers, or are equal, if there are no keys or they are not maps
+func itemsMatch(item interface{}, other interface{}, keys []string) bool {
+	itemMap, isItemMap := item.(map[string]interface{})
+	otherMap, isOtherMap := other.(map[string]interface{})
+	if len(keys) == 0 || !isItemMap || !isOtherMap {
+		return reflect.DeepEqual(withoutPatchMarkers(item), withoutPatchMarkers(other))
+	}
+	for _, key := range keys {
+		if fmt.Sprint(itemMap[key]) != fmt.Sprint(otherMap[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeYAMLMaps returns a YAML map merged into template one, key by key. Keys set to null are deleted
+func mergeYAMLMaps(path string, template string, value string) (string, error) {
+	templateMap := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(template), &templateMap); err != nil {
+		return "", &MergeError{path, "template value is not a YAML map: " + err.Error()}
+	}
+	valueMap := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(value), &valueMap); err != nil {
+		return "", &MergeError{path, "value is not a YAML map: " + err.Error()}
+	}
+	if templateMap == nil {
+		templateMap = map[string]interface{}{}
+	}
+	for key, keyValue := range valueMap {
+		if keyValue == nil {
+			delete(templateMap, key)
+			continue
+		}
+		templateMap[key] = keyValue
+	}
+	merged, err := yaml.Marshal(templateMap)
+	if err != nil {
+		return "", &MergeError{path, err.Error()}
+	}
+	return string(merged), nil
+}
+
+// isDeletePatchMarker whether a value has a delete marker
+func isDeletePatchMarker(value interface{}) bool {
+	valueMap, isMap := value.(map[string]interface{})
+	return isMap && valueMap[PatchMarkerKey] == DeletePatchMarker
+}
+
+// withoutPatchMarkers returns a copy of a value without merge markers, nor fields and
+// list items with a delete marker
+func withoutPatchMarkers(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(typedValue))
+		for key, fieldValue := range typedValue {
+			if key == PatchMarkerKey || isDeletePatchMarker(fieldValue) {
+				continue
+			}
+			copied[key] = withoutPatchMarkers(fieldValue)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, 0, len(typedValue))
+		for _, item := range typedValue {
+			if isDeletePatchMarker(item) {
+				continue
+			}
+			copied = append(copied, withoutPatchMarkers(item))
+		}
+		return copied
+	}
+	return runtime.DeepCopyJSONValue(value)
+}
+
+// setUnsetFields sets delete markers in a LMSMoodle component spec for the unset fields
+// starting with spec key, such as moodleSpec.moodleHost. List items are set by their keys,
+// such as moodleSpec.phpFpmTolerations[key=dedicated,effect=NoSchedule]
+func setUnsetFields(specKey string, spec map[string]interface{}, unsetFields []string) error {
+	for _, unsetField := range unsetFields {
+		if !strings.HasPrefix(unsetField, specKey+".") {
+			continue
+		}
+		matches := unsetFieldPattern.FindStringSubmatch(unsetField)
+		if matches == nil {
+			return &MergeError{unsetField, "invalid unset field"}
+		}
+		fields := strings.Split(strings.TrimPrefix(matches[1], specKey+"."), ".")
+
+		// field
+		if matches[2] == "" {
+			if err := unstructured.SetNestedField(spec, map[string]interface{}{PatchMarkerKey: DeletePatchMarker}, fields...); err != nil {
+				return &MergeError{unsetField, err.Error()}
+			}
+			continue
+		}
+
+		// list item, by its keys
+		deleteItem := map[string]interface{}{PatchMarkerKey: DeletePatchMarker}
+		for _, keyValue := range strings.Split(matches[2], ",") {
+			key, value, found := strings.Cut(keyValue, "=")
+			if !found {
+				return &MergeError{unsetField, "invalid list item key " + keyValue}
+			}
+			deleteItem[key] = value
+		}
+		items, _, err := unstructured.NestedSlice(spec, fields...)
+		if err != nil {
+			return &MergeError{unsetField, err.Error()}
+		}
+		if err := unstructured.SetNestedSlice(spec, append(items, deleteItem), fields...); err != nil {
+			return &MergeError{unsetField, err.Error()}
+		}
+	}
+	return nil
+}
+
+// mergeComponentSpec returns LMSMoodle component spec merged into template one, once unset
+// fields are set, following template merge strategies
+func (r *LMSMoodleReconciler) mergeComponentSpec(specKey string, templateSpec map[string]interface{}, spec map[string]interface{}) (map[string]interface{}, error) {
+	merger, err := newSpecMerger(r.lmsMoodleCtx.lmsMoodleTemplateSpec)
+	if err != nil {
+		return nil, err
+	}
+	spec = runtime.DeepCopyJSON(spec)
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	if err := setUnsetFields(specKey, spec, r.lmsMoodleCtx.unsetFields); err != nil {
+		return nil, err
+	}
+	return merger.mergeSpec(specKey, templateSpec, spec)
+}
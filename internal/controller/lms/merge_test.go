@@ -0,0 +1,388 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"reflect"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Merge engine", func() {
+	var merger *specMerger
+
+	BeforeEach(func() {
+		var err error
+		merger, err = newSpecMerger(nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	merge := func(specKey string, templateSpec map[string]interface{}, spec map[string]interface{}) map[string]interface{} {
+		merged, err := merger.mergeSpec(specKey, templateSpec, spec)
+		Expect(err).NotTo(HaveOccurred())
+		return merged
+	}
+
+	Context("When merging every field of component specs", func() {
+		componentSpecs := map[string]reflect.Type{
+			"moodleSpec":   reflect.TypeOf(lmsv1alpha1.MoodleSpec{}),
+			"postgresSpec": reflect.TypeOf(lmsv1alpha1.PostgresSpec{}),
+			"nfsSpec":      reflect.TypeOf(lmsv1alpha1.NfsSpec{}),
+			"keydbSpec":    reflect.TypeOf(lmsv1alpha1.KeydbSpec{}),
+		}
+
+		for specKey, specType := range componentSpecs {
+			specKey, specType := specKey, specType
+
+			It("should merge each "+specKey+" field by its type and merge strategy", func() {
+				for i := 0; i < specType.NumField(); i++ {
+					field := specType.Field(i)
+					name := strings.Split(field.Tag.Get("json"), ",")[0]
+					path := specKey + "." + name
+					templateValue, value, expected := mergeFieldSamples(merger.strategy(path), field.Type)
+					Expect(templateValue).NotTo(BeNil(), "field %s has type %s, unknown to merge engine tests", path, field.Type)
+
+					merged := merge(specKey,
+						map[string]interface{}{name: templateValue, "untouched": "template"},
+						map[string]interface{}{name: value},
+					)
+					Expect(merged).To(HaveKeyWithValue("untouched", "template"), "field %s", path)
+					if expectedYAML, isString := expected.(string); isString && strings.Contains(expectedYAML, ": ") {
+						Expect(merged[name]).To(MatchYAML(expectedYAML), "field %s", path)
+						continue
+					}
+					Expect(merged[name]).To(Equal(expected), "field %s", path)
+				}
+			})
+
+			It("should delete each "+specKey+" field with a delete marker or unset field", func() {
+				for i := 0; i < specType.NumField(); i++ {
+					field := specType.Field(i)
+					name := strings.Split(field.Tag.Get("json"), ",")[0]
+					templateValue, _, _ := mergeFieldSamples(merger.strategy(specKey+"."+name), field.Type)
+					templateSpec := map[string]interface{}{name: templateValue}
+
+					merged := merge(specKey, templateSpec, map[string]interface{}{
+						name: map[string]interface{}{PatchMarkerKey: DeletePatchMarker},
+					})
+					Expect(merged).NotTo(HaveKey(name), "field %s.%s", specKey, name)
+
+					spec := map[string]interface{}{}
+					Expect(setUnsetFields(specKey, spec, []string{specKey + "." + name})).To(Succeed())
+					Expect(merge(specKey, templateSpec, spec)).NotTo(HaveKey(name), "field %s.%s", specKey, name)
+				}
+			})
+		}
+	})
+
+	Context("When merging scalar fields", func() {
+		It("should replace template values, even with false, zero or empty values", func() {
+			merged := merge("moodleSpec", map[string]interface{}{
+				"moodleConfigDeveloper": true,
+				"moodleCronjobReplicas": int64(2),
+				"moodleHost":            "template.example.com",
+			}, map[string]interface{}{
+				"moodleConfigDeveloper": false,
+				"moodleCronjobReplicas": int64(0),
+				"moodleHost":            "",
+			})
+			Expect(merged).To(Equal(map[string]interface{}{
+				"moodleConfigDeveloper": false,
+				"moodleCronjobReplicas": int64(0),
+				"moodleHost":            "",
+			}))
+		})
+
+		It("should keep template values not set and leave both specs untouched", func() {
+			templateSpec := map[string]interface{}{"moodleHost": "template.example.com", "moodleProtocol": "https"}
+			spec := map[string]interface{}{"moodleHost": "site.example.com"}
+			merged := merge("moodleSpec", templateSpec, spec)
+			Expect(merged).To(Equal(map[string]interface{}{"moodleHost": "site.example.com", "moodleProtocol": "https"}))
+			Expect(templateSpec).To(HaveKeyWithValue("moodleHost", "template.example.com"))
+			Expect(spec).To(HaveLen(1))
+		})
+	})
+
+	Context("When merging list fields", func() {
+		It("should merge tolerations by key and effect", func() {
+			merged := merge("moodleSpec", map[string]interface{}{
+				"phpFpmTolerations": []interface{}{
+					map[string]interface{}{"key": "dedicated", "effect": "NoSchedule", "operator": "Exists"},
+					map[string]interface{}{"key": "zone", "effect": "NoExecute", "operator": "Exists"},
+				},
+			}, map[string]interface{}{
+				"phpFpmTolerations": []interface{}{
+					map[string]interface{}{"key": "dedicated", "effect": "NoSchedule", "operator": "Equal", "value": "lms"},
+					map[string]interface{}{"key": "zone", "effect": "NoExecute", PatchMarkerKey: DeletePatchMarker},
+					map[string]interface{}{"key": "gpu", "effect": "NoSchedule", "operator": "Exists"},
+				},
+			})
+			Expect(merged["phpFpmTolerations"]).To(Equal([]interface{}{
+				map[string]interface{}{"key": "dedicated", "effect": "NoSchedule", "operator": "Equal", "value": "lms"},
+				map[string]interface{}{"key": "gpu", "effect": "NoSchedule", "operator": "Exists"},
+			}))
+		})
+
+		It("should merge extra ports by port and protocol", func() {
+			merged := merge("postgresSpec", map[string]interface{}{
+				"postgresNetpolIngressExtraPorts": []interface{}{
+					map[string]interface{}{"port": int64(9187), "protocol": "TCP"},
+				},
+			}, map[string]interface{}{
+				"postgresNetpolIngressExtraPorts": []interface{}{
+					map[string]interface{}{"port": int64(9187), "protocol": "UDP"},
+				},
+			})
+			Expect(merged["postgresNetpolIngressExtraPorts"]).To(HaveLen(2))
+		})
+
+		It("should append peers, skipping those already in template", func() {
+			peer := map[string]interface{}{"ipBlock": map[string]interface{}{"cidr": "10.0.0.0/8"}}
+			merged := merge("nfsSpec", map[string]interface{}{
+				"ganeshaNetpolIngressPeers": []interface{}{peer},
+			}, map[string]interface{}{
+				"ganeshaNetpolIngressPeers": []interface{}{
+					peer,
+					map[string]interface{}{"podSelector": map[string]interface{}{}},
+				},
+			})
+			Expect(merged["ganeshaNetpolIngressPeers"]).To(Equal([]interface{}{
+				peer,
+				map[string]interface{}{"podSelector": map[string]interface{}{}},
+			}))
+		})
+
+		It("should replace other lists", func() {
+			merged := merge("moodleSpec", map[string]interface{}{
+				"routineStatusCrNotify": map[string]interface{}{"statusCode": []interface{}{int64(200), int64(201)}},
+			}, map[string]interface{}{
+				"routineStatusCrNotify": map[string]interface{}{"statusCode": []interface{}{int64(204)}},
+			})
+			Expect(merged["routineStatusCrNotify"]).To(Equal(map[string]interface{}{"statusCode": []interface{}{int64(204)}}))
+		})
+
+		It("should merge notify headers by name", func() {
+			merged := merge("moodleSpec", map[string]interface{}{
+				"routineStatusCrNotify": map[string]interface{}{
+					"url": "https://template.example.com",
+					"headers": []interface{}{
+						map[string]interface{}{"name": "X-Site", "value": "template"},
+						map[string]interface{}{"name": "X-Token", "value": "template"},
+					},
+				},
+			}, map[string]interface{}{
+				"routineStatusCrNotify": map[string]interface{}{
+					"headers": []interface{}{
+						map[string]interface{}{"name": "X-Site", "value": "site"},
+						map[string]interface{}{"name": "X-Token", PatchMarkerKey: DeletePatchMarker},
+					},
+				},
+			})
+			Expect(merged["routineStatusCrNotify"]).To(Equal(map[string]interface{}{
+				"url": "https://template.example.com",
+				"headers": []interface{}{
+					map[string]interface{}{"name": "X-Site", "value": "site"},
+				},
+			}))
+		})
+	})
+
+	Context("When merging map and YAML fields", func() {
+		It("should merge maps field by field, deleting fields with a delete marker", func() {
+			merged := merge("moodleSpec", map[string]interface{}{
+				"moodleConfigAdditionalCfg": map[string]interface{}{"debug": int64(0), "theme": "boost", "noemailever": true},
+			}, map[string]interface{}{
+				"moodleConfigAdditionalCfg": map[string]interface{}{"debug": int64(32767), "noemailever": map[string]interface{}{PatchMarkerKey: DeletePatchMarker}},
+			})
+			Expect(merged["moodleConfigAdditionalCfg"]).To(Equal(map[string]interface{}{"debug": int64(32767), "theme": "boost"}))
+		})
+
+		It("should replace maps with a replace marker", func() {
+			merged := merge("moodleSpec", map[string]interface{}{
+				"moodleConfigAdditionalCfg": map[string]interface{}{"debug": int64(0), "theme": "boost"},
+			}, map[string]interface{}{
+				"moodleConfigAdditionalCfg": map[string]interface{}{"debug": int64(32767), PatchMarkerKey: ReplacePatchMarker},
+			})
+			Expect(merged["moodleConfigAdditionalCfg"]).To(Equal(map[string]interface{}{"debug": int64(32767)}))
+		})
+
+		It("should merge ingress annotations key by key, deleting those set to null", func() {
+			merged := merge("moodleSpec", map[string]interface{}{
+				"nginxIngressAnnotations": "cert-manager.io/cluster-issuer: letsencrypt\nnginx.ingress.kubernetes.io/proxy-body-size: 50m\n",
+			}, map[string]interface{}{
+				"nginxIngressAnnotations": "nginx.ingress.kubernetes.io/proxy-body-size: 100m\ncert-manager.io/cluster-issuer: null\n",
+			})
+			Expect(merged["nginxIngressAnnotations"]).To(MatchYAML("nginx.ingress.kubernetes.io/proxy-body-size: 100m"))
+		})
+
+		It("should fail to merge ingress annotations not being a YAML map", func() {
+			_, err := merger.mergeSpec("moodleSpec", map[string]interface{}{
+				"nginxIngressAnnotations": "a: b",
+			}, map[string]interface{}{
+				"nginxIngressAnnotations": "- a",
+			})
+			Expect(err).To(BeAssignableToTypeOf(&MergeError{}))
+		})
+	})
+
+	Context("When setting unset fields", func() {
+		It("should delete template fields and list items by keys", func() {
+			spec := map[string]interface{}{"moodleHost": "site.example.com"}
+			Expect(setUnsetFields("moodleSpec", spec, []string{
+				"moodleSpec.moodleConfigAdditionalCfg.theme",
+				"moodleSpec.nginxTolerations[key=dedicated]",
+				"moodleSpec.moodleNetpolIngressExtraPorts[port=443,protocol=TCP]",
+				"postgresSpec.postgresImage",
+			})).To(Succeed())
+
+			merged := merge("moodleSpec", map[string]interface{}{
+				"moodleConfigAdditionalCfg": map[string]interface{}{"debug": int64(0), "theme": "boost"},
+				"nginxTolerations": []interface{}{
+					map[string]interface{}{"key": "dedicated", "effect": "NoSchedule"},
+					map[string]interface{}{"key": "dedicated", "effect": "NoExecute"},
+					map[string]interface{}{"key": "zone", "effect": "NoSchedule"},
+				},
+				"moodleNetpolIngressExtraPorts": []interface{}{
+					map[string]interface{}{"port": int64(443), "protocol": "TCP"},
+					map[string]interface{}{"port": int64(8080), "protocol": "TCP"},
+				},
+			}, spec)
+			Expect(merged).To(Equal(map[string]interface{}{
+				"moodleHost":                "site.example.com",
+				"moodleConfigAdditionalCfg": map[string]interface{}{"debug": int64(0)},
+				"nginxTolerations": []interface{}{
+					map[string]interface{}{"key": "zone", "effect": "NoSchedule"},
+				},
+				"moodleNetpolIngressExtraPorts": []interface{}{
+					map[string]interface{}{"port": int64(8080), "protocol": "TCP"},
+				},
+			}))
+		})
+
+		It("should fail with invalid list item keys", func() {
+			err := setUnsetFields("moodleSpec", map[string]interface{}{}, []string{"moodleSpec.nginxTolerations[dedicated]"})
+			Expect(err).To(BeAssignableToTypeOf(&MergeError{}))
+		})
+	})
+
+	Context("When template sets merge strategies", func() {
+		It("should take precedence over default ones", func() {
+			var err error
+			merger, err = newSpecMerger(map[string]interface{}{
+				"mergeStrategies": []interface{}{
+					map[string]interface{}{"path": "keydbSpec.keydbTolerations", "strategy": lmsv1alpha1.ReplaceMergeStrategy},
+					map[string]interface{}{"path": "moodleSpec.routineStatusCrNotify.statusCode", "strategy": lmsv1alpha1.AppendMergeStrategy},
+					map[string]interface{}{"path": "moodleSpec.moodleConfigAdditionalCfg", "strategy": lmsv1alpha1.ReplaceMergeStrategy},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			merged := merge("keydbSpec", map[string]interface{}{
+				"keydbTolerations": []interface{}{map[string]interface{}{"key": "dedicated", "effect": "NoSchedule"}},
+			}, map[string]interface{}{
+				"keydbTolerations": []interface{}{map[string]interface{}{"key": "zone", "effect": "NoSchedule"}},
+			})
+			Expect(merged["keydbTolerations"]).To(Equal([]interface{}{map[string]interface{}{"key": "zone", "effect": "NoSchedule"}}))
+
+			merged = merge("moodleSpec", map[string]interface{}{
+				"routineStatusCrNotify":     map[string]interface{}{"statusCode": []interface{}{int64(200)}},
+				"moodleConfigAdditionalCfg": map[string]interface{}{"debug": int64(0), "theme": "boost"},
+			}, map[string]interface{}{
+				"routineStatusCrNotify":     map[string]interface{}{"statusCode": []interface{}{int64(204)}},
+				"moodleConfigAdditionalCfg": map[string]interface{}{"debug": int64(32767)},
+			})
+			Expect(merged["routineStatusCrNotify"]).To(Equal(map[string]interface{}{"statusCode": []interface{}{int64(200), int64(204)}}))
+			Expect(merged["moodleConfigAdditionalCfg"]).To(Equal(map[string]interface{}{"debug": int64(32767)}))
+		})
+	})
+})
+
+// mergeFieldSamples returns template and LMSMoodle sample values of a component spec field
+// type, along with their expected merge, following field merge strategy. Template value is
+// nil if the field type is unknown
+func mergeFieldSamples(strategy fieldMergeStrategy, fieldType reflect.Type) (templateValue interface{}, value interface{}, expected interface{}) {
+	switch fieldType.Kind() {
+	case reflect.String:
+		if strategy.strategy == lmsv1alpha1.MergeByKeyMergeStrategy {
+			return "template: template\nboth: template\n", "site: site\nboth: site\n", "template: template\nsite: site\nboth: site\n"
+		}
+		return "template", "site", "site"
+	case reflect.Bool:
+		return true, false, false
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		return int64(1), int64(0), int64(0)
+	case reflect.Struct:
+		return map[string]interface{}{"template": "template", "both": "template"},
+			map[string]interface{}{"site": "site", "both": "site"},
+			map[string]interface{}{"template": "template", "site": "site", "both": "site"}
+	case reflect.Slice:
+		templateItem, item := listItemSamples(strategy, fieldType.Elem())
+		if templateItem == nil {
+			return nil, nil, nil
+		}
+		switch strategy.strategy {
+		case lmsv1alpha1.AppendMergeStrategy, lmsv1alpha1.MergeByKeyMergeStrategy:
+			return []interface{}{templateItem}, []interface{}{item}, []interface{}{templateItem, item}
+		}
+		return []interface{}{templateItem}, []interface{}{item}, []interface{}{item}
+	}
+	return nil, nil, nil
+}
+
+// listItemSamples returns template and LMSMoodle sample list items of a type, differing in
+// merge keys, which must be fields of the item type. Items are nil if the type is unknown
+func listItemSamples(strategy fieldMergeStrategy, itemType reflect.Type) (templateItem interface{}, item interface{}) {
+	switch itemType.Kind() {
+	case reflect.String:
+		return "template", "site"
+	case reflect.Int32, reflect.Int64:
+		return int64(1), int64(2)
+	case reflect.Struct:
+		templateItemMap := map[string]interface{}{}
+		itemMap := map[string]interface{}{}
+		for _, key := range strategy.keys {
+			field, found := structFieldByJSONName(itemType, key)
+			if !found {
+				return nil, nil
+			}
+			templateItemMap[key], itemMap[key] = listItemSamples(fieldMergeStrategy{}, field.Type)
+			if templateItemMap[key] == nil {
+				return nil, nil
+			}
+		}
+		if len(strategy.keys) == 0 {
+			templateItemMap["template"], itemMap["site"] = "template", "site"
+		}
+		return templateItemMap, itemMap
+	}
+	return nil, nil
+}
+
+// structFieldByJSONName returns a struct field by its json name
+func structFieldByJSONName(structType reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
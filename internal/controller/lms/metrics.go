@@ -0,0 +1,252 @@
+package lms
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// timeToReadySeconds observes how long a LMSMoodle takes to be ready after an operation
+	timeToReadySeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "lmsmoodle_time_to_ready_seconds",
+			Help:    "Time for a LMSMoodle to become ready after being provisioned, upgraded or resumed",
+			Buckets: []float64{30, 60, 120, 300, 600, 900, 1200, 1800, 2700, 3600, 7200},
+		},
+		[]string{"template", "operation"},
+	)
+
+	// lmsMoodleMetrics collects business metrics of every LMSMoodle
+	lmsMoodleMetrics = newLMSMoodleCollector()
+
+	// knownStates are always exposed by one-hot state metrics. Any other state,
+	// such as PostgresCreating, is exposed as progressing
+	knownStates = []string{
+		ProgressingMetricState,
+		lmsv1alpha1.UnknownState,
+		lmsv1alpha1.FailedState,
+		lmsv1alpha1.ReadyState,
+		lmsv1alpha1.TerminatingState,
+		lmsv1alpha1.SuspendedState,
+		lmsv1alpha1.PartiallySuspendedState,
+		lmsv1alpha1.PausedState,
+	}
+
+	// knownDesiredStates are always exposed by one-hot desired state metrics
+	knownDesiredStates = []string{
+		lmsv1alpha1.ReadyState,
+		lmsv1alpha1.SuspendedState,
+	}
+)
+
+const (
+	// ProgressingMetricState is the state label value of transient states
+	ProgressingMetricState string = "Progressing"
+)
+
+var (
+	lmsMoodleStateDesc = prometheus.NewDesc(
+		"lmsmoodle_state",
+		"Current state of a LMSMoodle, 1 for current state",
+		[]string{"name", "template", "state"}, nil,
+	)
+	lmsMoodleDesiredStateDesc = prometheus.NewDesc(
+		"lmsmoodle_desired_state",
+		"Desired state of a LMSMoodle, 1 for desired state",
+		[]string{"name", "template", "state"}, nil,
+	)
+	lmsMoodleRegisteredUsersDesc = prometheus.NewDesc(
+		"lmsmoodle_registered_users",
+		"Registered users of a LMSMoodle",
+		[]string{"name", "template"}, nil,
+	)
+	lmsMoodleStorageGbDesc = prometheus.NewDesc(
+		"lmsmoodle_storage_gb",
+		"Storage used by a LMSMoodle, in GB",
+		[]string{"name", "template"}, nil,
+	)
+	lmsMoodleReleaseDesc = prometheus.NewDesc(
+		"lmsmoodle_release_info",
+		"Moodle release of a LMSMoodle, always 1",
+		[]string{"name", "template", "release"}, nil,
+	)
+	lmsMoodleComponentReadyDesc = prometheus.NewDesc(
+		"lmsmoodle_component_ready",
+		"Whether a LMSMoodle component is ready, 1 if ready",
+		[]string{"name", "template", "component"}, nil,
+	)
+	lmsMoodlesByStateDesc = prometheus.NewDesc(
+		"lmsmoodles_by_state",
+		"Number of LMSMoodles per state",
+		[]string{"state"}, nil,
+	)
+	lmsMoodlesByReleaseDesc = prometheus.NewDesc(
+		"lmsmoodles_by_release",
+		"Number of LMSMoodles per Moodle release",
+		[]string{"release"}, nil,
+	)
+)
+
+func init() {
+	// Register custom metrics with the global controller-runtime registry
+	metrics.Registry.MustRegister(timeToReadySeconds, lmsMoodleMetrics)
+}
+
+// lmsMoodleSiteMetrics holds last observed business values of a LMSMoodle
+type lmsMoodleSiteMetrics struct {
+	template           string
+	state              string
+	desiredState       string
+	release            string
+	registeredUsers    int64
+	registeredUsersSet bool
+	storageGb          float64
+	storageGbSet       bool
+	components         map[string]bool
+}
+
+// lmsMoodleCollector exposes per LMSMoodle and fleet metrics from last observed values
+type lmsMoodleCollector struct {
+	mu    sync.RWMutex
+	sites map[string]*lmsMoodleSiteMetrics
+}
+
+// newLMSMoodleCollector returns an empty LMSMoodle collector
+func newLMSMoodleCollector() *lmsMoodleCollector {
+	return &lmsMoodleCollector{sites: map[string]*lmsMoodleSiteMetrics{}}
+}
+
+// site returns metrics of a LMSMoodle, creating them if needed. Lock must be held
+func (c *lmsMoodleCollector) site(name string) *lmsMoodleSiteMetrics {
+	site, found := c.sites[name]
+	if !found {
+		site = &lmsMoodleSiteMetrics{components: map[string]bool{}}
+		c.sites[name] = site
+	}
+	return site
+}
+
+// SetState records state, desired state and component readiness of a LMSMoodle
+func (c *lmsMoodleCollector) SetState(name string, template string, state string, desiredState string, components map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	site := c.site(name)
+	site.template = template
+	site.state = metricState(knownStates, state, ProgressingMetricState)
+	site.desiredState = metricState(knownDesiredStates, desiredState, "")
+	site.components = components
+}
+
+// SetUsageFromStatus records registered users, storage and release from LMSMoodle status
+func (c *lmsMoodleCollector) SetUsageFromStatus(siteU *unstructured.Unstructured) {
+	template, _, _ := unstructured.NestedString(siteU.Object, "spec", "lmsMoodleTemplateName")
+	registeredUsers, registeredUsersFound, _ := unstructured.NestedInt64(siteU.Object, "status", "registeredUsers")
+	storageGbString, storageGbFound, _ := unstructured.NestedString(siteU.Object, "status", "storageGb")
+	release, _, _ := unstructured.NestedString(siteU.Object, "status", "release")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	site := c.site(siteU.GetName())
+	site.template = template
+	site.release = release
+	site.registeredUsers = registeredUsers
+	site.registeredUsersSet = registeredUsersFound
+	site.storageGbSet = false
+	if storageGbFound {
+		if storageGb, err := strconv.ParseFloat(storageGbString, 64); err == nil {
+			site.storageGb = storageGb
+			site.storageGbSet = true
+		}
+	}
+}
+
+// Forget stops exposing metrics of a LMSMoodle
+func (c *lmsMoodleCollector) Forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.sites, name)
+}
+
+// Describe implements prometheus.Collector
+func (c *lmsMoodleCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lmsMoodleStateDesc
+	ch <- lmsMoodleDesiredStateDesc
+	ch <- lmsMoodleRegisteredUsersDesc
+	ch <- lmsMoodleStorageGbDesc
+	ch <- lmsMoodleReleaseDesc
+	ch <- lmsMoodleComponentReadyDesc
+	ch <- lmsMoodlesByStateDesc
+	ch <- lmsMoodlesByReleaseDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *lmsMoodleCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	byState := map[string]float64{}
+	for _, state := range knownStates {
+		byState[state] = 0
+	}
+	byRelease := map[string]float64{}
+
+	for name, site := range c.sites {
+		if site.state != "" {
+			byState[site.state]++
+			for _, state := range knownStates {
+				ch <- prometheus.MustNewConstMetric(lmsMoodleStateDesc, prometheus.GaugeValue, boolToFloat64(state == site.state), name, site.template, state)
+			}
+		}
+		if site.desiredState != "" {
+			for _, state := range knownDesiredStates {
+				ch <- prometheus.MustNewConstMetric(lmsMoodleDesiredStateDesc, prometheus.GaugeValue, boolToFloat64(state == site.desiredState), name, site.template, state)
+			}
+		}
+		if site.registeredUsersSet {
+			ch <- prometheus.MustNewConstMetric(lmsMoodleRegisteredUsersDesc, prometheus.GaugeValue, float64(site.registeredUsers), name, site.template)
+		}
+		if site.storageGbSet {
+			ch <- prometheus.MustNewConstMetric(lmsMoodleStorageGbDesc, prometheus.GaugeValue, site.storageGb, name, site.template)
+		}
+		if site.release != "" {
+			byRelease[site.release]++
+			ch <- prometheus.MustNewConstMetric(lmsMoodleReleaseDesc, prometheus.GaugeValue, 1, name, site.template, site.release)
+		}
+		for component, ready := range site.components {
+			ch <- prometheus.MustNewConstMetric(lmsMoodleComponentReadyDesc, prometheus.GaugeValue, boolToFloat64(ready), name, site.template, component)
+		}
+	}
+
+	for state, count := range byState {
+		ch <- prometheus.MustNewConstMetric(lmsMoodlesByStateDesc, prometheus.GaugeValue, count, state)
+	}
+	for release, count := range byRelease {
+		ch <- prometheus.MustNewConstMetric(lmsMoodlesByReleaseDesc, prometheus.GaugeValue, count, release)
+	}
+}
+
+// metricState returns a state as label value, if known. Otherwise, the default state,
+// so that label values are bounded
+func metricState(states []string, state string, defaultState string) string {
+	if state == "" || slices.Contains(states, state) {
+		return state
+	}
+	return defaultState
+}
+
+// boolToFloat64 returns 1 if true, 0 otherwise
+func boolToFloat64(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
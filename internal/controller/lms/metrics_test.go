@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("LMSMoodle metrics", func() {
+	var collector *lmsMoodleCollector
+
+	BeforeEach(func() {
+		collector = newLMSMoodleCollector()
+	})
+
+	// collect returns collected metric values by metric name and label values
+	collect := func() map[string]float64 {
+		ch := make(chan prometheus.Metric, 100)
+		collector.Collect(ch)
+		close(ch)
+		values := map[string]float64{}
+		for metric := range ch {
+			m := &dto.Metric{}
+			Expect(metric.Write(m)).To(Succeed())
+			// fqName is the first quoted string of a description
+			key := strings.Split(metric.Desc().String(), `"`)[1] + ":"
+			for _, label := range m.GetLabel() {
+				key += label.GetName() + "=" + label.GetValue() + ","
+			}
+			values[key] = m.GetGauge().GetValue()
+		}
+		return values
+	}
+
+	Context("When a LMSMoodle is in a transient state", func() {
+		It("should expose it as progressing, with bounded state labels", func() {
+			collector.SetState("site", "template", "PostgresCreating", lmsv1alpha1.ReadyState, map[string]bool{"postgres": false})
+			values := collect()
+			Expect(values).To(HaveKeyWithValue("lmsmoodle_state:name=site,state=Progressing,template=template,", 1.0))
+			Expect(values).To(HaveKeyWithValue("lmsmoodle_state:name=site,state=Ready,template=template,", 0.0))
+			Expect(values).To(HaveKeyWithValue("lmsmoodles_by_state:state=Progressing,", 1.0))
+			for key := range values {
+				Expect(key).NotTo(ContainSubstring("PostgresCreating"))
+			}
+		})
+
+		It("should not expose unknown desired states", func() {
+			collector.SetState("site", "template", lmsv1alpha1.ReadyState, "Invalid", nil)
+			for key := range collect() {
+				Expect(key).NotTo(ContainSubstring("Invalid"))
+			}
+		})
+	})
+
+	Context("When a LMSMoodle is forgotten", func() {
+		It("should no longer expose its metrics", func() {
+			collector.SetState("site", "template", lmsv1alpha1.ReadyState, lmsv1alpha1.ReadyState, map[string]bool{"moodle": true})
+			Expect(collect()).To(HaveKeyWithValue("lmsmoodle_component_ready:component=moodle,name=site,template=template,", 1.0))
+
+			collector.Forget("site")
+			for key := range collect() {
+				Expect(key).NotTo(ContainSubstring("name=site"))
+			}
+		})
+	})
+})
@@ -0,0 +1,235 @@
+package lms
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+const (
+	// PodSecurityLabelPrefix prefix of Pod Security Admission namespace labels
+	PodSecurityLabelPrefix string = "pod-security.kubernetes.io/"
+	// SystemNamespacePrefix prefix of system namespaces, never target namespaces
+	SystemNamespacePrefix string = "kube-"
+)
+
+// systemNamespaces are never target namespaces, along with those with SystemNamespacePrefix
+var systemNamespaces = []string{metav1.NamespaceDefault}
+
+// NamespacePolicy restricts namespaces LMSMoodles can read from or place resources in, other
+// than the ones created for them
+type NamespacePolicy struct {
+	// SecretNamespaces where secrets referenced by LMSMoodles, such as status notify secrets,
+	// can be read from, besides the namespace created for each LMSMoodle
+	SecretNamespaces []string
+	// TargetNamespaces LMSMoodles can deploy dependants in, instead of namespaces created for them
+	TargetNamespaces []string
+	// TargetNamespaceSelector selects, by label, other namespaces LMSMoodles can deploy dependants in.
+	// None if nil
+	TargetNamespaceSelector labels.Selector
+	// OperatorNamespace the operator runs in, never a target namespace, as system namespaces
+	OperatorNamespace string
+}
+
+// targetNamespaceAllowed returns whether LMSMoodles can deploy dependants in a namespace
+func (p NamespacePolicy) targetNamespaceAllowed(namespace *corev1.Namespace) bool {
+	name := namespace.GetName()
+	if slices.Contains(systemNamespaces, name) || strings.HasPrefix(name, SystemNamespacePrefix) || name == p.OperatorNamespace {
+		return false
+	}
+	if slices.Contains(p.TargetNamespaces, name) {
+		return true
+	}
+	return p.TargetNamespaceSelector != nil && p.TargetNamespaceSelector.Matches(labels.Set(namespace.GetLabels()))
+}
+
+// TargetNamespaceNotFoundError is returned when LMSMoodle target namespace does not exist
+type TargetNamespaceNotFoundError struct {
+	Name string // namespace name
+}
+
+func (e *TargetNamespaceNotFoundError) Error() string {
+	return fmt.Sprintf("target namespace '%s' not found. It must exist, since it is not created by the operator", e.Name)
+}
+
+// TargetNamespaceNotAllowedError is returned when LMSMoodle target namespace is not allowed
+// by namespace policy
+type TargetNamespaceNotAllowedError struct {
+	Name string // namespace name
+}
+
+func (e *TargetNamespaceNotAllowedError) Error() string {
+	return fmt.Sprintf("target namespace '%s' is not allowed. It must be one of the operator target namespaces and neither a system nor the operator namespace", e.Name)
+}
+
+// defineNamespace define lms moodle namespace with LMSMoodle labels and annotations propagated
+// to it, namespace metadata labels and annotations and Pod Security Admission labels. Since namespace is applied, labels and
+// annotations dropped from spec are removed from it. Should be used once lms moodle labels are set
+func (r *LMSMoodleReconciler) defineNamespace() error {
+	// target namespace is not managed by the operator
+	if r.lmsMoodleCtx.targetNamespace != "" {
+		return nil
+	}
+
+	namespaceMetadata, err := r.getNamespaceMetadata()
+	if err != nil {
+		return err
+	}
+
+	propagatedLabels, propagatedAnnotations, err := r.propagatedMetadata(lmsv1alpha1.NamespacePropagationTarget)
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{}
+	for key, value := range namespaceMetadata.Labels {
+		labels[key] = value
+	}
+	for key, value := range podSecurityLabels(namespaceMetadata.PodSecurity) {
+		labels[key] = value
+	}
+	for key, value := range propagatedLabels {
+		labels[key] = value
+	}
+	annotations := mergeStringMaps(namespaceMetadata.Annotations, propagatedAnnotations)
+
+	r.lmsMoodleCtx.namespace.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"}
+	r.lmsMoodleCtx.namespace.SetLabels(labels)
+	if len(annotations) > 0 {
+		r.lmsMoodleCtx.namespace.SetAnnotations(annotations)
+	}
+
+	return nil
+}
+
+// reconcileNamespace applies lms moodle namespace. Namespaces created by operator versions
+// before server-side apply are applied again, once their labels are owned by the operator,
+// so that those no longer set are removed
+func (r *LMSMoodleReconciler) reconcileNamespace(ctx context.Context) error {
+	namespace := r.lmsMoodleCtx.namespace.DeepCopy()
+	if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.namespace); err != nil {
+		return err
+	}
+
+	if moved, err := r.ReconcileLegacyLabels(ctx, r.lmsMoodleCtx.namespace, OPERATORNAME); err != nil || !moved {
+		return err
+	}
+	r.lmsMoodleCtx.namespace = namespace
+	return r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.namespace)
+}
+
+// reconcileTargetNamespace reads LMSMoodle target namespace, without taking ownership
+// of it. It returns a TargetNamespaceNotFoundError if it does not exist or is being deleted,
+// and a TargetNamespaceNotAllowedError if namespace policy does not allow it
+func (r *LMSMoodleReconciler) reconcileTargetNamespace(ctx context.Context) error {
+	// namespaces are not cached
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	if err := reader.Get(ctx, types.NamespacedName{Name: r.lmsMoodleCtx.namespaceName}, r.lmsMoodleCtx.namespace); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	} else if !r.NamespacePolicy.targetNamespaceAllowed(r.lmsMoodleCtx.namespace) {
+		targetNamespaceNotAllowedError := &TargetNamespaceNotAllowedError{r.lmsMoodleCtx.namespaceName}
+		r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, corev1.EventTypeWarning, TargetNamespaceNotAllowedEventReason, targetNamespaceNotAllowedError.Error())
+		return targetNamespaceNotAllowedError
+	} else if r.lmsMoodleCtx.namespace.GetDeletionTimestamp() == nil {
+		return nil
+	}
+
+	targetNamespaceNotFoundError := &TargetNamespaceNotFoundError{r.lmsMoodleCtx.namespaceName}
+	r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, corev1.EventTypeWarning, TargetNamespaceNotFoundEventReason, targetNamespaceNotFoundError.Error())
+	return targetNamespaceNotFoundError
+}
+
+// getNamespaceMetadata returns LMSMoodleTemplate namespace metadata, overridden by LMSMoodle one.
+// Labels and annotations are overridden by key and Pod Security Admission levels, field by field
+func (r *LMSMoodleReconciler) getNamespaceMetadata() (lmsv1alpha1.NamespaceMetadata, error) {
+	namespaceMetadata := lmsv1alpha1.NamespaceMetadata{}
+
+	for _, spec := range []map[string]interface{}{r.lmsMoodleCtx.lmsMoodleTemplateSpec, r.lmsMoodleCtx.spec} {
+		specNamespaceMetadataU, found, _ := unstructured.NestedMap(spec, "namespaceMetadata")
+		if !found {
+			continue
+		}
+		specNamespaceMetadata := lmsv1alpha1.NamespaceMetadata{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specNamespaceMetadataU, &specNamespaceMetadata); err != nil {
+			return namespaceMetadata, err
+		}
+		namespaceMetadata.Labels = mergeStringMaps(namespaceMetadata.Labels, specNamespaceMetadata.Labels)
+		namespaceMetadata.Annotations = mergeStringMaps(namespaceMetadata.Annotations, specNamespaceMetadata.Annotations)
+		if specPodSecurity := specNamespaceMetadata.PodSecurity; specPodSecurity != nil {
+			if namespaceMetadata.PodSecurity == nil {
+				namespaceMetadata.PodSecurity = &lmsv1alpha1.NamespacePodSecurity{}
+			}
+			podSecurity := namespaceMetadata.PodSecurity
+			for _, field := range []struct {
+				value    string
+				override *string
+			}{
+				{string(specPodSecurity.Enforce), (*string)(&podSecurity.Enforce)},
+				{string(specPodSecurity.EnforceVersion), (*string)(&podSecurity.EnforceVersion)},
+				{string(specPodSecurity.Audit), (*string)(&podSecurity.Audit)},
+				{string(specPodSecurity.AuditVersion), (*string)(&podSecurity.AuditVersion)},
+				{string(specPodSecurity.Warn), (*string)(&podSecurity.Warn)},
+				{string(specPodSecurity.WarnVersion), (*string)(&podSecurity.WarnVersion)},
+			} {
+				if field.value != "" {
+					*field.override = field.value
+				}
+			}
+		}
+	}
+
+	return namespaceMetadata, nil
+}
+
+// podSecurityLabels returns Pod Security Admission namespace labels of levels and versions set
+func podSecurityLabels(podSecurity *lmsv1alpha1.NamespacePodSecurity) map[string]string {
+	labels := map[string]string{}
+	if podSecurity == nil {
+		return labels
+	}
+	for mode, value := range map[string]string{
+		"enforce":         string(podSecurity.Enforce),
+		"enforce-version": string(podSecurity.EnforceVersion),
+		"audit":           string(podSecurity.Audit),
+		"audit-version":   string(podSecurity.AuditVersion),
+		"warn":            string(podSecurity.Warn),
+		"warn-version":    string(podSecurity.WarnVersion),
+	} {
+		if value != "" {
+			labels[PodSecurityLabelPrefix+mode] = value
+		}
+	}
+	return labels
+}
+
+// mergeStringMaps returns a map overridden by another one, by key
+func mergeStringMaps(m map[string]string, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return m
+	}
+	merged := map[string]string{}
+	for key, value := range m {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+	return merged
+}
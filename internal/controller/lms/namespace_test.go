@@ -0,0 +1,207 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Namespace", func() {
+	var r *LMSMoodleReconciler
+
+	BeforeEach(func() {
+		r = &LMSMoodleReconciler{}
+		r.lmsMoodleCtx.name = "site"
+		r.lmsMoodleCtx.lmsMoodle = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		r.lmsMoodleCtx.lmsMoodle.SetName("site")
+		r.lmsMoodleCtx.namespace = &corev1.Namespace{}
+		r.lmsMoodleCtx.lmsMoodleTemplateSpec = map[string]interface{}{"namespaceMetadata": map[string]interface{}{
+			"podSecurity": map[string]interface{}{"enforce": "baseline", "warn": "restricted"},
+			"labels":      map[string]interface{}{"team": "education", "tier": "shared"},
+		}}
+		r.lmsMoodleCtx.spec = map[string]interface{}{"namespaceMetadata": map[string]interface{}{
+			"podSecurity": map[string]interface{}{"enforce": "restricted"},
+			"labels":      map[string]interface{}{"tier": "dedicated"},
+			"annotations": map[string]interface{}{"example.com/cost-centre": "education"},
+		}}
+	})
+
+	It("should define labels and annotations from namespace metadata, overridden by LMSMoodle", func() {
+		Expect(r.defineNamespace()).To(Succeed())
+		labels := r.lmsMoodleCtx.namespace.GetLabels()
+		Expect(labels).To(HaveKeyWithValue("team", "education"))
+		Expect(labels).To(HaveKeyWithValue("tier", "dedicated"))
+		Expect(labels).To(HaveKeyWithValue(PodSecurityLabelPrefix+"enforce", "restricted"))
+		Expect(labels).To(HaveKeyWithValue(PodSecurityLabelPrefix+"warn", "restricted"))
+		Expect(labels).To(HaveKeyWithValue(lmsv1alpha1.GroupVersion.Group+"/lms-name", "site"))
+		Expect(r.lmsMoodleCtx.namespace.GetAnnotations()).To(Equal(map[string]string{"example.com/cost-centre": "education"}))
+	})
+
+	It("should not define a target namespace", func() {
+		r.lmsMoodleCtx.targetNamespace = "shared"
+		Expect(r.defineNamespace()).To(Succeed())
+		Expect(r.lmsMoodleCtx.namespace.GetLabels()).To(BeEmpty())
+	})
+
+	Context("When labels are owned by the legacy field manager", func() {
+		managedFieldsEntry := func(manager string, operation metav1.ManagedFieldsOperationType, fieldsV1 string) metav1.ManagedFieldsEntry {
+			return metav1.ManagedFieldsEntry{
+				Manager:    manager,
+				Operation:  operation,
+				APIVersion: "v1",
+				FieldsType: "FieldsV1",
+				FieldsV1:   &metav1.FieldsV1{Raw: []byte(fieldsV1)},
+			}
+		}
+
+		fieldsOf := func(entry metav1.ManagedFieldsEntry) map[string]interface{} {
+			fields := map[string]interface{}{}
+			Expect(json.Unmarshal(entry.FieldsV1.Raw, &fields)).To(Succeed())
+			return fields
+		}
+
+		legacyManagedFields := func() []metav1.ManagedFieldsEntry {
+			return []metav1.ManagedFieldsEntry{
+				managedFieldsEntry(LegacyFieldManager, metav1.ManagedFieldsOperationUpdate,
+					`{"f:metadata":{"f:labels":{".":{},"f:stale":{},"f:team":{}},"f:ownerReferences":{}}}`),
+				managedFieldsEntry(OPERATORNAME, metav1.ManagedFieldsOperationApply, `{"f:metadata":{"f:labels":{"f:team":{}}}}`),
+				managedFieldsEntry("kubectl-label", metav1.ManagedFieldsOperationUpdate, `{"f:metadata":{"f:labels":{"f:manual":{}}}}`),
+			}
+		}
+
+		It("should move their ownership to the apply field manager, keeping other fields and managers", func() {
+			managedFields, moved, err := moveLabelsOwnership(legacyManagedFields(), OPERATORNAME)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(moved).To(BeTrue())
+			Expect(managedFields).To(HaveLen(3))
+			Expect(fieldsOf(managedFields[0])).To(Equal(map[string]interface{}{
+				"f:metadata": map[string]interface{}{"f:ownerReferences": map[string]interface{}{}},
+			}))
+			Expect(fieldsOf(managedFields[1])).To(Equal(map[string]interface{}{
+				"f:metadata": map[string]interface{}{"f:labels": map[string]interface{}{
+					".": map[string]interface{}{}, "f:stale": map[string]interface{}{}, "f:team": map[string]interface{}{},
+				}},
+			}))
+			Expect(managedFields[2]).To(Equal(legacyManagedFields()[2]))
+		})
+
+		It("should add an apply entry if missing and drop legacy entries owning nothing else", func() {
+			managedFields, moved, err := moveLabelsOwnership(legacyManagedFields()[:1], LMSMoodleMetadataFieldManager)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(moved).To(BeTrue())
+			Expect(managedFields).To(HaveLen(2))
+			Expect(managedFields[1].Manager).To(Equal(LMSMoodleMetadataFieldManager))
+			Expect(managedFields[1].Operation).To(Equal(metav1.ManagedFieldsOperationApply))
+			Expect(managedFields[1].APIVersion).To(Equal("v1"))
+
+			managedFields, moved, err = moveLabelsOwnership([]metav1.ManagedFieldsEntry{
+				managedFieldsEntry(LegacyFieldManager, metav1.ManagedFieldsOperationUpdate, `{"f:metadata":{"f:labels":{"f:stale":{}}}}`),
+			}, OPERATORNAME)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(moved).To(BeTrue())
+			Expect(managedFields).To(HaveLen(1))
+			Expect(managedFields[0].Manager).To(Equal(OPERATORNAME))
+		})
+
+		It("should not move anything once moved", func() {
+			managedFields, _, err := moveLabelsOwnership(legacyManagedFields(), OPERATORNAME)
+			Expect(err).NotTo(HaveOccurred())
+			_, moved, err := moveLabelsOwnership(managedFields, OPERATORNAME)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(moved).To(BeFalse())
+		})
+
+		It("should patch managed fields of the namespace", func() {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:          "lms-site",
+				Labels:        map[string]string{"stale": "true", "team": "education", "manual": "true"},
+				ManagedFields: legacyManagedFields(),
+			}}
+			c := fake.NewClientBuilder().WithScheme(newDependantsScheme()).WithObjects(namespace).Build()
+			r.Client = c
+			Expect(c.Get(ctx, types.NamespacedName{Name: "lms-site"}, namespace)).To(Succeed())
+
+			moved, err := r.ReconcileLegacyLabels(ctx, namespace, OPERATORNAME)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(moved).To(BeTrue())
+
+			liveNamespace := &corev1.Namespace{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: "lms-site"}, liveNamespace)).To(Succeed())
+			_, moved, err = moveLabelsOwnership(liveNamespace.GetManagedFields(), OPERATORNAME)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(moved).To(BeFalse())
+		})
+	})
+
+	Context("When reading a target namespace", func() {
+		readTargetNamespace := func(namespace *corev1.Namespace) error {
+			r.Client = fake.NewClientBuilder().WithObjects(namespace).Build()
+			r.Recorder = &record.FakeRecorder{}
+			r.lmsMoodleCtx.targetNamespace = namespace.Name
+			r.lmsMoodleCtx.namespaceName = namespace.Name
+			return r.reconcileTargetNamespace(ctx)
+		}
+
+		It("should allow only target namespaces of namespace policy", func() {
+			Expect(readTargetNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared"}})).To(MatchError(&TargetNamespaceNotAllowedError{"shared"}))
+
+			r.NamespacePolicy.TargetNamespaces = []string{"shared"}
+			Expect(readTargetNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared"}})).To(Succeed())
+		})
+
+		It("should allow namespaces selected by namespace policy", func() {
+			selector, err := labels.Parse("lms.krestomat.io/target=true")
+			Expect(err).NotTo(HaveOccurred())
+			r.NamespacePolicy.TargetNamespaceSelector = selector
+
+			Expect(readTargetNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared"}})).NotTo(Succeed())
+			Expect(readTargetNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name: "shared", Labels: map[string]string{"lms.krestomat.io/target": "true"},
+			}})).To(Succeed())
+		})
+
+		It("should never allow system namespaces nor the operator namespace", func() {
+			r.NamespacePolicy = NamespacePolicy{
+				TargetNamespaces:        []string{"default", "kube-system", "lms-moodle-operator-system"},
+				TargetNamespaceSelector: labels.Everything(),
+				OperatorNamespace:       "lms-moodle-operator-system",
+			}
+			for _, name := range r.NamespacePolicy.TargetNamespaces {
+				Expect(readTargetNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(MatchError(&TargetNamespaceNotAllowedError{name}))
+			}
+		})
+
+		It("should fail if the target namespace does not exist", func() {
+			r.Client = fake.NewClientBuilder().Build()
+			r.Recorder = &record.FakeRecorder{}
+			r.lmsMoodleCtx.namespaceName = "missing"
+			Expect(r.reconcileTargetNamespace(ctx)).To(MatchError(&TargetNamespaceNotFoundError{"missing"}))
+		})
+	})
+})
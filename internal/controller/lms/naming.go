@@ -0,0 +1,212 @@
+package lms
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// NameHashSuffixLength characters of the hash suffix of truncated names
+	NameHashSuffixLength int = 5
+)
+
+// NamingPolicy defines how names of namespace and dependant resources are derived
+// from LMSMoodle name. Names already in use by a LMSMoodle are kept in its status,
+// so changing the policy only affects new LMSMoodles
+type NamingPolicy struct {
+	// Prefix of namespace and dependant names, unless LMSMoodle name already has it
+	Prefix string
+	// MaxLength of dependant names. Namespace name is not truncated
+	MaxLength int
+	// HashSuffix whether truncated names end with a hash of the untruncated one, so that
+	// LMSMoodle names sharing a long prefix do not collide
+	HashSuffix bool
+}
+
+// LegacyNamingPolicy derives names as before naming policies were configurable
+var LegacyNamingPolicy = NamingPolicy{Prefix: LMSMoodleNamePrefix, MaxLength: TruncateCharactersInName}
+
+// DefaultNamingPolicy derives names as LegacyNamingPolicy. Hash suffix in truncated names
+// is opt-in, so that names derived by default do not change
+var DefaultNamingPolicy = NamingPolicy{Prefix: LMSMoodleNamePrefix, MaxLength: TruncateCharactersInName}
+
+// NameCollisionError is returned when dependant names derived for a LMSMoodle
+// are already in use by resources of another owner
+type NameCollisionError struct {
+	Kind string // resource kind
+	Name string // resource name
+}
+
+func (e *NameCollisionError) Error() string {
+	return fmt.Sprintf("%s '%s' already exists and belongs to another owner. Change LMSMoodle name or operator naming policy", e.Kind, e.Name)
+}
+
+// Names returns namespace and base name of dependant resources of a LMSMoodle
+func (p NamingPolicy) Names(lmsMoodleName string) (namespaceName string, baseName string) {
+	namespaceName = lmsMoodleName
+	// if lmsMoodle name already include the prefix, do not use it
+	if !strings.HasPrefix(lmsMoodleName, p.Prefix) {
+		namespaceName = p.Prefix + lmsMoodleName
+	}
+	baseName = namespaceName
+	if p.MaxLength <= 0 || len(baseName) <= p.MaxLength {
+		return namespaceName, baseName
+	}
+	if !p.HashSuffix || p.MaxLength <= NameHashSuffixLength+1 {
+		return namespaceName, truncate(baseName, p.MaxLength)
+	}
+
+	// keep names starting and ending with an alphanumeric character
+	hash := sha256.Sum256([]byte(namespaceName))
+	suffix := hex.EncodeToString(hash[:])[:NameHashSuffixLength]
+	truncated := strings.TrimRight(truncate(baseName, p.MaxLength-NameHashSuffixLength-1), "-")
+	return namespaceName, truncated + "-" + suffix
+}
+
+// namingPolicy returns reconciler naming policy, the default one if not set
+func (r *LMSMoodleReconciler) namingPolicy() NamingPolicy {
+	if r.NamingPolicy == (NamingPolicy{}) {
+		return DefaultNamingPolicy
+	}
+	return r.NamingPolicy
+}
+
+// statusNames returns namespace and base name of dependant resources kept in
+// LMSMoodle status, and whether both are set
+func (r *LMSMoodleReconciler) statusNames() (namespaceName string, baseName string, found bool) {
+	namespaceName, _, _ = unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "names", "namespace")
+	baseName, _, _ = unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "names", "base")
+	return namespaceName, baseName, namespaceName != "" && baseName != ""
+}
+
+// resolveDependantNames sets names of namespace and dependant resources. Names kept in
+// LMSMoodle status are used once set. Otherwise, LMSMoodles with namespace or any dependant
+// already present by legacy names keep them, and new ones get names from naming policy,
+// failing if these collide with resources of another owner. Dependants of LMSMoodles with
+// a target namespace are deployed there. Should be used once lmsMoodle is set
+func (r *LMSMoodleReconciler) resolveDependantNames(ctx context.Context) error {
+	log := log.FromContext(ctx)
+
+	if namespaceName, baseName, found := r.statusNames(); found {
+		r.setDependantNames(namespaceName, baseName)
+		return nil
+	}
+
+	// existing namespace, possibly shared with other LMSMoodles
+	if r.lmsMoodleCtx.targetNamespace != "" {
+		_, baseName := r.namingPolicy().Names(r.lmsMoodleCtx.name)
+		if err := r.checkNameCollision(ctx, r.lmsMoodleCtx.targetNamespace, baseName); err != nil {
+			return err
+		}
+		r.setDependantNames(r.lmsMoodleCtx.targetNamespace, baseName)
+		return nil
+	}
+
+	// existing LMSMoodle, named before naming policies were configurable
+	namespaceName, baseName := r.namingPolicy().Names(r.lmsMoodleCtx.name)
+	legacyNamespaceName, legacyBaseName := LegacyNamingPolicy.Names(r.lmsMoodleCtx.name)
+	if legacyNamespaceName != namespaceName || legacyBaseName != baseName {
+		if legacy, err := r.hasLegacyDependants(ctx, legacyNamespaceName, legacyBaseName); err != nil {
+			return err
+		} else if legacy {
+			log.V(1).Info("Keeping legacy dependant names", "Namespace", legacyNamespaceName, "Name", legacyBaseName)
+			r.setDependantNames(legacyNamespaceName, legacyBaseName)
+			return nil
+		}
+	}
+
+	if err := r.checkNameCollision(ctx, namespaceName, baseName); err != nil {
+		return err
+	}
+	r.setDependantNames(namespaceName, baseName)
+	return nil
+}
+
+// hasLegacyDependants returns whether namespace or any dependant by legacy names is
+// controlled by the LMSMoodle, even if others are not present, such as suspended ones
+func (r *LMSMoodleReconciler) hasLegacyDependants(ctx context.Context, namespaceName string, baseName string) (bool, error) {
+	// namespaces are not cached
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	namespace := &corev1.Namespace{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: namespaceName}, namespace); client.IgnoreNotFound(err) != nil {
+		return false, err
+	} else if err == nil && metav1.IsControlledBy(namespace, r.lmsMoodleCtx.lmsMoodle) {
+		return true, nil
+	}
+
+	for _, gvk := range []schema.GroupVersionKind{r.MoodleGVK, r.PostgresGVK, r.KeydbGVK, r.NfsGVK} {
+		dependant := newUnstructuredObject(gvk)
+		if err := r.Get(ctx, types.NamespacedName{Name: baseName, Namespace: namespaceName}, dependant); client.IgnoreNotFound(err) != nil {
+			return false, err
+		} else if err == nil && metav1.IsControlledBy(dependant, r.lmsMoodleCtx.lmsMoodle) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkNameCollision returns a NameCollisionError if namespace or Moodle by names derived
+// from naming policy exist and are not controlled by the LMSMoodle, such as namespaces
+// created by others. A target namespace is not owned by any LMSMoodle, so only Moodle is
+// checked. LMSMoodles being deleted are not checked, since only resources they own are deleted
+func (r *LMSMoodleReconciler) checkNameCollision(ctx context.Context, namespaceName string, baseName string) error {
+	if r.lmsMoodleCtx.markedToBeDeleted {
+		return nil
+	}
+
+	if r.lmsMoodleCtx.targetNamespace == "" {
+		// namespaces are not cached
+		var reader client.Reader = r.Client
+		if r.APIReader != nil {
+			reader = r.APIReader
+		}
+		namespace := &corev1.Namespace{}
+		if err := reader.Get(ctx, types.NamespacedName{Name: namespaceName}, namespace); client.IgnoreNotFound(err) != nil {
+			return err
+		} else if err == nil && !metav1.IsControlledBy(namespace, r.lmsMoodleCtx.lmsMoodle) {
+			return &NameCollisionError{"Namespace", namespaceName}
+		}
+	}
+
+	moodle := newUnstructuredObject(r.MoodleGVK)
+	if err := r.Get(ctx, types.NamespacedName{Name: baseName, Namespace: namespaceName}, moodle); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err == nil && !metav1.IsControlledBy(moodle, r.lmsMoodleCtx.lmsMoodle) {
+		return &NameCollisionError{moodle.GetKind(), baseName}
+	}
+
+	return nil
+}
+
+// SetStatusNames set names of namespace and dependant resources in lms moodle status,
+// keeping them even if naming policy changes
+// It returns a bool flag if names were updated, and any error
+func (r *LMSMoodleReconciler) SetStatusNames() (bool, error) {
+	names := map[string]interface{}{
+		"namespace": r.lmsMoodleCtx.namespaceName,
+		"base":      r.lmsMoodleCtx.moodleName,
+	}
+	// namespace quota and limit range, once applied, to delete them only then if omitted
+	if r.lmsMoodleCtx.namespaceQuotaApplied {
+		names["resourceQuota"] = r.lmsMoodleCtx.namespaceQuota.GetName()
+	}
+	if r.lmsMoodleCtx.namespaceLimitRangeApplied {
+		names["limitRange"] = r.lmsMoodleCtx.namespaceLimitRange.GetName()
+	}
+	return setNestedFieldIfChanged(r.lmsMoodleCtx.lmsMoodle, names, "status", "names")
+}
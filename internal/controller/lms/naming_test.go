@@ -0,0 +1,163 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Naming policy", func() {
+	Context("When deriving names", func() {
+		It("should add the prefix unless the name already has it", func() {
+			namespaceName, baseName := DefaultNamingPolicy.Names("site")
+			Expect(namespaceName).To(Equal("lms-site"))
+			Expect(baseName).To(Equal("lms-site"))
+
+			namespaceName, baseName = DefaultNamingPolicy.Names("lms-site")
+			Expect(namespaceName).To(Equal("lms-site"))
+			Expect(baseName).To(Equal("lms-site"))
+		})
+
+		It("should truncate base names only, as legacy names by default", func() {
+			Expect(DefaultNamingPolicy).To(Equal(LegacyNamingPolicy))
+
+			namespaceName, baseName := DefaultNamingPolicy.Names("university-of-somewhere")
+			Expect(namespaceName).To(Equal("lms-university-of-somewhere"))
+			Expect(baseName).To(Equal("lms-university-of"))
+
+			_, baseName = NamingPolicy{Prefix: "acme-"}.Names("university-of-somewhere")
+			Expect(baseName).To(Equal("acme-university-of-somewhere"))
+		})
+
+		It("should end truncated names with a hash suffix, if set", func() {
+			policy := NamingPolicy{Prefix: LMSMoodleNamePrefix, MaxLength: TruncateCharactersInName, HashSuffix: true}
+
+			_, baseName := policy.Names("university-of-somewhere")
+			_, otherBaseName := policy.Names("university-of-elsewhere")
+			Expect(baseName).To(HaveLen(TruncateCharactersInName))
+			Expect(baseName).To(HavePrefix("lms-univers-"))
+			Expect(otherBaseName).To(HavePrefix("lms-univers-"))
+			Expect(baseName).NotTo(Equal(otherBaseName))
+
+			// names not truncated have no suffix, and truncated ones do not end with a dash
+			_, baseName = policy.Names("site")
+			Expect(baseName).To(Equal("lms-site"))
+			_, baseName = policy.Names("abcdef-hijklmnopq")
+			Expect(baseName).To(HavePrefix("lms-abcdef-"))
+			Expect(baseName).NotTo(ContainSubstring("--"))
+		})
+	})
+
+	Context("When resolving dependant names", func() {
+		var r *LMSMoodleReconciler
+
+		newReconciler := func(status map[string]interface{}, objs ...client.Object) {
+			scheme := newDependantsScheme()
+			r = &LMSMoodleReconciler{
+				Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+				Scheme:       scheme,
+				Recorder:     &record.FakeRecorder{},
+				MoodleGVK:    MoodleGVK,
+				NfsGVK:       NfsGVK,
+				KeydbGVK:     KeydbGVK,
+				PostgresGVK:  PostgresGVK,
+				NamingPolicy: NamingPolicy{Prefix: "acme-"},
+			}
+			r.lmsMoodleCtx.name = "site"
+			r.lmsMoodleCtx.lmsMoodle = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+			r.lmsMoodleCtx.lmsMoodle.SetName("site")
+			r.lmsMoodleCtx.lmsMoodle.SetUID("site-uid")
+			if status != nil {
+				r.lmsMoodleCtx.lmsMoodle.Object["status"] = status
+			}
+		}
+
+		controllerReference := func() []metav1.OwnerReference {
+			controller := true
+			return []metav1.OwnerReference{{
+				APIVersion: lmsv1alpha1.GroupVersion.String(),
+				Kind:       "LMSMoodle",
+				Name:       "site",
+				UID:        "site-uid",
+				Controller: &controller,
+			}}
+		}
+
+		legacyPostgres := func() *unstructured.Unstructured {
+			postgres := newUnstructuredObject(PostgresGVK)
+			postgres.SetName("lms-site")
+			postgres.SetNamespace("lms-site")
+			postgres.SetOwnerReferences(controllerReference())
+			return postgres
+		}
+
+		It("should use names kept in status", func() {
+			newReconciler(map[string]interface{}{"names": map[string]interface{}{"namespace": "kept", "base": "kept-site"}})
+			Expect(r.resolveDependantNames(ctx)).To(Succeed())
+			Expect(r.lmsMoodleCtx.namespaceName).To(Equal("kept"))
+			Expect(r.lmsMoodleCtx.moodleName).To(Equal("kept-site"))
+		})
+
+		It("should derive names from naming policy for new LMSMoodles", func() {
+			newReconciler(nil)
+			Expect(r.resolveDependantNames(ctx)).To(Succeed())
+			Expect(r.lmsMoodleCtx.namespaceName).To(Equal("acme-site"))
+			Expect(r.lmsMoodleCtx.moodleName).To(Equal("acme-site"))
+		})
+
+		It("should keep legacy names if any dependant is present by them, without Moodle", func() {
+			newReconciler(nil, legacyPostgres())
+			Expect(r.resolveDependantNames(ctx)).To(Succeed())
+			Expect(r.lmsMoodleCtx.namespaceName).To(Equal("lms-site"))
+			Expect(r.lmsMoodleCtx.postgresName).To(Equal("lms-site"))
+		})
+
+		It("should keep legacy names if the namespace is present by them", func() {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "lms-site", OwnerReferences: controllerReference()}}
+			newReconciler(nil, namespace)
+			Expect(r.resolveDependantNames(ctx)).To(Succeed())
+			Expect(r.lmsMoodleCtx.namespaceName).To(Equal("lms-site"))
+		})
+
+		It("should not keep legacy names of resources of another owner", func() {
+			postgres := legacyPostgres()
+			postgres.SetOwnerReferences(nil)
+			newReconciler(nil, postgres)
+			Expect(r.resolveDependantNames(ctx)).To(Succeed())
+			Expect(r.lmsMoodleCtx.namespaceName).To(Equal("acme-site"))
+		})
+
+		It("should fail if a namespace by policy names exists, unless controlled by the LMSMoodle", func() {
+			newReconciler(nil, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme-site"}})
+			Expect(r.resolveDependantNames(ctx)).To(MatchError(&NameCollisionError{"Namespace", "acme-site"}))
+
+			newReconciler(nil, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme-site", OwnerReferences: controllerReference()}})
+			Expect(r.resolveDependantNames(ctx)).To(Succeed())
+			Expect(r.lmsMoodleCtx.namespaceName).To(Equal("acme-site"))
+		})
+	})
+})
@@ -0,0 +1,182 @@
+package lms
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+const (
+	// DNSNamespaceName namespace allowed for DNS egress traffic
+	DNSNamespaceName string = "kube-system"
+	// DNSPort port allowed for DNS egress traffic
+	DNSPort int32 = 53
+)
+
+// getNamespaceNetworkPolicy returns LMSMoodleTemplate namespace network policy,
+// overridden by LMSMoodle one, field by field. Extra egress rules are overridden by name
+// and their CIDRs validated
+func (r *LMSMoodleReconciler) getNamespaceNetworkPolicy() (lmsv1alpha1.NamespaceNetworkPolicy, error) {
+	namespaceNetworkPolicy := lmsv1alpha1.NamespaceNetworkPolicy{}
+	extraEgressIndex := map[string]int{}
+
+	for _, spec := range []map[string]interface{}{r.lmsMoodleCtx.lmsMoodleTemplateSpec, r.lmsMoodleCtx.spec} {
+		specNamespaceNetworkPolicyU, found, _ := unstructured.NestedMap(spec, "namespaceNetworkPolicy")
+		if !found {
+			continue
+		}
+		specNamespaceNetworkPolicy := lmsv1alpha1.NamespaceNetworkPolicy{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specNamespaceNetworkPolicyU, &specNamespaceNetworkPolicy); err != nil {
+			return namespaceNetworkPolicy, err
+		}
+		if specNamespaceNetworkPolicy.AllowSameNamespace != nil {
+			namespaceNetworkPolicy.AllowSameNamespace = specNamespaceNetworkPolicy.AllowSameNamespace
+		}
+		if specNamespaceNetworkPolicy.AllowDNS != nil {
+			namespaceNetworkPolicy.AllowDNS = specNamespaceNetworkPolicy.AllowDNS
+		}
+		if specNamespaceNetworkPolicy.IngressController != nil {
+			namespaceNetworkPolicy.IngressController = specNamespaceNetworkPolicy.IngressController
+		}
+		if specNamespaceNetworkPolicy.Monitoring != nil {
+			namespaceNetworkPolicy.Monitoring = specNamespaceNetworkPolicy.Monitoring
+		}
+		for _, extraEgress := range specNamespaceNetworkPolicy.ExtraEgress {
+			if _, _, err := net.ParseCIDR(extraEgress.CIDR); err != nil {
+				return namespaceNetworkPolicy, fmt.Errorf("invalid namespaceNetworkPolicy extraEgress %q cidr %q", extraEgress.Name, extraEgress.CIDR)
+			}
+			if i, found := extraEgressIndex[extraEgress.Name]; found {
+				namespaceNetworkPolicy.ExtraEgress[i] = extraEgress
+				continue
+			}
+			extraEgressIndex[extraEgress.Name] = len(namespaceNetworkPolicy.ExtraEgress)
+			namespaceNetworkPolicy.ExtraEgress = append(namespaceNetworkPolicy.ExtraEgress, extraEgress)
+		}
+	}
+
+	return namespaceNetworkPolicy, nil
+}
+
+// namespaceNetpolIngressRules returns ingress rules allowed by namespace network policy
+func namespaceNetpolIngressRules(namespaceNetworkPolicy lmsv1alpha1.NamespaceNetworkPolicy) []networkingv1.NetworkPolicyIngressRule {
+	var rules []networkingv1.NetworkPolicyIngressRule
+	if namespaceNetworkPolicy.AllowSameNamespace != nil && *namespaceNetworkPolicy.AllowSameNamespace {
+		rules = append(rules, networkingv1.NetworkPolicyIngressRule{
+			From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+		})
+	}
+	for _, ingress := range []*lmsv1alpha1.NamespaceNetworkPolicyIngress{namespaceNetworkPolicy.IngressController, namespaceNetworkPolicy.Monitoring} {
+		if ingress == nil {
+			continue
+		}
+		peer := networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: ingress.NamespaceLabels}}
+		if len(ingress.PodLabels) > 0 {
+			peer.PodSelector = &metav1.LabelSelector{MatchLabels: ingress.PodLabels}
+		}
+		rules = append(rules, networkingv1.NetworkPolicyIngressRule{
+			From:  []networkingv1.NetworkPolicyPeer{peer},
+			Ports: netpolPorts(ingress.Ports),
+		})
+	}
+	return rules
+}
+
+// namespaceNetpolEgressRules returns egress rules allowed by namespace network policy
+func namespaceNetpolEgressRules(namespaceNetworkPolicy lmsv1alpha1.NamespaceNetworkPolicy) []networkingv1.NetworkPolicyEgressRule {
+	var rules []networkingv1.NetworkPolicyEgressRule
+	if namespaceNetworkPolicy.AllowSameNamespace != nil && *namespaceNetworkPolicy.AllowSameNamespace {
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+		})
+	}
+	if namespaceNetworkPolicy.AllowDNS != nil && *namespaceNetworkPolicy.AllowDNS {
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: DNSNamespaceName}},
+			}},
+			Ports: netpolPorts([]lmsv1alpha1.NetworkPolicyExtraPort{
+				{Port: DNSPort, Protocol: string(corev1.ProtocolUDP)},
+				{Port: DNSPort, Protocol: string(corev1.ProtocolTCP)},
+			}),
+		})
+	}
+	for _, extraEgress := range namespaceNetworkPolicy.ExtraEgress {
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To:    []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: extraEgress.CIDR}}},
+			Ports: netpolPorts(extraEgress.Ports),
+		})
+	}
+	return rules
+}
+
+// netpolPorts returns network policy ports, TCP by default
+func netpolPorts(extraPorts []lmsv1alpha1.NetworkPolicyExtraPort) []networkingv1.NetworkPolicyPort {
+	var ports []networkingv1.NetworkPolicyPort
+	for _, extraPort := range extraPorts {
+		protocol := corev1.ProtocolTCP
+		if extraPort.Protocol != "" {
+			protocol = corev1.Protocol(extraPort.Protocol)
+		}
+		port := intstr.FromInt32(extraPort.Port)
+		ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port})
+	}
+	return ports
+}
+
+// serializeNetpolPeers serializes typed ingress and egress peers of component default network
+// policies into the ip block fields the dependant expects, as their CIDR. Dependants support a
+// single CIDR. Components are field prefixes, such as nginx or phpFpm. Typed peers replace any
+// ip block already set and are removed from spec, since dependants do not know them
+func serializeNetpolPeers(objSpec map[string]interface{}, components ...string) error {
+	for _, component := range components {
+		for _, direction := range []string{"Ingress", "Egress"} {
+			peersField := component + "Netpol" + direction + "Peers"
+			specPeers, found, err := unstructured.NestedSlice(objSpec, peersField)
+			if err != nil {
+				return err
+			}
+			if !found {
+				continue
+			}
+			delete(objSpec, peersField)
+			if len(specPeers) == 0 {
+				continue
+			}
+
+			peersJson, err := json.Marshal(specPeers)
+			if err != nil {
+				return err
+			}
+			var peers []lmsv1alpha1.NetworkPolicyPeer
+			if err := json.Unmarshal(peersJson, &peers); err != nil {
+				return fmt.Errorf("invalid %s: %w", peersField, err)
+			}
+			cidr, err := netpolPeersCIDR(peers)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", peersField, err)
+			}
+			objSpec[component+"Netpol"+direction+"Ipblock"] = cidr
+		}
+	}
+	return nil
+}
+
+// netpolPeersCIDR validates typed peers and returns the CIDR of their ip block, IPv4 or IPv6
+func netpolPeersCIDR(peers []lmsv1alpha1.NetworkPolicyPeer) (string, error) {
+	if len(peers) != 1 {
+		return "", fmt.Errorf("a single peer is supported, found %d", len(peers))
+	}
+	if _, _, err := net.ParseCIDR(peers[0].IPBlock.CIDR); err != nil {
+		return "", fmt.Errorf("invalid ipBlock cidr %q", peers[0].IPBlock.CIDR)
+	}
+	return peers[0].IPBlock.CIDR, nil
+}
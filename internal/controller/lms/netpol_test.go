@@ -0,0 +1,98 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Namespace network policy", func() {
+	var r *LMSMoodleReconciler
+
+	extraEgress := func(rules ...map[string]interface{}) map[string]interface{} {
+		var extraEgress []interface{}
+		for _, rule := range rules {
+			extraEgress = append(extraEgress, rule)
+		}
+		return map[string]interface{}{"namespaceNetworkPolicy": map[string]interface{}{"extraEgress": extraEgress}}
+	}
+
+	BeforeEach(func() {
+		r = &LMSMoodleReconciler{}
+		r.lmsMoodleCtx.lmsMoodleTemplateSpec = extraEgress(
+			map[string]interface{}{"name": "smtp", "cidr": "203.0.113.10/32", "ports": []interface{}{map[string]interface{}{"port": int64(587)}}},
+			map[string]interface{}{"name": "api", "cidr": "198.51.100.0/24"},
+		)
+		r.lmsMoodleCtx.spec = map[string]interface{}{}
+	})
+
+	It("should override template extra egress rules by name", func() {
+		r.lmsMoodleCtx.spec = extraEgress(map[string]interface{}{"name": "api", "cidr": "2001:db8::/32"})
+
+		namespaceNetworkPolicy, err := r.getNamespaceNetworkPolicy()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(namespaceNetworkPolicy.ExtraEgress).To(HaveLen(2))
+		Expect(namespaceNetworkPolicy.ExtraEgress[1].CIDR).To(Equal("2001:db8::/32"))
+
+		rules := namespaceNetpolEgressRules(namespaceNetworkPolicy)
+		Expect(rules).To(HaveLen(2))
+		Expect(rules[0].To[0].IPBlock.CIDR).To(Equal("203.0.113.10/32"))
+		Expect(rules[0].Ports[0].Port.IntValue()).To(Equal(587))
+	})
+
+	It("should reject extra egress rules with an invalid CIDR", func() {
+		for _, cidr := range []string{"smtp.example.com", "203.0.113.10", "203.0.113.0/33"} {
+			r.lmsMoodleCtx.spec = extraEgress(map[string]interface{}{"name": "smtp", "cidr": cidr})
+
+			_, err := r.getNamespaceNetworkPolicy()
+			Expect(err).To(MatchError(ContainSubstring(`extraEgress "smtp" cidr`)), cidr)
+		}
+	})
+
+	Context("When serializing typed peers of component network policies", func() {
+		peers := func(cidrs ...string) []interface{} {
+			var peers []interface{}
+			for _, cidr := range cidrs {
+				peers = append(peers, map[string]interface{}{"ipBlock": map[string]interface{}{"cidr": cidr}})
+			}
+			return peers
+		}
+
+		It("should set ip block fields as the peer CIDR, removing peer fields", func() {
+			moodleSpec := map[string]interface{}{
+				"nginxNetpolIngressIpblock": "10.0.0.0/8",
+				"nginxNetpolIngressPeers":   peers("2001:db8::/32"),
+				"phpFpmNetpolEgressPeers":   peers("203.0.113.0/24"),
+				"moodleNetpolIngressPeers":  []interface{}{},
+			}
+
+			Expect(serializeNetpolPeers(moodleSpec, "moodle", "nginx", "phpFpm")).To(Succeed())
+			Expect(moodleSpec).To(Equal(map[string]interface{}{
+				"nginxNetpolIngressIpblock": "2001:db8::/32",
+				"phpFpmNetpolEgressIpblock": "203.0.113.0/24",
+			}))
+		})
+
+		It("should reject invalid CIDRs and several peers", func() {
+			Expect(serializeNetpolPeers(map[string]interface{}{"keydbNetpolIngressPeers": peers("10.0.0.0/33")}, "keydb")).
+				To(MatchError(ContainSubstring(`keydbNetpolIngressPeers: invalid ipBlock cidr "10.0.0.0/33"`)))
+			Expect(serializeNetpolPeers(map[string]interface{}{"keydbNetpolEgressPeers": peers("10.0.0.0/8", "10.1.0.0/16")}, "keydb")).
+				To(MatchError(ContainSubstring("a single peer is supported")))
+		})
+	})
+})
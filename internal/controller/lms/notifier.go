@@ -0,0 +1,73 @@
+package lms
+
+import (
+	"context"
+	"time"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	"github.com/krestomatio/lms-moodle-operator/internal/notifier"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// notifyStateChange queues an event about a LMSMoodle state change for its notifier endpoints,
+// before the change is saved. If saving fails, the change is notified again, as another event
+func (r *LMSMoodleReconciler) notifyStateChange(ctx context.Context, previousState string, state string, transitionTime time.Time) error {
+	log := log.FromContext(ctx)
+
+	if r.Notifier == nil {
+		return nil
+	}
+
+	endpoints, err := r.getNotifierEndpoints()
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+
+	status, _, _ := unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodle.Object, "status")
+	data := map[string]interface{}{
+		"name":          r.lmsMoodleCtx.name,
+		"template":      r.lmsMoodleCtx.lmsMoodleTemplateName,
+		"state":         state,
+		"previousState": previousState,
+	}
+	for _, key := range []string{"url", "release", "registeredUsers", "storageGb"} {
+		if value, found := status[key]; found {
+			data[key] = value
+		}
+	}
+
+	log.V(1).Info("Notifying state change", "State", state)
+	event := notifier.NewEvent(r.lmsMoodleCtx.lmsMoodle, previousState, state, transitionTime, data)
+	return r.Notifier.Notify(ctx, r.lmsMoodleCtx.name, state, endpoints, event)
+}
+
+// getNotifierEndpoints returns template notifier endpoints, overridden by
+// LMSMoodle endpoints with the same name
+func (r *LMSMoodleReconciler) getNotifierEndpoints() ([]lmsv1alpha1.NotifierEndpoint, error) {
+	var endpoints []lmsv1alpha1.NotifierEndpoint
+	index := map[string]int{}
+
+	for _, spec := range []map[string]interface{}{r.lmsMoodleCtx.lmsMoodleTemplateSpec, r.lmsMoodleCtx.spec} {
+		endpointsU, _, _ := unstructured.NestedSlice(spec, "notifier", "endpoints")
+		for _, endpointU := range endpointsU {
+			endpointMap, ok := endpointU.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			endpoint := lmsv1alpha1.NotifierEndpoint{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(endpointMap, &endpoint); err != nil {
+				return nil, err
+			}
+			if i, found := index[endpoint.Name]; found {
+				endpoints[i] = endpoint
+				continue
+			}
+			index[endpoint.Name] = len(endpoints)
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	return endpoints, nil
+}
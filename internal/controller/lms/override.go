@@ -0,0 +1,310 @@
+package lms
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+const (
+	// OverridesMessageMaxFields maximum number of overrides listed in OverridesAllowed condition message
+	OverridesMessageMaxFields int = 10
+	// OverridesRejectedReason failed reason of LMSMoodles rejected by template override policy
+	OverridesRejectedReason string = "OverridesRejected"
+)
+
+// overrideLifecycleFields are LMSMoodle spec fields always allowed, since they are not template ones
+var overrideLifecycleFields = map[string]bool{
+	"lmsMoodleTemplateName": true,
+	"desiredState":          true,
+	"paused":                true,
+	"componentStates":       true,
+}
+
+// OverridesRejectedError is returned when LMSMoodle sets overrides not allowed by a
+// template override policy rejecting them
+type OverridesRejectedError struct {
+	Message string // overrides not allowed
+}
+
+func (e *OverridesRejectedError) Error() string {
+	return e.Message
+}
+
+// notAllowedOverride is a LMSMoodle spec field not allowed to override template one
+type notAllowedOverride struct {
+	path   []string
+	reason string
+}
+
+// applyOverridePolicy removes LMSMoodle spec fields not allowed by template override policy,
+// so that they do not override template ones, and sets LMSMoodle as failed if the policy
+// rejects them. Should be used once lmsMoodle and lmsMoodleTemplate specs are set
+func (r *LMSMoodleReconciler) applyOverridePolicy() error {
+	r.lmsMoodleCtx.overridesRestricted = false
+	r.lmsMoodleCtx.overridesRejected = false
+	r.lmsMoodleCtx.notAllowedOverrides = nil
+
+	overridePolicyU, _, _ := unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodleTemplateSpec, "overridePolicy")
+	overridePolicy := lmsv1alpha1.OverridePolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(overridePolicyU, &overridePolicy); err != nil {
+		return err
+	}
+	if !overridePolicy.Restricted {
+		return nil
+	}
+	r.lmsMoodleCtx.overridesRestricted = true
+
+	allowedOverrides := append(overridePolicy.Allowed, r.claimAllowedOverrides()...)
+	notAllowedOverrides := getNotAllowedOverrides(r.lmsMoodleCtx.spec, allowedOverrides)
+	if len(notAllowedOverrides) == 0 {
+		return nil
+	}
+	r.lmsMoodleCtx.notAllowedOverrides = notAllowedOverrides
+
+	// remove them from spec, along with fields left empty
+	for _, override := range notAllowedOverrides {
+		unstructured.RemoveNestedField(r.lmsMoodleCtx.spec, override.path...)
+		for i := len(override.path) - 1; i > 0; i-- {
+			if parent, found, _ := unstructured.NestedMap(r.lmsMoodleCtx.spec, override.path[:i]...); !found || len(parent) > 0 {
+				break
+			}
+			unstructured.RemoveNestedField(r.lmsMoodleCtx.spec, override.path[:i]...)
+		}
+	}
+	if unsetFields, found, _ := unstructured.NestedStringSlice(r.lmsMoodleCtx.spec, "unsetFields"); found {
+		var allowedUnsetFields []string
+		for _, unsetField := range unsetFields {
+			if unsetFieldAllowed(allowedOverrides, unsetField) {
+				allowedUnsetFields = append(allowedUnsetFields, unsetField)
+			}
+		}
+		if len(allowedUnsetFields) == 0 {
+			unstructured.RemoveNestedField(r.lmsMoodleCtx.spec, "unsetFields")
+		} else if err := unstructured.SetNestedStringSlice(r.lmsMoodleCtx.spec, allowedUnsetFields, "unsetFields"); err != nil {
+			return err
+		}
+	}
+	r.setLMSMoodleSpecFields()
+
+	if overridePolicy.Action == lmsv1alpha1.RejectOverrideAction {
+		r.lmsMoodleCtx.overridesRejected = true
+		r.lmsMoodleCtx.failedReason = OverridesRejectedReason
+		r.lmsMoodleCtx.failedMessage = notAllowedOverridesMessage(notAllowedOverrides, true)
+	}
+
+	return nil
+}
+
+// claimAllowedOverrides returns fields set by the LMSMoodleClaim a LMSMoodle is bound to,
+// as allowed by template claims
+func (r *LMSMoodleReconciler) claimAllowedOverrides() []lmsv1alpha1.AllowedOverride {
+	if r.lmsMoodleCtx.lmsMoodle.GetAnnotations()[lmsv1alpha1.ClaimUIDAnnotation] == "" {
+		return nil
+	}
+	if claimsAllowed, _, _ := unstructured.NestedBool(r.lmsMoodleCtx.lmsMoodleTemplateSpec, "claims", "allowed"); !claimsAllowed {
+		return nil
+	}
+
+	var allowedOverrides []lmsv1alpha1.AllowedOverride
+	for _, path := range claimRequiredPaths {
+		allowedOverrides = append(allowedOverrides, lmsv1alpha1.AllowedOverride{Path: strings.Join(path, ".")})
+	}
+	claimOverrides, _, _ := unstructured.NestedStringSlice(r.lmsMoodleCtx.lmsMoodleTemplateSpec, "claims", "allowedOverrides")
+	for _, claimOverride := range claimOverrides {
+		if path, found := claimOverridePaths[lmsv1alpha1.LMSMoodleClaimOverride(claimOverride)]; found {
+			allowedOverrides = append(allowedOverrides, lmsv1alpha1.AllowedOverride{Path: strings.Join(path, ".")})
+		}
+	}
+	return allowedOverrides
+}
+
+// getNotAllowedOverrides returns spec fields not allowed by allowed overrides, sorted by path.
+// Maps without any field allowed are returned as a whole
+func getNotAllowedOverrides(spec map[string]interface{}, allowedOverrides []lmsv1alpha1.AllowedOverride) []notAllowedOverride {
+	var notAllowedOverrides []notAllowedOverride
+
+	var walk func(fields map[string]interface{}, parentPath []string)
+	walk = func(fields map[string]interface{}, parentPath []string) {
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if len(parentPath) == 0 && (overrideLifecycleFields[key] || key == "unsetFields") {
+				continue
+			}
+			path := append(append([]string{}, parentPath...), key)
+			allowedOverride, nestedAllowed := findAllowedOverride(allowedOverrides, strings.Join(path, "."))
+			if nestedFields, isMap := fields[key].(map[string]interface{}); isMap && len(nestedFields) > 0 && (allowedOverride != nil || nestedAllowed) {
+				walk(nestedFields, path)
+				continue
+			}
+			if allowedOverride == nil {
+				notAllowedOverrides = append(notAllowedOverrides, notAllowedOverride{path, "is not allowed"})
+			} else if reason := overrideViolation(fields[key], *allowedOverride); reason != "" {
+				notAllowedOverrides = append(notAllowedOverrides, notAllowedOverride{path, reason})
+			}
+		}
+	}
+	walk(spec, nil)
+
+	// unset fields override the fields they unset
+	unsetFields, _, _ := unstructured.NestedStringSlice(spec, "unsetFields")
+	for _, unsetField := range unsetFields {
+		if !unsetFieldAllowed(allowedOverrides, unsetField) {
+			notAllowedOverrides = append(notAllowedOverrides, notAllowedOverride{[]string{unsetField}, "cannot be unset"})
+		}
+	}
+
+	return notAllowedOverrides
+}
+
+// unsetFieldAllowed whether allowed overrides allow to unset a field or list item
+func unsetFieldAllowed(allowedOverrides []lmsv1alpha1.AllowedOverride, unsetField string) bool {
+	allowedOverride, _ := findAllowedOverride(allowedOverrides, strings.SplitN(unsetField, "[", 2)[0])
+	return allowedOverride != nil
+}
+
+// findAllowedOverride returns the allowed override with the longest path matching a field
+// path, the first one if several, and whether any allows fields nested in it
+func findAllowedOverride(allowedOverrides []lmsv1alpha1.AllowedOverride, path string) (allowedOverride *lmsv1alpha1.AllowedOverride, nestedAllowed bool) {
+	for i := range allowedOverrides {
+		allowedPath := allowedOverrides[i].Path
+		if strings.HasPrefix(allowedPath, path+".") {
+			nestedAllowed = true
+		}
+		if allowedPath != path && !strings.HasPrefix(path, allowedPath+".") {
+			continue
+		}
+		if allowedOverride == nil || len(allowedPath) > len(allowedOverride.Path) {
+			allowedOverride = &allowedOverrides[i]
+		}
+	}
+	return allowedOverride, nestedAllowed
+}
+
+// overrideViolation returns why a value does not meet allowed override constraints, empty
+// if it does. Constraints apply to each item of lists and are not checked on maps
+func overrideViolation(value interface{}, allowedOverride lmsv1alpha1.AllowedOverride) string {
+	switch typedValue := value.(type) {
+	case []interface{}:
+		for _, item := range typedValue {
+			if reason := overrideViolation(item, allowedOverride); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	case map[string]interface{}:
+		return ""
+	}
+
+	valueString := fmt.Sprint(value)
+	if len(allowedOverride.Enum) > 0 {
+		inEnum := false
+		for _, enumValue := range allowedOverride.Enum {
+			if enumValue == valueString {
+				inEnum = true
+				break
+			}
+		}
+		if !inEnum {
+			return "must be one of: " + strings.Join(allowedOverride.Enum, ", ")
+		}
+	}
+
+	if allowedOverride.Minimum != nil || allowedOverride.Maximum != nil {
+		quantity, err := overrideQuantity(value)
+		if err != nil {
+			return "must be a number or quantity"
+		}
+		if allowedOverride.Minimum != nil && quantity.Cmp(*allowedOverride.Minimum) < 0 {
+			return "must be at least " + allowedOverride.Minimum.String()
+		}
+		if allowedOverride.Maximum != nil && quantity.Cmp(*allowedOverride.Maximum) > 0 {
+			return "must be at most " + allowedOverride.Maximum.String()
+		}
+	}
+
+	if allowedOverride.Pattern != "" {
+		pattern, err := regexp.Compile(allowedOverride.Pattern)
+		if err != nil {
+			return "is constrained by an invalid pattern"
+		}
+		if !pattern.MatchString(valueString) {
+			return "must match " + allowedOverride.Pattern
+		}
+	}
+
+	return ""
+}
+
+// overrideQuantity returns a number or quantity value as a quantity
+func overrideQuantity(value interface{}) (resource.Quantity, error) {
+	switch typedValue := value.(type) {
+	case int64:
+		return *resource.NewQuantity(typedValue, resource.DecimalSI), nil
+	case float64:
+		return resource.ParseQuantity(strconv.FormatFloat(typedValue, 'f', -1, 64))
+	case string:
+		return resource.ParseQuantity(typedValue)
+	}
+	return resource.Quantity{}, fmt.Errorf("%v is not a number or quantity", value)
+}
+
+// getOverridesAllowedCondition returns OverridesAllowed condition, from overrides not allowed
+func (r *LMSMoodleReconciler) getOverridesAllowedCondition() map[string]interface{} {
+	switch {
+	case !r.lmsMoodleCtx.overridesRestricted:
+		return map[string]interface{}{
+			"type":    OverridesAllowedConditionType,
+			"status":  "True",
+			"reason":  "Unrestricted",
+			"message": "LMSMoodleTemplate allows any override",
+		}
+	case len(r.lmsMoodleCtx.notAllowedOverrides) == 0:
+		return map[string]interface{}{
+			"type":    OverridesAllowedConditionType,
+			"status":  "True",
+			"reason":  "Allowed",
+			"message": "LMSMoodleTemplate allows all overrides set",
+		}
+	}
+
+	reason := "Ignored"
+	if r.lmsMoodleCtx.overridesRejected {
+		reason = "Rejected"
+	}
+	return map[string]interface{}{
+		"type":    OverridesAllowedConditionType,
+		"status":  "False",
+		"reason":  reason,
+		"message": notAllowedOverridesMessage(r.lmsMoodleCtx.notAllowedOverrides, r.lmsMoodleCtx.overridesRejected),
+	}
+}
+
+// notAllowedOverridesMessage lists overrides not allowed and why
+func notAllowedOverridesMessage(notAllowedOverrides []notAllowedOverride, rejected bool) string {
+	var fields []string
+	for i, override := range notAllowedOverrides {
+		if i == OverridesMessageMaxFields {
+			fields = append(fields, fmt.Sprintf("and %d more", len(notAllowedOverrides)-i))
+			break
+		}
+		fields = append(fields, strings.Join(override.path, ".")+" "+override.reason)
+	}
+	if rejected {
+		return "Overrides rejected: " + strings.Join(fields, "; ")
+	}
+	return "Overrides ignored: " + strings.Join(fields, "; ")
+}
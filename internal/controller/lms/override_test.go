@@ -0,0 +1,149 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Override policy", func() {
+	notAllowedPaths := func(notAllowedOverrides []notAllowedOverride) map[string]string {
+		paths := map[string]string{}
+		for _, override := range notAllowedOverrides {
+			paths[strings.Join(override.path, ".")] = override.reason
+		}
+		return paths
+	}
+
+	Context("getNotAllowedOverrides", func() {
+		It("should always allow lifecycle fields and unset fields allowed", func() {
+			spec := map[string]interface{}{
+				"lmsMoodleTemplateName": "template",
+				"desiredState":          "Suspended",
+				"paused":                true,
+				"componentStates":       map[string]interface{}{"moodle": "Suspended"},
+				"unsetFields":           []interface{}{"moodleSpec.moodleHost"},
+			}
+			allowedOverrides := []lmsv1alpha1.AllowedOverride{{Path: "moodleSpec.moodleHost"}}
+			Expect(getNotAllowedOverrides(spec, allowedOverrides)).To(BeEmpty())
+		})
+
+		It("should allow target namespace only as allowed by the template", func() {
+			spec := map[string]interface{}{"targetNamespace": "shared"}
+			Expect(notAllowedPaths(getNotAllowedOverrides(spec, nil))).To(Equal(map[string]string{"targetNamespace": "is not allowed"}))
+
+			allowedOverrides := []lmsv1alpha1.AllowedOverride{{Path: "targetNamespace"}}
+			Expect(getNotAllowedOverrides(spec, allowedOverrides)).To(BeEmpty())
+		})
+
+		It("should return fields not allowed, sorted by path, and maps without any field allowed as a whole", func() {
+			spec := map[string]interface{}{
+				"moodleSpec": map[string]interface{}{
+					"moodleHost":   "site.example.com",
+					"moodleSize":   int64(3),
+					"moodleConfig": map[string]interface{}{"debug": true},
+				},
+				"postgresSpec":        map[string]interface{}{"postgresSize": int64(1)},
+				"lmsMoodleNetpolOmit": true,
+				"unsetFields":         []interface{}{"keydbSpec.keydbMode"},
+			}
+			allowedOverrides := []lmsv1alpha1.AllowedOverride{
+				{Path: "moodleSpec.moodleHost"},
+				{Path: "moodleSpec.moodleSize", Maximum: resource.NewQuantity(2, resource.DecimalSI)},
+			}
+
+			notAllowedOverrides := getNotAllowedOverrides(spec, allowedOverrides)
+			Expect(notAllowedPaths(notAllowedOverrides)).To(Equal(map[string]string{
+				"lmsMoodleNetpolOmit":     "is not allowed",
+				"moodleSpec.moodleConfig": "is not allowed",
+				"moodleSpec.moodleSize":   "must be at most 2",
+				"postgresSpec":            "is not allowed",
+				"keydbSpec.keydbMode":     "cannot be unset",
+			}))
+			Expect(notAllowedOverrides[0].path).To(Equal([]string{"lmsMoodleNetpolOmit"}))
+			Expect(notAllowedOverrides[len(notAllowedOverrides)-1].path).To(Equal([]string{"keydbSpec.keydbMode"}))
+		})
+
+		It("should allow fields nested in an allowed path, unless a longer path constrains them", func() {
+			spec := map[string]interface{}{
+				"moodleSpec": map[string]interface{}{
+					"moodleHost":        "site.example.com",
+					"moodlePvcDataSize": "20Gi",
+				},
+			}
+			allowedOverrides := []lmsv1alpha1.AllowedOverride{
+				{Path: "moodleSpec"},
+				{Path: "moodleSpec.moodlePvcDataSize", Maximum: resource.NewQuantity(10*1024*1024*1024, resource.BinarySI)},
+			}
+			Expect(notAllowedPaths(getNotAllowedOverrides(spec, allowedOverrides))).To(Equal(map[string]string{
+				"moodleSpec.moodlePvcDataSize": "must be at most 10Gi",
+			}))
+		})
+	})
+
+	Context("overrideViolation", func() {
+		It("should check values in enum", func() {
+			allowedOverride := lmsv1alpha1.AllowedOverride{Path: "desiredState", Enum: []string{"Ready", "true", "2"}}
+			Expect(overrideViolation("Ready", allowedOverride)).To(BeEmpty())
+			Expect(overrideViolation(true, allowedOverride)).To(BeEmpty())
+			Expect(overrideViolation(int64(2), allowedOverride)).To(BeEmpty())
+			Expect(overrideViolation("Suspended", allowedOverride)).To(Equal("must be one of: Ready, true, 2"))
+		})
+
+		It("should check numbers and quantities within minimum and maximum", func() {
+			allowedOverride := lmsv1alpha1.AllowedOverride{
+				Path:    "moodleSpec.moodlePvcDataSize",
+				Minimum: resource.NewQuantity(1024*1024*1024, resource.BinarySI),
+				Maximum: resource.NewQuantity(10*1024*1024*1024, resource.BinarySI),
+			}
+			Expect(overrideViolation("5Gi", allowedOverride)).To(BeEmpty())
+			Expect(overrideViolation("10Gi", allowedOverride)).To(BeEmpty())
+			Expect(overrideViolation("512Mi", allowedOverride)).To(Equal("must be at least 1Gi"))
+			Expect(overrideViolation("11Gi", allowedOverride)).To(Equal("must be at most 10Gi"))
+			Expect(overrideViolation("large", allowedOverride)).To(Equal("must be a number or quantity"))
+			Expect(overrideViolation(true, allowedOverride)).To(Equal("must be a number or quantity"))
+
+			allowedOverride = lmsv1alpha1.AllowedOverride{Path: "moodleSpec.moodleSize", Maximum: resource.NewQuantity(3, resource.DecimalSI)}
+			Expect(overrideViolation(int64(3), allowedOverride)).To(BeEmpty())
+			Expect(overrideViolation(float64(2.5), allowedOverride)).To(BeEmpty())
+			Expect(overrideViolation(int64(4), allowedOverride)).To(Equal("must be at most 3"))
+		})
+
+		It("should check values match pattern", func() {
+			allowedOverride := lmsv1alpha1.AllowedOverride{Path: "moodleSpec.moodleHost", Pattern: `^[a-z]+\.example\.com$`}
+			Expect(overrideViolation("site.example.com", allowedOverride)).To(BeEmpty())
+			Expect(overrideViolation("site.example.org", allowedOverride)).To(Equal(`must match ^[a-z]+\.example\.com$`))
+
+			allowedOverride.Pattern = "["
+			Expect(overrideViolation("site.example.com", allowedOverride)).To(Equal("is constrained by an invalid pattern"))
+		})
+
+		It("should check each list item and not check maps", func() {
+			allowedOverride := lmsv1alpha1.AllowedOverride{Path: "moodleSpec.moodleHostAliases", Pattern: `\.example\.com$`}
+			Expect(overrideViolation([]interface{}{"a.example.com", "b.example.com"}, allowedOverride)).To(BeEmpty())
+			Expect(overrideViolation([]interface{}{"a.example.com", "b.example.org"}, allowedOverride)).To(Equal(`must match \.example\.com$`))
+			Expect(overrideViolation(map[string]interface{}{"host": "b.example.org"}, allowedOverride)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,133 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+// newDependantsScheme returns a scheme with LMSMoodle types and dependants as unstructured ones
+func newDependantsScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(lmsv1alpha1.AddToScheme(scheme)).To(Succeed())
+	for _, gvk := range []schema.GroupVersionKind{MoodleGVK, NfsGVK, KeydbGVK, PostgresGVK} {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+	return scheme
+}
+
+var _ = Describe("Paused LMSMoodle", func() {
+	var c client.Client
+	var r *LMSMoodleReconciler
+
+	newLMSMoodle := func(status map[string]interface{}) *unstructured.Unstructured {
+		lmsMoodle := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "lms.krestomat.io/v1alpha1",
+			"kind":       "LMSMoodle",
+			"metadata":   map[string]interface{}{"name": "site"},
+			"spec": map[string]interface{}{
+				// template does not exist, it is not needed while paused
+				"lmsMoodleTemplateName": "template",
+				"paused":                true,
+				"stateHistory":          map[string]interface{}{"configMap": true},
+			},
+		}}
+		if status != nil {
+			lmsMoodle.Object["status"] = status
+		}
+		return lmsMoodle
+	}
+
+	newReconciler := func(objs ...client.Object) {
+		scheme := newDependantsScheme()
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+			WithStatusSubresource(&lmsv1alpha1.LMSMoodle{}).Build()
+		r = &LMSMoodleReconciler{
+			Client:      c,
+			Scheme:      scheme,
+			Recorder:    &record.FakeRecorder{},
+			MoodleGVK:   MoodleGVK,
+			NfsGVK:      NfsGVK,
+			KeydbGVK:    KeydbGVK,
+			PostgresGVK: PostgresGVK,
+		}
+	}
+
+	reconcileSite := func() *unstructured.Unstructured {
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "site"}})
+		Expect(err).NotTo(HaveOccurred())
+		lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		Expect(c.Get(ctx, types.NamespacedName{Name: "site"}, lmsMoodle)).To(Succeed())
+		return lmsMoodle
+	}
+
+	It("should add the finalizer to a site created paused, without applying anything", func() {
+		newReconciler(newLMSMoodle(nil))
+
+		lmsMoodle := reconcileSite()
+		Expect(lmsMoodle.GetFinalizers()).To(ContainElement(LMSMoodleFinalizer))
+		state, _, _ := unstructured.NestedString(lmsMoodle.Object, "status", "state")
+		Expect(state).To(Equal(lmsv1alpha1.PausedState))
+		namespaceName, _, _ := unstructured.NestedString(lmsMoodle.Object, "status", "names", "namespace")
+		Expect(namespaceName).To(BeEmpty())
+
+		namespaces := &corev1.NamespaceList{}
+		Expect(c.List(ctx, namespaces)).To(Succeed())
+		Expect(namespaces.Items).To(BeEmpty())
+		configMaps := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, configMaps)).To(Succeed())
+		Expect(configMaps.Items).To(BeEmpty())
+	})
+
+	It("should report dependants found by names kept in status, as they are", func() {
+		moodle := newUnstructuredObject(MoodleGVK)
+		moodle.SetName("lms-site")
+		moodle.SetNamespace("lms-site")
+		moodle.Object["spec"] = map[string]interface{}{"moodleHost": "site.example.com"}
+		newReconciler(newLMSMoodle(map[string]interface{}{
+			"names": map[string]interface{}{"namespace": "lms-site", "base": "lms-site"},
+		}), moodle)
+
+		lmsMoodle := reconcileSite()
+		componentStates, _, _ := unstructured.NestedMap(lmsMoodle.Object, "status", "componentStates")
+		Expect(componentStates).To(HaveKey("moodle"))
+		Expect(componentStates).NotTo(HaveKey("postgres"))
+
+		liveMoodle := newUnstructuredObject(MoodleGVK)
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(moodle), liveMoodle)).To(Succeed())
+		Expect(liveMoodle.Object["spec"]).To(Equal(moodle.Object["spec"]))
+		configMaps := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, configMaps)).To(Succeed())
+		Expect(configMaps.Items).To(BeEmpty())
+	})
+})
@@ -0,0 +1,251 @@
+package lms
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+const (
+	// LMSMoodleMetadataFieldManager is the field manager of labels and annotations propagated to
+	// LMSMoodle from its template, so that those no longer propagated are removed
+	LMSMoodleMetadataFieldManager string = OPERATORNAME + "-metadata"
+)
+
+// unpropagatedAnnotationPrefixes are prefixes of annotations that never propagate, since
+// they are set by the operator, such as paused and claim ones, or by clients
+var unpropagatedAnnotationPrefixes = []string{lmsv1alpha1.GroupVersion.Group + "/", "kubectl.kubernetes.io/"}
+
+// propagationTargets are valid targets of propagation rules
+var propagationTargets = []lmsv1alpha1.PropagationTarget{
+	lmsv1alpha1.LMSMoodlePropagationTarget,
+	lmsv1alpha1.NamespacePropagationTarget,
+	lmsv1alpha1.MoodlePropagationTarget,
+	lmsv1alpha1.PostgresPropagationTarget,
+	lmsv1alpha1.NfsPropagationTarget,
+	lmsv1alpha1.KeydbPropagationTarget,
+	lmsv1alpha1.PodsPropagationTarget,
+}
+
+// PropagationPolicy defines which label and annotation keys propagate to which targets.
+// Rules of each kind not set are the default ones. LMSMoodleTemplate rules of each kind,
+// if set, replace them
+type PropagationPolicy struct {
+	// Labels propagation rules
+	Labels []lmsv1alpha1.PropagationRule
+	// Annotations propagation rules
+	Annotations []lmsv1alpha1.PropagationRule
+}
+
+// propagationRule is a propagation rule with its regex, if any, compiled
+type propagationRule struct {
+	lmsv1alpha1.PropagationRule
+	regex *regexp.Regexp
+}
+
+// compiledPropagationPolicy is a propagation policy with its rules compiled
+type compiledPropagationPolicy struct {
+	labels      []propagationRule
+	annotations []propagationRule
+}
+
+// DefaultPropagationPolicy propagates labels as before propagation was configurable: all of
+// them to LMSMoodle, namespace and dependant resources, and to pods, except app and
+// app.kubernetes.io ones. Annotations do not propagate
+var DefaultPropagationPolicy = PropagationPolicy{
+	Labels: []lmsv1alpha1.PropagationRule{
+		{Targets: []lmsv1alpha1.PropagationTarget{
+			lmsv1alpha1.LMSMoodlePropagationTarget,
+			lmsv1alpha1.NamespacePropagationTarget,
+			lmsv1alpha1.MoodlePropagationTarget,
+			lmsv1alpha1.PostgresPropagationTarget,
+			lmsv1alpha1.NfsPropagationTarget,
+			lmsv1alpha1.KeydbPropagationTarget,
+		}},
+		{Prefix: "app.kubernetes.io", Exclude: true, Targets: []lmsv1alpha1.PropagationTarget{lmsv1alpha1.PodsPropagationTarget}},
+		{Regex: "^app$", Exclude: true, Targets: []lmsv1alpha1.PropagationTarget{lmsv1alpha1.PodsPropagationTarget}},
+		{Targets: []lmsv1alpha1.PropagationTarget{lmsv1alpha1.PodsPropagationTarget}},
+	},
+}
+
+// ParsePropagationRule parses a propagation rule, such as prefix:example.com/=Namespace,Pods
+// or regex:^team$=Moodle. Rules starting with ! exclude keys, such as !prefix:internal/=Pods
+func ParsePropagationRule(value string) (lmsv1alpha1.PropagationRule, error) {
+	rule := lmsv1alpha1.PropagationRule{}
+	ruleValue := value
+	if strings.HasPrefix(ruleValue, "!") {
+		rule.Exclude = true
+		ruleValue = ruleValue[1:]
+	}
+
+	// targets have no '=', unlike regexes
+	index := strings.LastIndex(ruleValue, "=")
+	if index < 0 {
+		return rule, fmt.Errorf("propagation rule '%s' has no targets", value)
+	}
+	switch kind, pattern, _ := strings.Cut(ruleValue[:index], ":"); kind {
+	case "prefix":
+		rule.Prefix = pattern
+	case "regex":
+		if _, err := regexp.Compile(pattern); err != nil {
+			return rule, fmt.Errorf("propagation rule '%s' has an invalid regex: %w", value, err)
+		}
+		rule.Regex = pattern
+	default:
+		return rule, fmt.Errorf("propagation rule '%s' must start with prefix: or regex:", value)
+	}
+
+	for _, target := range strings.Split(ruleValue[index+1:], ",") {
+		if !slices.Contains(propagationTargets, lmsv1alpha1.PropagationTarget(target)) {
+			return rule, fmt.Errorf("propagation rule '%s' has an invalid target '%s'", value, target)
+		}
+		rule.Targets = append(rule.Targets, lmsv1alpha1.PropagationTarget(target))
+	}
+	return rule, nil
+}
+
+// propagationPolicy returns reconciler propagation policy, with default rules of each kind not
+// set, replaced by lms moodle template rules of each kind set. It is compiled once per
+// reconcile, or once lms moodle template spec is set
+func (r *LMSMoodleReconciler) propagationPolicy() (*compiledPropagationPolicy, error) {
+	if r.lmsMoodleCtx.propagationPolicy != nil {
+		return r.lmsMoodleCtx.propagationPolicy, nil
+	}
+
+	propagationPolicy := r.PropagationPolicy
+	if propagationPolicy.Labels == nil {
+		propagationPolicy.Labels = DefaultPropagationPolicy.Labels
+	}
+	if propagationPolicy.Annotations == nil {
+		propagationPolicy.Annotations = DefaultPropagationPolicy.Annotations
+	}
+
+	if metadataPropagationU, found, _ := unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodleTemplateSpec, "metadataPropagation"); found {
+		metadataPropagation := lmsv1alpha1.MetadataPropagation{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(metadataPropagationU, &metadataPropagation); err != nil {
+			return nil, err
+		}
+		if len(metadataPropagation.Labels) > 0 {
+			propagationPolicy.Labels = metadataPropagation.Labels
+		}
+		if len(metadataPropagation.Annotations) > 0 {
+			propagationPolicy.Annotations = metadataPropagation.Annotations
+		}
+	}
+
+	compiled := &compiledPropagationPolicy{}
+	var err error
+	if compiled.labels, err = compilePropagationRules(propagationPolicy.Labels); err != nil {
+		return nil, err
+	}
+	if compiled.annotations, err = compilePropagationRules(propagationPolicy.Annotations); err != nil {
+		return nil, err
+	}
+	r.lmsMoodleCtx.propagationPolicy = compiled
+	return compiled, nil
+}
+
+// compilePropagationRules returns propagation rules with their regexes compiled
+func compilePropagationRules(rules []lmsv1alpha1.PropagationRule) ([]propagationRule, error) {
+	compiled := make([]propagationRule, 0, len(rules))
+	for _, rule := range rules {
+		compiledRule := propagationRule{PropagationRule: rule}
+		if rule.Regex != "" {
+			regex, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid propagation rule regex '%s': %w", rule.Regex, err)
+			}
+			compiledRule.regex = regex
+		}
+		compiled = append(compiled, compiledRule)
+	}
+	return compiled, nil
+}
+
+// baseLabels returns lms moodle base labels, which always propagate
+func (r *LMSMoodleReconciler) baseLabels() map[string]string {
+	return map[string]string{
+		lmsv1alpha1.GroupVersion.Group + "/lms-name":           r.lmsMoodleCtx.name,
+		lmsv1alpha1.GroupVersion.Group + "/meta-operator-name": OPERATORNAME,
+	}
+}
+
+// propagateMetadata returns labels and annotations of a source propagated to a target, along
+// with lms moodle base labels
+func (r *LMSMoodleReconciler) propagateMetadata(source metav1.Object, target lmsv1alpha1.PropagationTarget) (labels map[string]string, annotations map[string]string, err error) {
+	propagationPolicy, err := r.propagationPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	labels = propagateKeys(propagationPolicy.labels, target, source.GetLabels())
+	for key, value := range r.baseLabels() {
+		labels[key] = value
+	}
+
+	sourceAnnotations := map[string]string{}
+	for key, value := range source.GetAnnotations() {
+		if !slices.ContainsFunc(unpropagatedAnnotationPrefixes, func(prefix string) bool { return strings.HasPrefix(key, prefix) }) {
+			sourceAnnotations[key] = value
+		}
+	}
+	annotations = propagateKeys(propagationPolicy.annotations, target, sourceAnnotations)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	return labels, annotations, nil
+}
+
+// propagatedMetadata returns lms moodle labels and annotations propagated to a target.
+// Should be used once lms moodle labels are set
+func (r *LMSMoodleReconciler) propagatedMetadata(target lmsv1alpha1.PropagationTarget) (labels map[string]string, annotations map[string]string, err error) {
+	return r.propagateMetadata(r.lmsMoodleCtx.lmsMoodle, target)
+}
+
+// setPropagatedMetadata sets lms moodle labels and annotations propagated to a target object
+func (r *LMSMoodleReconciler) setPropagatedMetadata(obj metav1.Object, target lmsv1alpha1.PropagationTarget) error {
+	labels, annotations, err := r.propagatedMetadata(target)
+	if err != nil {
+		return err
+	}
+	obj.SetLabels(labels)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// propagateKeys returns keys and values of a source that propagate to a target
+func propagateKeys(rules []propagationRule, target lmsv1alpha1.PropagationTarget, source map[string]string) map[string]string {
+	propagated := map[string]string{}
+	for key, value := range source {
+		if keyPropagates(rules, target, key) {
+			propagated[key] = value
+		}
+	}
+	return propagated
+}
+
+// keyPropagates whether a key propagates to a target, by the first rule of the target
+// matching it. Keys no rule matches do not propagate
+func keyPropagates(rules []propagationRule, target lmsv1alpha1.PropagationTarget, key string) bool {
+	for _, rule := range rules {
+		if !slices.Contains(rule.Targets, target) {
+			continue
+		}
+		matches := strings.HasPrefix(key, rule.Prefix)
+		if rule.regex != nil {
+			matches = rule.regex.MatchString(key)
+		}
+		if matches {
+			return !rule.Exclude
+		}
+	}
+	return false
+}
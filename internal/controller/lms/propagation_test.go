@@ -0,0 +1,187 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Metadata propagation", func() {
+	Context("ParsePropagationRule", func() {
+		It("should parse prefix and regex rules, with their targets", func() {
+			rule, err := ParsePropagationRule("prefix:example.com/=Namespace,Pods")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rule).To(Equal(lmsv1alpha1.PropagationRule{
+				Prefix:  "example.com/",
+				Targets: []lmsv1alpha1.PropagationTarget{lmsv1alpha1.NamespacePropagationTarget, lmsv1alpha1.PodsPropagationTarget},
+			}))
+
+			rule, err = ParsePropagationRule("regex:^team=[a-z]+$=Moodle")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rule).To(Equal(lmsv1alpha1.PropagationRule{
+				Regex:   "^team=[a-z]+$",
+				Targets: []lmsv1alpha1.PropagationTarget{lmsv1alpha1.MoodlePropagationTarget},
+			}))
+		})
+
+		It("should parse rules excluding keys", func() {
+			rule, err := ParsePropagationRule("!prefix:internal/=Pods")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rule.Exclude).To(BeTrue())
+			Expect(rule.Prefix).To(Equal("internal/"))
+		})
+
+		It("should reject rules without targets, kind, valid regex or valid targets", func() {
+			for value, message := range map[string]string{
+				"prefix:example.com/":            "has no targets",
+				"suffix:example.com/=Pods":       "must start with prefix: or regex:",
+				"regex:[=Pods":                   "has an invalid regex",
+				"prefix:example.com/=Deployment": "has an invalid target 'Deployment'",
+			} {
+				_, err := ParsePropagationRule(value)
+				Expect(err).To(MatchError(ContainSubstring(message)), value)
+			}
+		})
+
+	})
+
+	Context("propagationPolicy", func() {
+		It("should compile rules once, until template spec is set again", func() {
+			r := &LMSMoodleReconciler{}
+			r.lmsMoodleCtx.lmsMoodleTemplate = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleTemplate"))
+			r.lmsMoodleCtx.lmsMoodleTemplate.Object["spec"] = map[string]interface{}{
+				"metadataPropagation": map[string]interface{}{"labels": []interface{}{
+					map[string]interface{}{"regex": "^team$", "targets": []interface{}{"Pods"}},
+				}},
+			}
+			r.setLMSMoodleTemplateSpec()
+
+			propagationPolicy, err := r.propagationPolicy()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(propagationPolicy.labels).To(HaveLen(1))
+			Expect(propagationPolicy.labels[0].regex.String()).To(Equal("^team$"))
+			Expect(r.propagationPolicy()).To(BeIdenticalTo(propagationPolicy))
+
+			r.setLMSMoodleTemplateSpec()
+			Expect(r.propagationPolicy()).NotTo(BeIdenticalTo(propagationPolicy))
+		})
+
+		It("should fail on invalid regexes", func() {
+			r := &LMSMoodleReconciler{PropagationPolicy: PropagationPolicy{Labels: []lmsv1alpha1.PropagationRule{
+				{Regex: "[", Targets: []lmsv1alpha1.PropagationTarget{lmsv1alpha1.PodsPropagationTarget}},
+			}}}
+			_, err := r.propagationPolicy()
+			Expect(err).To(MatchError(ContainSubstring("invalid propagation rule regex '['")))
+		})
+	})
+
+	Context("keyPropagates", func() {
+		compile := func(rules []lmsv1alpha1.PropagationRule) []propagationRule {
+			compiled, err := compilePropagationRules(rules)
+			Expect(err).NotTo(HaveOccurred())
+			return compiled
+		}
+		var rules []propagationRule
+
+		BeforeEach(func() {
+			rules = compile([]lmsv1alpha1.PropagationRule{
+				{Prefix: "internal/", Exclude: true, Targets: []lmsv1alpha1.PropagationTarget{lmsv1alpha1.PodsPropagationTarget}},
+				{Regex: "^team$", Targets: []lmsv1alpha1.PropagationTarget{lmsv1alpha1.NamespacePropagationTarget}},
+				{Prefix: "", Targets: []lmsv1alpha1.PropagationTarget{lmsv1alpha1.PodsPropagationTarget}},
+			})
+		})
+
+		It("should apply the first rule of the target matching a key", func() {
+			for key, propagates := range map[string]bool{"internal/cost": false, "team": true, "app": true} {
+				Expect(keyPropagates(rules, lmsv1alpha1.PodsPropagationTarget, key)).To(Equal(propagates), key)
+			}
+			Expect(keyPropagates(rules, lmsv1alpha1.NamespacePropagationTarget, "team")).To(BeTrue())
+		})
+
+		It("should not propagate keys no rule of the target matches", func() {
+			Expect(keyPropagates(rules, lmsv1alpha1.NamespacePropagationTarget, "teams")).To(BeFalse())
+			Expect(keyPropagates(rules, lmsv1alpha1.MoodlePropagationTarget, "team")).To(BeFalse())
+		})
+
+		It("should follow default policy", func() {
+			labels := compile(DefaultPropagationPolicy.Labels)
+			for key, propagates := range map[string]bool{"app": false, "app.kubernetes.io/name": false, "application": true, "team": true} {
+				Expect(keyPropagates(labels, lmsv1alpha1.PodsPropagationTarget, key)).To(Equal(propagates), key)
+				Expect(keyPropagates(labels, lmsv1alpha1.NamespacePropagationTarget, key)).To(BeTrue(), key)
+			}
+			Expect(keyPropagates(compile(DefaultPropagationPolicy.Annotations), lmsv1alpha1.NamespacePropagationTarget, "team")).To(BeFalse())
+		})
+	})
+
+	Context("When site labels were patched by the legacy field manager", func() {
+		It("should move their ownership to the metadata field manager and apply them again", func() {
+			lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+			lmsMoodle.SetName("site")
+			lmsMoodle.SetLabels(map[string]string{"stale": "true", "team": "education"})
+			lmsMoodle.SetManagedFields([]metav1.ManagedFieldsEntry{{
+				Manager:    LegacyFieldManager,
+				Operation:  metav1.ManagedFieldsOperationUpdate,
+				APIVersion: lmsv1alpha1.GroupVersion.String(),
+				FieldsType: "FieldsV1",
+				FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{".":{},"f:stale":{},"f:team":{}}}}`)},
+			}})
+			lmsMoodleTemplate := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodleTemplate"))
+			lmsMoodleTemplate.SetName("template")
+			lmsMoodleTemplate.SetLabels(map[string]string{"team": "education"})
+
+			applies := 0
+			c := fake.NewClientBuilder().WithScheme(newDependantsScheme()).WithObjects(lmsMoodle).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						if patch.Type() == types.ApplyPatchType {
+							applies++
+						}
+						return applyAsUpdate(ctx, c, obj, patch, opts...)
+					},
+				}).Build()
+			r := &LMSMoodleReconciler{Client: c, Scheme: c.Scheme(), Recorder: &record.FakeRecorder{}}
+			Expect(c.Get(ctx, types.NamespacedName{Name: "site"}, lmsMoodle)).To(Succeed())
+			r.lmsMoodleCtx.name = "site"
+			r.lmsMoodleCtx.lmsMoodle = lmsMoodle
+			r.lmsMoodleCtx.lmsMoodleTemplate = lmsMoodleTemplate
+
+			Expect(r.setSiteLabels(ctx)).To(Succeed())
+			Expect(applies).To(Equal(2))
+			liveLMSMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+			Expect(c.Get(ctx, types.NamespacedName{Name: "site"}, liveLMSMoodle)).To(Succeed())
+			Expect(liveLMSMoodle.GetLabels()).To(Equal(mergeStringMaps(map[string]string{"team": "education"}, r.baseLabels())))
+			Expect(liveLMSMoodle.GetManagedFields()).To(HaveLen(1))
+			Expect(liveLMSMoodle.GetManagedFields()[0].Manager).To(Equal(LMSMoodleMetadataFieldManager))
+
+			// once moved, labels are applied once
+			Expect(r.setSiteLabels(ctx)).To(Succeed())
+			Expect(applies).To(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,444 @@
+package lms
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+const (
+	// DefaultQuotaHeadroomPercent headroom added to resource requests of combined specs
+	// for calculated namespace quota hard limits
+	DefaultQuotaHeadroomPercent int32 = 50
+)
+
+// limitRangeDefaults are container limit range defaults required by quota hard limits,
+// since pods without those requests or limits are rejected
+var limitRangeDefaults = []struct {
+	hard      corev1.ResourceName
+	field     string
+	container corev1.ResourceName
+}{
+	{corev1.ResourceCPU, "defaultRequest", corev1.ResourceCPU},
+	{corev1.ResourceLimitsCPU, "default", corev1.ResourceCPU},
+	{corev1.ResourceLimitsMemory, "default", corev1.ResourceMemory},
+	{corev1.ResourceMemory, "defaultRequest", corev1.ResourceMemory},
+	{corev1.ResourceRequestsCPU, "defaultRequest", corev1.ResourceCPU},
+	{corev1.ResourceRequestsMemory, "defaultRequest", corev1.ResourceMemory},
+}
+
+// componentResources are resource requests and limits of each pod of a component,
+// as set in combined specs, and its number of pods
+type componentResources struct {
+	component string
+	pods      int64
+	requests  corev1.ResourceList
+	limits    corev1.ResourceList
+}
+
+// defineNamespaceQuota define resource quota of lms moodle namespace, from namespace quota
+// and resource requests in combined specs. Should be used once combined specs are set
+func (r *LMSMoodleReconciler) defineNamespaceQuota() error {
+	r.lmsMoodleCtx.quotaExceeded = nil
+	resourceQuotaName, _, _ := unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "names", "resourceQuota")
+	r.lmsMoodleCtx.namespaceQuotaApplied = resourceQuotaName != ""
+	namespaceQuota, found, err := r.getNamespaceQuota()
+	if err != nil {
+		return err
+	}
+	// target namespace quotas are managed by its owners
+	r.lmsMoodleCtx.namespaceQuotaOmit = !found || namespaceQuota.Omit || r.lmsMoodleCtx.targetNamespace != ""
+
+	components, err := r.getComponentResources()
+	if err != nil {
+		return err
+	}
+	total := totalComponentResources(components)
+
+	// calculated hard limits, plus headroom
+	headroomPercent := DefaultQuotaHeadroomPercent
+	if namespaceQuota.HeadroomPercent != nil {
+		headroomPercent = *namespaceQuota.HeadroomPercent
+	}
+	hard := corev1.ResourceList{}
+	for _, name := range []corev1.ResourceName{corev1.ResourceRequestsCPU, corev1.ResourceRequestsMemory} {
+		if quantity, found := total[name]; found && !quantity.IsZero() {
+			hard[name] = withHeadroom(name, quantity, headroomPercent)
+		}
+	}
+	for name, quantity := range namespaceQuota.Hard {
+		hard[name] = quantity
+	}
+
+	r.lmsMoodleCtx.namespaceQuota = &corev1.ResourceQuota{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ResourceQuota"},
+		Spec:     corev1.ResourceQuotaSpec{Hard: hard},
+	}
+	r.lmsMoodleCtx.namespaceQuota.SetNamespace(r.lmsMoodleCtx.namespaceName)
+	r.lmsMoodleCtx.namespaceQuota.SetName(r.lmsMoodleCtx.networkPolicyBaseName + "-quota")
+
+	if !r.lmsMoodleCtx.namespaceQuotaOmit {
+		r.lmsMoodleCtx.quotaExceeded = quotaExceeded(total, hard)
+	}
+
+	return nil
+}
+
+// defineNamespaceLimitRange define container limit range of lms moodle namespace, from
+// namespace limit range and resource requests and limits in combined specs. One is defined
+// for a namespace quota, even if not set, with the defaults its hard limits require. Should
+// be used once combined specs and namespace quota are set
+func (r *LMSMoodleReconciler) defineNamespaceLimitRange() error {
+	limitRangeName, _, _ := unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "names", "limitRange")
+	r.lmsMoodleCtx.namespaceLimitRangeApplied = limitRangeName != ""
+	namespaceLimitRange, found, err := r.getNamespaceLimitRange()
+	if err != nil {
+		return err
+	}
+	// target namespace limit ranges are managed by its owners
+	r.lmsMoodleCtx.namespaceLimitRangeOmit = (!found && r.lmsMoodleCtx.namespaceQuotaOmit) || namespaceLimitRange.Omit || r.lmsMoodleCtx.targetNamespace != ""
+
+	components, err := r.getComponentResources()
+	if err != nil {
+		return err
+	}
+
+	// calculated default requests and limits, the smallest ones in combined specs
+	defaultRequest := corev1.ResourceList{}
+	defaultLimit := corev1.ResourceList{}
+	for _, component := range components {
+		smallestResources(defaultRequest, component.requests, corev1.ResourceRequestsCPU, corev1.ResourceRequestsMemory)
+		smallestResources(defaultLimit, component.limits, corev1.ResourceLimitsCPU, corev1.ResourceLimitsMemory)
+	}
+	defaultRequest = mergeResourceLists(defaultRequest, namespaceLimitRange.DefaultRequest)
+	defaultLimit = mergeResourceLists(defaultLimit, namespaceLimitRange.Default)
+	// default requests can not exceed default limits
+	for name, limit := range defaultLimit {
+		if request, found := defaultRequest[name]; found && request.Cmp(limit) > 0 {
+			defaultRequest[name] = limit
+		}
+	}
+
+	limitRangeItem := corev1.LimitRangeItem{
+		Type:           corev1.LimitTypeContainer,
+		Default:        defaultLimit,
+		DefaultRequest: defaultRequest,
+		Max:            namespaceLimitRange.Max,
+		Min:            namespaceLimitRange.Min,
+	}
+	if len(limitRangeItem.Default) == 0 {
+		limitRangeItem.Default = nil
+	}
+	if len(limitRangeItem.DefaultRequest) == 0 {
+		limitRangeItem.DefaultRequest = nil
+	}
+
+	if !r.lmsMoodleCtx.namespaceQuotaOmit {
+		if err := checkLimitRangeDefaults(r.lmsMoodleCtx.namespaceQuota.Spec.Hard, limitRangeItem, r.lmsMoodleCtx.namespaceLimitRangeOmit); err != nil {
+			return err
+		}
+	}
+
+	r.lmsMoodleCtx.namespaceLimitRange = &corev1.LimitRange{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "LimitRange"},
+		Spec:     corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{limitRangeItem}},
+	}
+	r.lmsMoodleCtx.namespaceLimitRange.SetNamespace(r.lmsMoodleCtx.namespaceName)
+	r.lmsMoodleCtx.namespaceLimitRange.SetName(r.lmsMoodleCtx.networkPolicyBaseName + "-limitrange")
+
+	return nil
+}
+
+// reconcileNamespaceQuota applies namespace resource quota and limit range, unless omitted.
+// Omitted ones are deleted only if applied before, as recorded in LMSMoodle status names
+func (r *LMSMoodleReconciler) reconcileNamespaceQuota(ctx context.Context) error {
+	for _, namespaceObj := range []struct {
+		omit    bool
+		obj     client.Object
+		applied *bool
+	}{
+		{r.lmsMoodleCtx.namespaceQuotaOmit, r.lmsMoodleCtx.namespaceQuota, &r.lmsMoodleCtx.namespaceQuotaApplied},
+		{r.lmsMoodleCtx.namespaceLimitRangeOmit, r.lmsMoodleCtx.namespaceLimitRange, &r.lmsMoodleCtx.namespaceLimitRangeApplied},
+	} {
+		if !namespaceObj.omit {
+			if err := r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, namespaceObj.obj); err != nil {
+				return err
+			}
+			*namespaceObj.applied = true
+			continue
+		}
+		if !*namespaceObj.applied {
+			continue
+		}
+		if err := r.ReconcileDeleteDependant(ctx, r.lmsMoodleCtx.lmsMoodle, namespaceObj.obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		*namespaceObj.applied = false
+	}
+	return nil
+}
+
+// checkLimitRangeDefaults returns an error if quota hard limits constrain cpu or memory
+// requests or limits, and the limit range, omitted or not, does not set their defaults
+func checkLimitRangeDefaults(hard corev1.ResourceList, limitRangeItem corev1.LimitRangeItem, limitRangeOmit bool) error {
+	for _, required := range limitRangeDefaults {
+		if _, found := hard[required.hard]; !found {
+			continue
+		}
+		if limitRangeOmit {
+			return fmt.Errorf("namespaceQuota hard %s requires namespaceLimitRange, which is omitted", required.hard)
+		}
+		defaults := limitRangeItem.DefaultRequest
+		if required.field == "default" {
+			defaults = limitRangeItem.Default
+		}
+		if _, found := defaults[required.container]; !found {
+			return fmt.Errorf("namespaceQuota hard %s requires namespaceLimitRange %s %s, since it is not set in combined specs", required.hard, required.field, required.container)
+		}
+	}
+	return nil
+}
+
+// smallestResources sets cpu and memory of a container resource list to quantities in
+// another one, named after cpuName and memoryName, if smaller or not set yet
+func smallestResources(smallest corev1.ResourceList, resourceList corev1.ResourceList, cpuName corev1.ResourceName, memoryName corev1.ResourceName) {
+	for name, containerName := range map[corev1.ResourceName]corev1.ResourceName{cpuName: corev1.ResourceCPU, memoryName: corev1.ResourceMemory} {
+		quantity, found := resourceList[name]
+		if !found {
+			continue
+		}
+		if current, found := smallest[containerName]; !found || quantity.Cmp(current) < 0 {
+			smallest[containerName] = quantity
+		}
+	}
+}
+
+// getNamespaceQuota returns LMSMoodleTemplate namespace quota, overridden by LMSMoodle one,
+// field by field, and whether any of them is set. Hard limits are overridden by resource name
+func (r *LMSMoodleReconciler) getNamespaceQuota() (namespaceQuota lmsv1alpha1.NamespaceQuota, found bool, err error) {
+	for _, spec := range []map[string]interface{}{r.lmsMoodleCtx.lmsMoodleTemplateSpec, r.lmsMoodleCtx.spec} {
+		specNamespaceQuotaU, specFound, _ := unstructured.NestedMap(spec, "namespaceQuota")
+		if !specFound {
+			continue
+		}
+		found = true
+		specNamespaceQuota := lmsv1alpha1.NamespaceQuota{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specNamespaceQuotaU, &specNamespaceQuota); err != nil {
+			return namespaceQuota, found, err
+		}
+		if _, omitFound := specNamespaceQuotaU["omit"]; omitFound {
+			namespaceQuota.Omit = specNamespaceQuota.Omit
+		}
+		if specNamespaceQuota.HeadroomPercent != nil {
+			namespaceQuota.HeadroomPercent = specNamespaceQuota.HeadroomPercent
+		}
+		namespaceQuota.Hard = mergeResourceLists(namespaceQuota.Hard, specNamespaceQuota.Hard)
+	}
+	return namespaceQuota, found, nil
+}
+
+// getNamespaceLimitRange returns LMSMoodleTemplate namespace limit range, overridden by
+// LMSMoodle one, field by field, and whether any of them is set. Resources are overridden by name
+func (r *LMSMoodleReconciler) getNamespaceLimitRange() (namespaceLimitRange lmsv1alpha1.NamespaceLimitRange, found bool, err error) {
+	for _, spec := range []map[string]interface{}{r.lmsMoodleCtx.lmsMoodleTemplateSpec, r.lmsMoodleCtx.spec} {
+		specNamespaceLimitRangeU, specFound, _ := unstructured.NestedMap(spec, "namespaceLimitRange")
+		if !specFound {
+			continue
+		}
+		found = true
+		specNamespaceLimitRange := lmsv1alpha1.NamespaceLimitRange{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specNamespaceLimitRangeU, &specNamespaceLimitRange); err != nil {
+			return namespaceLimitRange, found, err
+		}
+		if _, omitFound := specNamespaceLimitRangeU["omit"]; omitFound {
+			namespaceLimitRange.Omit = specNamespaceLimitRange.Omit
+		}
+		namespaceLimitRange.DefaultRequest = mergeResourceLists(namespaceLimitRange.DefaultRequest, specNamespaceLimitRange.DefaultRequest)
+		namespaceLimitRange.Default = mergeResourceLists(namespaceLimitRange.Default, specNamespaceLimitRange.Default)
+		namespaceLimitRange.Max = mergeResourceLists(namespaceLimitRange.Max, specNamespaceLimitRange.Max)
+		namespaceLimitRange.Min = mergeResourceLists(namespaceLimitRange.Min, specNamespaceLimitRange.Min)
+	}
+	return namespaceLimitRange, found, nil
+}
+
+// getComponentResources returns resource requests and limits of present components, as set in
+// combined specs. Requests are added unless disabled and limits only when enabled, as dependants do.
+// Components with no replicas set are counted as one pod, and jobs as one pod each
+func (r *LMSMoodleReconciler) getComponentResources() ([]componentResources, error) {
+	type component struct {
+		spec   map[string]interface{}
+		prefix string
+		pods   int64
+	}
+
+	moodleSpec := r.lmsMoodleCtx.combinedMoodleSpec
+	components := []component{
+		{moodleSpec, "nginx", specSize(moodleSpec, "nginxSize", 1)},
+		{moodleSpec, "phpFpm", specSize(moodleSpec, "phpFpmSize", 1)},
+		{moodleSpec, "moodleCronjob", 1},
+		{moodleSpec, "moodleUpdateJob", 1},
+		{moodleSpec, "moodleNewInstanceJob", 1},
+	}
+	if r.lmsMoodleCtx.hasPostgres {
+		postgresSpec := r.lmsMoodleCtx.combinedPostgresSpec
+		readreplicas := specSize(postgresSpec, "postgresReadreplicasSize", 0)
+		components = append(components,
+			component{postgresSpec, "postgres", specSize(postgresSpec, "postgresSize", 1)},
+			component{postgresSpec, "postgresReadreplicas", readreplicas},
+			component{postgresSpec, "pgbouncer", 1},
+			component{postgresSpec, "pgbouncerReadonly", min(readreplicas, 1)},
+		)
+	}
+	if r.lmsMoodleCtx.hasKeydb {
+		keydbSpec := r.lmsMoodleCtx.combinedKeydbSpec
+		components = append(components, component{keydbSpec, "keydb", specSize(keydbSpec, "keydbSize", 1)})
+	}
+	if r.lmsMoodleCtx.hasNfs {
+		components = append(components, component{r.lmsMoodleCtx.combinedNfsSpec, "ganesha", 1})
+	}
+
+	var componentsResources []componentResources
+	for _, component := range components {
+		if component.pods == 0 {
+			continue
+		}
+		resources := componentResources{component: component.prefix, pods: component.pods}
+		var err error
+		if requests, found, _ := unstructured.NestedBool(component.spec, component.prefix+"ResourceRequests"); !found || requests {
+			if resources.requests, err = specResourceList(component.spec, component.prefix+"ResourceRequests", corev1.ResourceRequestsCPU, corev1.ResourceRequestsMemory); err != nil {
+				return nil, err
+			}
+		}
+		if limits, _, _ := unstructured.NestedBool(component.spec, component.prefix+"ResourceLimits"); limits {
+			if resources.limits, err = specResourceList(component.spec, component.prefix+"ResourceLimits", corev1.ResourceLimitsCPU, corev1.ResourceLimitsMemory); err != nil {
+				return nil, err
+			}
+		}
+		componentsResources = append(componentsResources, resources)
+	}
+	return componentsResources, nil
+}
+
+// specResourceList returns cpu and memory quantities set in spec fields with given prefix,
+// such as nginxResourceRequestsCpu, named after cpuName and memoryName
+func specResourceList(spec map[string]interface{}, fieldPrefix string, cpuName corev1.ResourceName, memoryName corev1.ResourceName) (corev1.ResourceList, error) {
+	resourceList := corev1.ResourceList{}
+	for name, field := range map[corev1.ResourceName]string{cpuName: fieldPrefix + "Cpu", memoryName: fieldPrefix + "Memory"} {
+		value, found, _ := unstructured.NestedString(spec, field)
+		if !found || value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", field, err)
+		}
+		resourceList[name] = quantity
+	}
+	return resourceList, nil
+}
+
+// specSize returns number of replicas set in a spec field or a default value
+func specSize(spec map[string]interface{}, field string, defaultSize int64) int64 {
+	if size, found, _ := unstructured.NestedInt64(spec, field); found {
+		return size
+	}
+	return defaultSize
+}
+
+// totalComponentResources returns requests and limits of all component pods
+func totalComponentResources(components []componentResources) corev1.ResourceList {
+	total := corev1.ResourceList{}
+	for _, component := range components {
+		for _, resourceList := range []corev1.ResourceList{component.requests, component.limits} {
+			for name, quantity := range resourceList {
+				componentTotal := quantity.DeepCopy()
+				componentTotal.Mul(component.pods)
+				sum := total[name]
+				sum.Add(componentTotal)
+				total[name] = sum
+			}
+		}
+	}
+	return total
+}
+
+// withHeadroom returns a quantity plus a percentage, in its format. Memory is rounded up
+// to Mi, or to M in decimal format, so it is not rendered in bytes
+func withHeadroom(name corev1.ResourceName, quantity resource.Quantity, headroomPercent int32) resource.Quantity {
+	if strings.HasSuffix(string(name), string(corev1.ResourceMemory)) {
+		unit := int64(1000 * 1000)
+		if quantity.Format == resource.BinarySI {
+			unit = 1024 * 1024
+		}
+		value := (quantity.Value()*int64(100+headroomPercent) + 99) / 100
+		return *resource.NewQuantity((value+unit-1)/unit*unit, quantity.Format)
+	}
+	milliValue := (quantity.MilliValue()*int64(100+headroomPercent) + 99) / 100
+	return *resource.NewMilliQuantity(milliValue, quantity.Format)
+}
+
+// mergeResourceLists returns a resource list overridden by another one, by resource name
+func mergeResourceLists(resourceList corev1.ResourceList, override corev1.ResourceList) corev1.ResourceList {
+	if len(override) == 0 {
+		return resourceList
+	}
+	merged := corev1.ResourceList{}
+	for name, quantity := range resourceList {
+		merged[name] = quantity
+	}
+	for name, quantity := range override {
+		merged[name] = quantity
+	}
+	return merged
+}
+
+// quotaExceeded returns messages about requests and limits of combined specs exceeding
+// quota hard limits. cpu and memory hard limits are the same as requests ones
+func quotaExceeded(total corev1.ResourceList, hard corev1.ResourceList) []string {
+	aliases := map[corev1.ResourceName]corev1.ResourceName{
+		corev1.ResourceCPU:    corev1.ResourceRequestsCPU,
+		corev1.ResourceMemory: corev1.ResourceRequestsMemory,
+	}
+
+	var exceeded []string
+	for name, hardQuantity := range hard {
+		totalName := name
+		if alias, found := aliases[name]; found {
+			totalName = alias
+		}
+		if totalQuantity, found := total[totalName]; found && totalQuantity.Cmp(hardQuantity) > 0 {
+			exceeded = append(exceeded, fmt.Sprintf("%s %s exceeds hard limit %s", name, totalQuantity.String(), hardQuantity.String()))
+		}
+	}
+	sort.Strings(exceeded)
+	return exceeded
+}
+
+// getQuotaExceededCondition returns QuotaExceeded condition, whether combined specs exceed
+// namespace quota
+func (r *LMSMoodleReconciler) getQuotaExceededCondition() map[string]interface{} {
+	if len(r.lmsMoodleCtx.quotaExceeded) == 0 {
+		return map[string]interface{}{
+			"type":    QuotaExceededConditionType,
+			"status":  "False",
+			"reason":  "WithinQuota",
+			"message": "Combined specs are within namespace quota",
+		}
+	}
+	return map[string]interface{}{
+		"type":    QuotaExceededConditionType,
+		"status":  "True",
+		"reason":  "CombinedSpecsExceedQuota",
+		"message": "Combined specs exceed namespace quota: " + strings.Join(r.lmsMoodleCtx.quotaExceeded, "; "),
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Namespace quota", func() {
+	var r *LMSMoodleReconciler
+
+	BeforeEach(func() {
+		r = &LMSMoodleReconciler{}
+		r.lmsMoodleCtx.lmsMoodle = newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		r.lmsMoodleCtx.lmsMoodle.SetName("site")
+		r.lmsMoodleCtx.lmsMoodle.SetUID("site-uid")
+		r.lmsMoodleCtx.namespaceName = "lms-site"
+		r.lmsMoodleCtx.networkPolicyBaseName = "lms-site"
+		r.lmsMoodleCtx.lmsMoodleTemplateSpec = map[string]interface{}{}
+		r.lmsMoodleCtx.spec = map[string]interface{}{}
+		r.lmsMoodleCtx.combinedMoodleSpec = map[string]interface{}{
+			"nginxResourceRequestsCpu":     "100m",
+			"nginxResourceRequestsMemory":  "64Mi",
+			"phpFpmSize":                   int64(2),
+			"phpFpmResourceRequestsCpu":    "500m",
+			"phpFpmResourceRequestsMemory": "1Gi",
+		}
+	})
+
+	define := func() error {
+		if err := r.defineNamespaceQuota(); err != nil {
+			return err
+		}
+		return r.defineNamespaceLimitRange()
+	}
+
+	It("should keep the format of quantities with headroom", func() {
+		for _, quantity := range []struct {
+			name     corev1.ResourceName
+			value    string
+			headroom int32
+			expected string
+		}{
+			{corev1.ResourceRequestsMemory, "1Gi", 50, "1536Mi"},
+			{corev1.ResourceRequestsMemory, "1Gi", 33, "1362Mi"},
+			{corev1.ResourceRequestsMemory, "1G", 50, "1500M"},
+			{corev1.ResourceRequestsCPU, "500m", 50, "750m"},
+		} {
+			withHeadroom := withHeadroom(quantity.name, resource.MustParse(quantity.value), quantity.headroom)
+			Expect(withHeadroom.String()).To(Equal(quantity.expected), quantity.value)
+		}
+	})
+
+	It("should calculate hard limits and generate a limit range with the smallest requests", func() {
+		r.lmsMoodleCtx.lmsMoodleTemplateSpec = map[string]interface{}{"namespaceQuota": map[string]interface{}{}}
+
+		Expect(define()).To(Succeed())
+		Expect(r.lmsMoodleCtx.namespaceQuotaOmit).To(BeFalse())
+		hard := r.lmsMoodleCtx.namespaceQuota.Spec.Hard
+		Expect(hard.Name(corev1.ResourceRequestsMemory, resource.BinarySI).String()).To(Equal("3168Mi"))
+		Expect(hard.Name(corev1.ResourceRequestsCPU, resource.DecimalSI).String()).To(Equal("1650m"))
+
+		Expect(r.lmsMoodleCtx.namespaceLimitRangeOmit).To(BeFalse())
+		limitRangeItem := r.lmsMoodleCtx.namespaceLimitRange.Spec.Limits[0]
+		Expect(limitRangeItem.DefaultRequest.Cpu().String()).To(Equal("100m"))
+		Expect(limitRangeItem.DefaultRequest.Memory().String()).To(Equal("64Mi"))
+		Expect(limitRangeItem.Default).To(BeNil())
+	})
+
+	It("should omit the limit range when neither it nor a quota is set", func() {
+		Expect(define()).To(Succeed())
+		Expect(r.lmsMoodleCtx.namespaceQuotaOmit).To(BeTrue())
+		Expect(r.lmsMoodleCtx.namespaceLimitRangeOmit).To(BeTrue())
+	})
+
+	It("should require limit range defaults for quota hard limits", func() {
+		r.lmsMoodleCtx.lmsMoodleTemplateSpec = map[string]interface{}{"namespaceQuota": map[string]interface{}{
+			"hard": map[string]interface{}{"limits.memory": "8Gi"},
+		}}
+		Expect(define()).To(MatchError(ContainSubstring("namespaceQuota hard limits.memory requires namespaceLimitRange default memory")))
+
+		r.lmsMoodleCtx.spec = map[string]interface{}{"namespaceLimitRange": map[string]interface{}{
+			"default":        map[string]interface{}{"memory": "512Mi"},
+			"defaultRequest": map[string]interface{}{"memory": "1Gi"},
+		}}
+		Expect(define()).To(Succeed())
+		limitRangeItem := r.lmsMoodleCtx.namespaceLimitRange.Spec.Limits[0]
+		Expect(limitRangeItem.Default.Memory().String()).To(Equal("512Mi"))
+		Expect(limitRangeItem.DefaultRequest.Memory().String()).To(Equal("512Mi"))
+
+		r.lmsMoodleCtx.spec = map[string]interface{}{"namespaceLimitRange": map[string]interface{}{"omit": true}}
+		Expect(define()).To(MatchError(ContainSubstring("requires namespaceLimitRange, which is omitted")))
+	})
+
+	Context("When omitted", func() {
+		var c client.Client
+
+		BeforeEach(func() {
+			scheme := newDependantsScheme()
+			controller := true
+			ownerReferences := []metav1.OwnerReference{{
+				APIVersion: lmsv1alpha1.GroupVersion.String(),
+				Kind:       "LMSMoodle",
+				Name:       "site",
+				UID:        "site-uid",
+				Controller: &controller,
+			}}
+			quota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "lms-site-quota", Namespace: "lms-site", OwnerReferences: ownerReferences}}
+			limitRange := &corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: "lms-site-limitrange", Namespace: "lms-site", OwnerReferences: ownerReferences}}
+			c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(quota, limitRange).Build()
+			r.Client = c
+			r.Scheme = scheme
+			r.Recorder = &record.FakeRecorder{}
+		})
+
+		countObjects := func() int {
+			quotas := &corev1.ResourceQuotaList{}
+			Expect(c.List(ctx, quotas)).To(Succeed())
+			limitRanges := &corev1.LimitRangeList{}
+			Expect(c.List(ctx, limitRanges)).To(Succeed())
+			return len(quotas.Items) + len(limitRanges.Items)
+		}
+
+		It("should not delete a quota and limit range not applied before", func() {
+			Expect(define()).To(Succeed())
+			Expect(r.reconcileNamespaceQuota(ctx)).To(Succeed())
+			Expect(countObjects()).To(Equal(2))
+
+			_, err := r.SetStatusNames()
+			Expect(err).NotTo(HaveOccurred())
+			names, _, _ := unstructured.NestedStringMap(r.lmsMoodleCtx.lmsMoodle.Object, "status", "names")
+			Expect(names).NotTo(HaveKey("resourceQuota"))
+			Expect(names).NotTo(HaveKey("limitRange"))
+		})
+
+		It("should delete a quota and limit range applied before, as recorded in status", func() {
+			Expect(unstructured.SetNestedStringMap(r.lmsMoodleCtx.lmsMoodle.Object, map[string]string{
+				"namespace":     "lms-site",
+				"base":          "lms-site",
+				"resourceQuota": "lms-site-quota",
+				"limitRange":    "lms-site-limitrange",
+			}, "status", "names")).To(Succeed())
+
+			Expect(define()).To(Succeed())
+			Expect(r.lmsMoodleCtx.namespaceQuotaApplied).To(BeTrue())
+			Expect(r.reconcileNamespaceQuota(ctx)).To(Succeed())
+			Expect(countObjects()).To(Equal(0))
+			Expect(r.lmsMoodleCtx.namespaceQuotaApplied).To(BeFalse())
+			Expect(r.lmsMoodleCtx.namespaceLimitRangeApplied).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,152 @@
+package lms
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+// Render returns the resources the reconciler would create for a LMSMoodle, without
+// reaching the cluster: namespace, unless it is a target one, default network policy,
+// resource quota, limit range and Moodle, Postgres, Keydb and NFS Ganesha resources.
+// The LMSMoodleTemplate referenced by the LMSMoodle is taken from lmsMoodleTemplates.
+// Specs are combined the same way as during reconcile, leaving out overrides not allowed
+// by the template, except for secret references, which are rendered redacted
+func Render(ctx context.Context, lmsMoodle *unstructured.Unstructured, lmsMoodleTemplates []*unstructured.Unstructured, namingPolicy NamingPolicy, propagationPolicy PropagationPolicy, namespacePolicy NamespacePolicy, moodleGVK, nfsGVK, keydbGVK, postgresGVK schema.GroupVersionKind) ([]client.Object, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := lmsv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	// stubs, so that nothing reaches the cluster: an empty in-memory client and
+	// a recorder dropping events
+	r := &LMSMoodleReconciler{
+		Client:            fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Scheme:            scheme,
+		Recorder:          &record.FakeRecorder{},
+		MoodleGVK:         moodleGVK,
+		NfsGVK:            nfsGVK,
+		KeydbGVK:          keydbGVK,
+		PostgresGVK:       postgresGVK,
+		NamingPolicy:      namingPolicy,
+		PropagationPolicy: propagationPolicy,
+		NamespacePolicy:   namespacePolicy,
+	}
+
+	r.lmsMoodleCtx.name = lmsMoodle.GetName()
+	r.lmsMoodleCtx.lmsMoodle = lmsMoodle.DeepCopy()
+	r.setLMSMoodleSpec()
+
+	for _, lmsMoodleTemplate := range lmsMoodleTemplates {
+		if lmsMoodleTemplate.GetName() == r.lmsMoodleCtx.lmsMoodleTemplateName {
+			r.lmsMoodleCtx.lmsMoodleTemplate = lmsMoodleTemplate.DeepCopy()
+		}
+	}
+	if r.lmsMoodleCtx.lmsMoodleTemplate == nil {
+		return nil, &LMSMoodleTemplateNotFoundError{r.lmsMoodleCtx.lmsMoodleTemplateName}
+	}
+
+	if err := r.prepareSite(ctx, prepareSteps{
+		setSiteLabels:         r.renderSiteLabels,
+		resolveDependantNames: r.renderDependantNames,
+		getSecretKey:          redactedSecretKey,
+	}); err != nil {
+		return nil, err
+	}
+	if r.lmsMoodleCtx.overridesRejected {
+		return nil, &OverridesRejectedError{r.lmsMoodleCtx.failedMessage}
+	}
+
+	return r.renderObjects()
+}
+
+// renderSiteLabels sets lms moodle labels and annotations, without applying them
+func (r *LMSMoodleReconciler) renderSiteLabels(_ context.Context) error {
+	_, _, err := r.defineSiteLabels()
+	return err
+}
+
+// renderDependantNames sets names of namespace and dependant resources kept in status
+// or from naming policy, without checking collisions
+func (r *LMSMoodleReconciler) renderDependantNames(_ context.Context) error {
+	namespaceName, baseName, found := r.statusNames()
+	if !found {
+		namespaceName, baseName = r.namingPolicy().Names(r.lmsMoodleCtx.name)
+		if r.lmsMoodleCtx.targetNamespace != "" {
+			namespaceName = r.lmsMoodleCtx.targetNamespace
+		}
+	}
+	r.setDependantNames(namespaceName, baseName)
+	return nil
+}
+
+// renderObjects returns namespace, unless it is a target one, default network policy, resource
+// quota, limit range, notify secret and dependant resources, with their specs as applied by reconcile. Should
+// be used once dependants are prepared
+func (r *LMSMoodleReconciler) renderObjects() ([]client.Object, error) {
+	var objs []client.Object
+	if r.lmsMoodleCtx.targetNamespace == "" {
+		r.lmsMoodleCtx.namespace.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+		objs = append(objs, r.lmsMoodleCtx.namespace)
+	}
+
+	if !r.lmsMoodleCtx.lmsMoodleNetpolOmit {
+		r.lmsMoodleCtx.lmsMoodleDefaultNetpol.SetGroupVersionKind(networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy"))
+		objs = append(objs, r.lmsMoodleCtx.lmsMoodleDefaultNetpol)
+	}
+	if !r.lmsMoodleCtx.namespaceQuotaOmit {
+		objs = append(objs, r.lmsMoodleCtx.namespaceQuota)
+	}
+	if !r.lmsMoodleCtx.namespaceLimitRangeOmit {
+		objs = append(objs, r.lmsMoodleCtx.namespaceLimitRange)
+	}
+	if r.lmsMoodleCtx.notifySecret != nil {
+		objs = append(objs, r.lmsMoodleCtx.notifySecret)
+	}
+
+	dependants := []struct {
+		present      bool
+		obj          *unstructured.Unstructured
+		combinedSpec map[string]interface{}
+		desiredState string
+	}{
+		{r.lmsMoodleCtx.hasPostgres, r.lmsMoodleCtx.postgres, r.lmsMoodleCtx.combinedPostgresSpec, r.lmsMoodleCtx.postgresDesiredState},
+		{r.lmsMoodleCtx.hasKeydb, r.lmsMoodleCtx.keydb, r.lmsMoodleCtx.combinedKeydbSpec, r.lmsMoodleCtx.keydbDesiredState},
+		{r.lmsMoodleCtx.hasNfs, r.lmsMoodleCtx.nfs, r.lmsMoodleCtx.combinedNfsSpec, r.lmsMoodleCtx.nfsDesiredState},
+		{true, r.lmsMoodleCtx.moodle, r.lmsMoodleCtx.combinedMoodleSpec, r.lmsMoodleCtx.moodleDesiredState},
+	}
+	for _, dependant := range dependants {
+		if !dependant.present {
+			continue
+		}
+		dependant.obj.Object["spec"] = dependant.combinedSpec
+		// Set suspended, as when suspending a dependant
+		if dependant.desiredState == lmsv1alpha1.SuspendedState {
+			if err := unstructured.SetNestedField(dependant.combinedSpec, "suspended", "cr_state"); err != nil {
+				return nil, fmt.Errorf("unable to set %s state: %w", dependant.obj.GetKind(), err)
+			}
+		}
+		objs = append(objs, dependant.obj)
+	}
+
+	return objs, nil
+}
+
+// redactedSecretKey returns a redacted value in place of a secret key
+func redactedSecretKey(_ context.Context, _ lmsv1alpha1.SecretKeySelector) (string, error) {
+	return RedactedValue, nil
+}
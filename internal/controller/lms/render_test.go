@@ -0,0 +1,71 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Render", func() {
+	lmsMoodleTemplate := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "lms.krestomat.io/v1alpha1",
+			"kind":       "LMSMoodleTemplate",
+			"metadata":   map[string]interface{}{"name": "template"},
+			"spec": map[string]interface{}{
+				"moodleSpec": map[string]interface{}{"moodleNewInstanceFullname": "Template"},
+			},
+		}}
+	}
+
+	It("should render resources of a LMSMoodle without reaching the cluster", func() {
+		lmsMoodle := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "lms.krestomat.io/v1alpha1",
+			"kind":       "LMSMoodle",
+			"metadata":   map[string]interface{}{"name": "site"},
+			"spec": map[string]interface{}{
+				"lmsMoodleTemplateName": "template",
+				"moodleSpec":            map[string]interface{}{"moodleNewInstanceFullname": "Site"},
+			},
+		}}
+
+		objs, err := Render(ctx, lmsMoodle, []*unstructured.Unstructured{lmsMoodleTemplate()}, DefaultNamingPolicy, DefaultPropagationPolicy, NamespacePolicy{}, MoodleGVK, NfsGVK, KeydbGVK, PostgresGVK)
+		Expect(err).NotTo(HaveOccurred())
+
+		var kinds []string
+		for _, obj := range objs {
+			kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+		}
+		Expect(kinds).To(Equal([]string{"Namespace", "NetworkPolicy", "Moodle"}))
+		moodle := objs[len(objs)-1].(*unstructured.Unstructured)
+		fullname, _, _ := unstructured.NestedString(moodle.Object, "spec", "moodleNewInstanceFullname")
+		Expect(fullname).To(Equal("Site"))
+	})
+
+	It("should fail when the template is not given", func() {
+		lmsMoodle := &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "site"},
+			"spec":     map[string]interface{}{"lmsMoodleTemplateName": "other"},
+		}}
+
+		_, err := Render(ctx, lmsMoodle, []*unstructured.Unstructured{lmsMoodleTemplate()}, DefaultNamingPolicy, DefaultPropagationPolicy, NamespacePolicy{}, MoodleGVK, NfsGVK, KeydbGVK, PostgresGVK)
+		Expect(err).To(MatchError(&LMSMoodleTemplateNotFoundError{"other"}))
+	})
+})
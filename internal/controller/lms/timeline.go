@@ -0,0 +1,169 @@
+package lms
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// StartTimelineOperation starts timing an operation in LMSMoodle status:
+// Provision when no operation has been timed yet, Resume when it was suspended and
+// Upgrade when its combined specs changed after being ready
+func (r *LMSMoodleReconciler) StartTimelineOperation(ctx context.Context) error {
+	log := log.FromContext(ctx)
+
+	status, _, _ := unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodle.Object, "status")
+	operation, _, _ := unstructured.NestedString(status, "timeline", "operation")
+	_, completedAtFound, _ := unstructured.NestedString(status, "timeline", "completedAt")
+	appliedSpecHash, _, _ := unstructured.NestedString(status, "appliedSpecHash")
+	state, _, _ := unstructured.NestedString(status, "state")
+
+	var startedAt time.Time
+	switch {
+	case operation == "":
+		operation = lmsv1alpha1.ProvisionOperation
+		startedAt = r.lmsMoodleCtx.lmsMoodle.GetCreationTimestamp().Time
+	case r.lmsMoodleCtx.moodleDesiredState == lmsv1alpha1.SuspendedState:
+		return nil
+	case state == lmsv1alpha1.SuspendedState:
+		operation = lmsv1alpha1.ResumeOperation
+		startedAt = time.Now()
+	case completedAtFound && appliedSpecHash != "" && appliedSpecHash != r.combinedSpecsHash():
+		operation = lmsv1alpha1.UpgradeOperation
+		startedAt = time.Now()
+	default:
+		return nil
+	}
+
+	log.V(1).Info("Timing LMSMoodle operation", "Operation", operation)
+	timeline := map[string]interface{}{
+		"operation": operation,
+		"startedAt": startedAt.UTC().Format(time.RFC3339),
+	}
+	// sites already settled before being timed are not observed
+	if operation == lmsv1alpha1.ProvisionOperation && isSettledState(state) {
+		timeline["completedAt"] = metav1.Now().UTC().Format(time.RFC3339)
+		timeline["completedState"] = state
+	}
+	// keep last completed operation
+	if completedAtFound {
+		lastTimeline, _, _ := unstructured.NestedMap(status, "timeline")
+		if err := unstructured.SetNestedMap(r.lmsMoodleCtx.lmsMoodle.Object, lastTimeline, "status", "lastTimeline"); err != nil {
+			return err
+		}
+	}
+	if err := unstructured.SetNestedMap(r.lmsMoodleCtx.lmsMoodle.Object, timeline, "status", "timeline"); err != nil {
+		return err
+	}
+	r.lmsMoodleCtx.timelineUpdated = true
+
+	return nil
+}
+
+// MarkTimelineApplied sets when a component was first applied during current operation
+func (r *LMSMoodleReconciler) MarkTimelineApplied(component string) error {
+	return r.markTimeline(component, "appliedAt")
+}
+
+// MarkTimelineReady sets when a component first became ready during current operation
+func (r *LMSMoodleReconciler) MarkTimelineReady(component string) error {
+	return r.markTimeline(component, "readyAt")
+}
+
+// MarkTimelineDependant sets when a dependant was first applied and,
+// if ready, when it first became ready during current operation
+func (r *LMSMoodleReconciler) MarkTimelineDependant(ctx context.Context, component string, dependantObj *unstructured.Unstructured) error {
+	if err := r.MarkTimelineApplied(component); err != nil {
+		return err
+	}
+	if ready, _ := getReadyStatus(ctx, dependantObj); ready {
+		return r.MarkTimelineReady(component)
+	}
+	return nil
+}
+
+// MarkTimelineNamespace sets when namespace was first applied and became active
+func (r *LMSMoodleReconciler) MarkTimelineNamespace(namespace *corev1.Namespace) error {
+	if err := r.MarkTimelineApplied("namespace"); err != nil {
+		return err
+	}
+	if namespace.Status.Phase == corev1.NamespaceActive {
+		return r.MarkTimelineReady("namespace")
+	}
+	return nil
+}
+
+// markTimeline sets a component timestamp in current operation timeline, if not set
+func (r *LMSMoodleReconciler) markTimeline(component string, field string) error {
+	operation, _, _ := unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "timeline", "operation")
+	if operation == "" {
+		return nil
+	}
+
+	if _, found, _ := unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "timeline", "components", component, field); found {
+		return nil
+	}
+
+	if err := unstructured.SetNestedField(r.lmsMoodleCtx.lmsMoodle.Object, metav1.Now().UTC().Format(time.RFC3339), "status", "timeline", "components", component, field); err != nil {
+		return err
+	}
+	r.lmsMoodleCtx.timelineUpdated = true
+
+	return nil
+}
+
+// CompleteTimelineOperation sets when LMSMoodle settled during current operation and,
+// if serving, observes the time to ready metric
+func (r *LMSMoodleReconciler) CompleteTimelineOperation(ctx context.Context, statusState string) error {
+	log := log.FromContext(ctx)
+
+	if !isSettledState(statusState) {
+		return nil
+	}
+
+	timeline, timelineFound, _ := unstructured.NestedMap(r.lmsMoodleCtx.lmsMoodle.Object, "status", "timeline")
+	if !timelineFound {
+		return nil
+	}
+	if _, completedAtFound := timeline["completedAt"]; completedAtFound {
+		return nil
+	}
+
+	completedAt := metav1.Now().UTC()
+	if err := unstructured.SetNestedField(r.lmsMoodleCtx.lmsMoodle.Object, completedAt.Format(time.RFC3339), "status", "timeline", "completedAt"); err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedField(r.lmsMoodleCtx.lmsMoodle.Object, statusState, "status", "timeline", "completedState"); err != nil {
+		return err
+	}
+	r.lmsMoodleCtx.timelineUpdated = true
+
+	// Moodle is not serving when suspended
+	if r.lmsMoodleCtx.moodleDesiredState == lmsv1alpha1.SuspendedState {
+		return nil
+	}
+	if err := r.MarkTimelineReady("moodle"); err != nil {
+		return err
+	}
+
+	operation, _ := timeline["operation"].(string)
+	startedAtString, _ := timeline["startedAt"].(string)
+	if startedAt, err := time.Parse(time.RFC3339, startedAtString); err == nil {
+		timeToReady := completedAt.Sub(startedAt)
+		timeToReadySeconds.WithLabelValues(r.lmsMoodleCtx.lmsMoodleTemplateName, strings.ToLower(operation)).Observe(timeToReady.Seconds())
+		log.Info("LMSMoodle is ready", "Operation", operation, "TimeToReady", timeToReady.String())
+	}
+
+	return nil
+}
+
+// isSettledState whether a state completes a timed operation
+func isSettledState(state string) bool {
+	return state == lmsv1alpha1.ReadyState || state == lmsv1alpha1.PartiallySuspendedState || state == lmsv1alpha1.SuspendedState
+}
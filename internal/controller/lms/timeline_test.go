@@ -0,0 +1,81 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Timeline", func() {
+	var r *LMSMoodleReconciler
+
+	BeforeEach(func() {
+		r = &LMSMoodleReconciler{}
+		r.lmsMoodleCtx.lmsMoodle = &unstructured.Unstructured{Object: map[string]interface{}{}}
+		r.lmsMoodleCtx.moodleDesiredState = lmsv1alpha1.ReadyState
+		r.lmsMoodleCtx.combinedMoodleSpec = map[string]interface{}{"moodleHost": "site.example.com"}
+	})
+
+	timelineField := func(fields ...string) string {
+		value, _, _ := unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, append([]string{"status"}, fields...)...)
+		return value
+	}
+
+	Context("When completing an operation", func() {
+		It("should complete on any settled state", func() {
+			for _, state := range []string{lmsv1alpha1.ReadyState, lmsv1alpha1.PartiallySuspendedState, lmsv1alpha1.SuspendedState} {
+				r.lmsMoodleCtx.lmsMoodle.Object = map[string]interface{}{}
+				Expect(r.StartTimelineOperation(context.Background())).To(Succeed())
+				Expect(timelineField("timeline", "operation")).To(Equal(lmsv1alpha1.ProvisionOperation))
+
+				Expect(r.CompleteTimelineOperation(context.Background(), "PostgresCreating")).To(Succeed())
+				Expect(timelineField("timeline", "completedAt")).To(BeEmpty())
+
+				Expect(r.CompleteTimelineOperation(context.Background(), state)).To(Succeed())
+				Expect(timelineField("timeline", "completedAt")).NotTo(BeEmpty(), "state %s", state)
+				Expect(timelineField("timeline", "completedState")).To(Equal(state))
+			}
+		})
+	})
+
+	Context("When starting a new operation", func() {
+		It("should keep the last completed operation", func() {
+			Expect(r.StartTimelineOperation(context.Background())).To(Succeed())
+			Expect(r.CompleteTimelineOperation(context.Background(), lmsv1alpha1.ReadyState)).To(Succeed())
+			Expect(unstructured.SetNestedField(r.lmsMoodleCtx.lmsMoodle.Object, lmsv1alpha1.ReadyState, "status", "state")).To(Succeed())
+			Expect(unstructured.SetNestedField(r.lmsMoodleCtx.lmsMoodle.Object, r.combinedSpecsHash(), "status", "appliedSpecHash")).To(Succeed())
+
+			// same combined specs, no new operation
+			Expect(r.StartTimelineOperation(context.Background())).To(Succeed())
+			Expect(timelineField("lastTimeline", "operation")).To(BeEmpty())
+
+			r.lmsMoodleCtx.combinedMoodleSpec["moodleHost"] = "new.example.com"
+			Expect(r.StartTimelineOperation(context.Background())).To(Succeed())
+			Expect(timelineField("timeline", "operation")).To(Equal(lmsv1alpha1.UpgradeOperation))
+			Expect(timelineField("timeline", "completedAt")).To(BeEmpty())
+			Expect(timelineField("lastTimeline", "operation")).To(Equal(lmsv1alpha1.ProvisionOperation))
+			Expect(timelineField("lastTimeline", "completedState")).To(Equal(lmsv1alpha1.ReadyState))
+		})
+	})
+})
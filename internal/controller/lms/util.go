@@ -2,17 +2,23 @@ package lms
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/imdario/mergo"
 	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -23,6 +29,9 @@ import (
 
 const (
 	OPERATORNAME string = "lms-moodle-operator"
+	// LegacyFieldManager is the field manager of resources created or patched by operator
+	// versions before server-side apply, named after the operator binary
+	LegacyFieldManager string = "manager"
 )
 
 // ReconcileCreate create resource if it does not exists. Otherwise it does nothing
@@ -56,6 +65,7 @@ func (r *LMSMoodleReconciler) ReconcileCreate(ctx context.Context, parentObj cli
 	}
 
 	log.Info("Resource created", "Resource", obj.GetObjectKind())
+	r.Recorder.Eventf(parentObj, corev1.EventTypeNormal, CreatedEventReason, "Created %s '%s'", objectKind(r.Scheme, obj), obj.GetName())
 	return nil
 }
 
@@ -70,16 +80,143 @@ func (r *LMSMoodleReconciler) ReconcileApply(ctx context.Context, parentObj clie
 		return err
 	}
 
+	// Whether a dependant is going to be created, from cache since dependants are watched.
+	// Other resources are not cached, so they are applied without checking
+	created := false
+	if _, isDependant := obj.(*unstructured.Unstructured); isDependant {
+		existingObj := obj.DeepCopyObject().(client.Object)
+		err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, existingObj)
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Failed to get resource", "Resource", obj.GetObjectKind())
+			return err
+		}
+		created = errors.IsNotFound(err)
+	}
+
 	// Apply resource
 	force := true
 	if err := r.Patch(ctx, obj, client.Apply, &client.PatchOptions{Force: &force, FieldManager: OPERATORNAME}); err != nil {
 		log.Error(err, "Failed to attempt patching changes", "Resource", obj.GetObjectKind())
+		r.Recorder.Eventf(parentObj, corev1.EventTypeWarning, ApplyFailedEventReason, "Failed to apply %s '%s': %s", objectKind(r.Scheme, obj), obj.GetName(), err.Error())
 		return err
 	}
 
+	if created {
+		log.Info("Resource created", "Resource", obj.GetObjectKind())
+		r.Recorder.Eventf(parentObj, corev1.EventTypeNormal, CreatedEventReason, "Created %s '%s'", objectKind(r.Scheme, obj), obj.GetName())
+	}
+
 	return nil
 }
 
+// ReconcileLegacyLabels moves ownership of labels of an applied resource from legacy field
+// manager to the one applying it, patching its managed fields. Otherwise, labels set by
+// operator versions before server-side apply would be kept once no longer applied. It
+// returns whether ownership was moved, so that the resource is applied again
+func (r *LMSMoodleReconciler) ReconcileLegacyLabels(ctx context.Context, obj client.Object, fieldManager string) (bool, error) {
+	log := log.FromContext(ctx)
+
+	managedFields, moved, err := moveLabelsOwnership(obj.GetManagedFields(), fieldManager)
+	if err != nil || !moved {
+		return false, err
+	}
+
+	original := obj.DeepCopyObject().(client.Object)
+	obj.SetManagedFields(managedFields)
+	if err := r.Patch(ctx, obj, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})); err != nil {
+		log.Error(err, "Failed to move ownership of legacy labels", "Resource", obj.GetObjectKind())
+		return false, err
+	}
+
+	log.Info("Ownership of legacy labels moved", "Resource", obj.GetObjectKind(), "FieldManager", fieldManager)
+	return true, nil
+}
+
+// moveLabelsOwnership returns managed fields with labels owned by legacy field manager
+// update operations moved to fieldManager apply operation, and whether any were moved
+func moveLabelsOwnership(managedFields []metav1.ManagedFieldsEntry, fieldManager string) ([]metav1.ManagedFieldsEntry, bool, error) {
+	movedLabels := map[string]interface{}{}
+	var movedManagedFields []metav1.ManagedFieldsEntry
+	applyEntryIndex := -1
+	apiVersion := ""
+	for _, entry := range managedFields {
+		if entry.Manager == fieldManager && entry.Operation == metav1.ManagedFieldsOperationApply && entry.Subresource == "" {
+			applyEntryIndex = len(movedManagedFields)
+		}
+		if entry.Manager != LegacyFieldManager || entry.Operation != metav1.ManagedFieldsOperationUpdate || entry.Subresource != "" || entry.FieldsV1 == nil {
+			movedManagedFields = append(movedManagedFields, entry)
+			continue
+		}
+
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return nil, false, err
+		}
+		labels, found, _ := unstructured.NestedMap(fields, "f:metadata", "f:labels")
+		if !found {
+			movedManagedFields = append(movedManagedFields, entry)
+			continue
+		}
+		for key, value := range labels {
+			movedLabels[key] = value
+		}
+		apiVersion = entry.APIVersion
+
+		// entries owning nothing else are dropped
+		unstructured.RemoveNestedField(fields, "f:metadata", "f:labels")
+		if metadata, _, _ := unstructured.NestedMap(fields, "f:metadata"); len(metadata) == 0 {
+			delete(fields, "f:metadata")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		raw, err := json.Marshal(fields)
+		if err != nil {
+			return nil, false, err
+		}
+		entry.FieldsV1 = &metav1.FieldsV1{Raw: raw}
+		movedManagedFields = append(movedManagedFields, entry)
+	}
+	if len(movedLabels) == 0 {
+		return managedFields, false, nil
+	}
+
+	if applyEntryIndex < 0 {
+		applyEntryIndex = len(movedManagedFields)
+		movedManagedFields = append(movedManagedFields, metav1.ManagedFieldsEntry{
+			Manager:    fieldManager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: apiVersion,
+			Time:       &metav1.Time{Time: time.Now()},
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte("{}")},
+		})
+	}
+	applyEntry := movedManagedFields[applyEntryIndex].DeepCopy()
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(applyEntry.FieldsV1.Raw, &fields); err != nil {
+		return nil, false, err
+	}
+	labels, _, _ := unstructured.NestedMap(fields, "f:metadata", "f:labels")
+	if labels == nil {
+		labels = map[string]interface{}{}
+	}
+	for key, value := range movedLabels {
+		labels[key] = value
+	}
+	if err := unstructured.SetNestedMap(fields, labels, "f:metadata", "f:labels"); err != nil {
+		return nil, false, err
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false, err
+	}
+	applyEntry.FieldsV1 = &metav1.FieldsV1{Raw: raw}
+	movedManagedFields[applyEntryIndex] = *applyEntry
+
+	return movedManagedFields, true, nil
+}
+
 func (r *LMSMoodleReconciler) ReconcileSetOwner(ctx context.Context, parentObj client.Object, obj client.Object) error {
 	log := log.FromContext(ctx)
 
@@ -99,7 +236,12 @@ func (r *LMSMoodleReconciler) ReconcileDeleteDependant(ctx context.Context, pare
 
 	log.V(1).Info("Deleting dependant resource", "Resource", obj.GetObjectKind())
 
-	if err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj); err != nil {
+	// Dependants are read from cache, since they are watched. Other resources, uncached
+	var reader client.Reader = r.Client
+	if _, isUnstructured := obj.(*unstructured.Unstructured); !isUnstructured && r.APIReader != nil {
+		reader = r.APIReader
+	}
+	if err := reader.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj); err != nil {
 		log.V(1).Info(err.Error(), "Dependant", obj.GetObjectKind())
 		return err
 	}
@@ -128,13 +270,35 @@ func (r *LMSMoodleReconciler) ReconcileDeleteDependant(ctx context.Context, pare
 	}
 
 	log.Info("Dependant resource set to be deleted", "Dependant", obj.GetObjectKind())
+	r.Recorder.Eventf(parentObj, corev1.EventTypeNormal, DeletedEventReason, "Deleted %s '%s'", objectKind(r.Scheme, obj), obj.GetName())
 	return nil
 }
 
+// targetNamespaceResources returns resources other than dependants that LMSMoodle places in
+// its namespace: default network policy, effective specs and state history ConfigMaps and,
+// once applied, namespace quota and limit range. The notify secret is garbage collected
+func (r *LMSMoodleReconciler) targetNamespaceResources() []client.Object {
+	objs := []client.Object{r.lmsMoodleCtx.lmsMoodleDefaultNetpol}
+	for _, configMapName := range []string{r.effectiveSpecsConfigMapName(), r.stateHistoryConfigMapName()} {
+		configMap := &corev1.ConfigMap{}
+		configMap.SetName(configMapName)
+		configMap.SetNamespace(r.lmsMoodleCtx.namespaceName)
+		objs = append(objs, configMap)
+	}
+	if r.lmsMoodleCtx.namespaceQuotaApplied {
+		objs = append(objs, r.lmsMoodleCtx.namespaceQuota)
+	}
+	if r.lmsMoodleCtx.namespaceLimitRangeApplied {
+		objs = append(objs, r.lmsMoodleCtx.namespaceLimitRange)
+	}
+	return objs
+}
+
 // finalizeLMSMoodle cleans up before deleting LMSMoodle
 func (r *LMSMoodleReconciler) finalizeLMSMoodle(ctx context.Context) (requeue bool, err error) {
 	log := log.FromContext(ctx)
 	log.Info("Finalizing")
+	r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, corev1.EventTypeNormal, FinalizingEventReason, "Deleting dependant resources")
 
 	// Delete moodle and inmediately requeue in order to wait for it to be completely be removed.
 	// By doing so, any dependant CR removal will be done after, and removal
@@ -184,14 +348,35 @@ func (r *LMSMoodleReconciler) finalizeLMSMoodle(ctx context.Context) (requeue bo
 		}
 	}
 
+	// Delete resources in target namespace, since it is not deleted along with LMSMoodle.
+	// Only those owned by it are deleted
+	if r.lmsMoodleCtx.targetNamespace != "" {
+		for _, obj := range r.targetNamespaceResources() {
+			if err := r.ReconcileDeleteDependant(ctx, r.lmsMoodleCtx.lmsMoodle, obj); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Resource in target namespace not deleted", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+				return false, err
+			}
+		}
+	}
+
 	if !requeue {
 		// Set terminated state
 		if _, err := r.SetFalseReadyCondition(ctx, lmsv1alpha1.TerminatedState, "Finalizer ended"); err != nil {
 			return false, err
 		}
+		previousStatusState, _, _ := unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "state")
 		if statusStateUpdated, err := SetStatusState(r.lmsMoodleCtx.lmsMoodle, lmsv1alpha1.TerminatedState); err != nil {
 			return false, err
 		} else if statusStateUpdated {
+			transitionTime := time.Now()
+			if err := r.AppendStateHistory(ctx, lmsv1alpha1.TerminatedState, transitionTime); err != nil {
+				return false, err
+			}
+			// notified before saving it, as any other state change
+			if err := r.notifyStateChange(ctx, previousStatusState, lmsv1alpha1.TerminatedState, transitionTime); err != nil {
+				log.Error(err, "unable to notify LMSMoodle '"+r.lmsMoodleCtx.name+"' state")
+				return false, err
+			}
 			if err := r.Status().Update(ctx, r.lmsMoodleCtx.lmsMoodle); err != nil {
 				log.Error(err, "Unable to update LMSMoodle '"+r.lmsMoodleCtx.name+"' state")
 				return false, err
@@ -199,6 +384,7 @@ func (r *LMSMoodleReconciler) finalizeLMSMoodle(ctx context.Context) (requeue bo
 		}
 
 		log.Info("Successfully finalized LMSMoodle")
+		r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, corev1.EventTypeNormal, FinalizedEventReason, "Dependant resources deleted")
 	}
 
 	return requeue, nil
@@ -221,10 +407,12 @@ func (r *LMSMoodleTemplateReconciler) finalizeLMSMoodleTemplate(ctx context.Cont
 	if sitesUsingLMSMoodleTemplate > 0 {
 		lmsMoodleTemplateNotFoundError := &LMSMoodleTemplateInUsedError{r.lmsMoodleTemplateCtx.lmsMoodleTemplate.GetName(), sitesUsingLMSMoodleTemplate}
 		log.Error(lmsMoodleTemplateNotFoundError, "Cannot delete LMSMoodleTemplate")
+		r.Recorder.Event(r.lmsMoodleTemplateCtx.lmsMoodleTemplate, corev1.EventTypeWarning, TemplateInUseEventReason, lmsMoodleTemplateNotFoundError.Error())
 		return lmsMoodleTemplateNotFoundError
 	}
 
 	log.Info("Successfully finalized LMSMoodleTemplate")
+	r.Recorder.Event(r.lmsMoodleTemplateCtx.lmsMoodleTemplate, corev1.EventTypeNormal, FinalizedEventReason, "LMSMoodleTemplate no longer in use")
 	return nil
 }
 
@@ -243,6 +431,7 @@ func (r *LMSMoodleReconciler) updateLMSMoodleStatus(ctx context.Context) (requeu
 	}
 
 	// Set state in lms moodle object
+	previousStatusState, _, _ := unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "state")
 	statusStateUpdated, err := SetStatusState(r.lmsMoodleCtx.lmsMoodle, statusState)
 	if err != nil {
 		log.Error(err, "unable to update LMSMoodle '"+r.lmsMoodleCtx.lmsMoodle.GetName()+"' state")
@@ -266,8 +455,55 @@ func (r *LMSMoodleReconciler) updateLMSMoodleStatus(ctx context.Context) (requeu
 		log.V(1).Info("LMSMoodle status from moodle not updated")
 	}
 
+	// Set component states in lms moodle object
+	componentStatesUpdated, err := SetStatusComponentStates(r.lmsMoodleCtx.lmsMoodle, r.getComponentStates(ctx))
+	if err != nil {
+		log.Error(err, "unable to update LMSMoodle '"+r.lmsMoodleCtx.lmsMoodle.GetName()+"' component states")
+		return true, err
+	}
+
+	// Set effective specs hash and fields by provenance in lms moodle object, unless they are not applied while paused
+	effectiveSpecsUpdated := false
+	if !r.lmsMoodleCtx.paused {
+		effectiveSpecsUpdated, err = SetStatusEffectiveSpecs(r.lmsMoodleCtx.lmsMoodle, r.getEffectiveSpecsStatus())
+		if err != nil {
+			log.Error(err, "unable to update LMSMoodle '"+r.lmsMoodleCtx.lmsMoodle.GetName()+"' effective specs")
+			return true, err
+		}
+	}
+
+	// Set observed generation in lms moodle object
+	observedGenerationUpdated, err := SetStatusObservedGeneration(r.lmsMoodleCtx.lmsMoodle)
+	if err != nil {
+		log.Error(err, "unable to update LMSMoodle '"+r.lmsMoodleCtx.lmsMoodle.GetName()+"' observed generation")
+		return true, err
+	}
+
+	// Set names of namespace and dependants in lms moodle object, unless they are read from it while paused
+	namesUpdated := false
+	if !r.lmsMoodleCtx.paused {
+		namesUpdated, err = r.SetStatusNames()
+		if err != nil {
+			log.Error(err, "unable to update LMSMoodle '"+r.lmsMoodleCtx.lmsMoodle.GetName()+"' names")
+			return true, err
+		}
+	}
+
+	// Set standard conditions in lms moodle object
+	standardConditionsUpdated, err := r.SetStandardConditions(ctx, statusState)
+	if err != nil {
+		log.Error(err, "unable to update LMSMoodle '"+r.lmsMoodleCtx.lmsMoodle.GetName()+"' conditions")
+		return true, err
+	}
+
+	// Complete timed operation once ready
+	if err := r.CompleteTimelineOperation(ctx, statusState); err != nil {
+		log.Error(err, "unable to update LMSMoodle '"+r.lmsMoodleCtx.lmsMoodle.GetName()+"' timeline")
+		return true, err
+	}
+
 	// If status not updated, return
-	if !statusStateUpdated && !moodleStatusUpdated {
+	if !statusStateUpdated && !moodleStatusUpdated && !componentStatesUpdated && !observedGenerationUpdated && !namesUpdated && !standardConditionsUpdated && !effectiveSpecsUpdated && !r.lmsMoodleCtx.timelineUpdated {
 		log.V(1).Info("LMSMoodle status not updated")
 		return false, nil
 	}
@@ -288,6 +524,49 @@ func (r *LMSMoodleReconciler) updateLMSMoodleStatus(ctx context.Context) (requeu
 		if _, err := r.SetFalseReadyCondition(ctx, statusState, "LMSMoodle is suspended"); err != nil {
 			return false, err
 		}
+	} else if statusState == lmsv1alpha1.PartiallySuspendedState {
+		requeue = false
+		// still ready while Moodle serves, such as when only its cronjob is suspended
+		if r.lmsMoodleCtx.moodleDesiredState == lmsv1alpha1.ReadyState {
+			if _, err := r.SetReadyCondition(ctx, "True", lmsv1alpha1.SuccessfulState, "LMSMoodle is ready, with suspended components"); err != nil {
+				return false, err
+			}
+		} else if _, err := r.SetFalseReadyCondition(ctx, statusState, "LMSMoodle has suspended components"); err != nil {
+			return false, err
+		}
+	} else if statusState == lmsv1alpha1.PausedState {
+		// keep ready condition as it was before pausing
+		requeue = false
+	} else if statusState == lmsv1alpha1.FailedState {
+		if _, err := r.SetFalseReadyCondition(ctx, r.lmsMoodleCtx.failedReason, r.lmsMoodleCtx.failedMessage); err != nil {
+			return false, err
+		}
+		if statusStateUpdated {
+			r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, corev1.EventTypeWarning, r.lmsMoodleCtx.failedReason, r.lmsMoodleCtx.failedMessage)
+		}
+	}
+
+	// Record state transition
+	if statusStateUpdated {
+		r.Recorder.Event(r.lmsMoodleCtx.lmsMoodle, stateEventType(statusState), StateChangedEventReason, stateChangedMessage(previousStatusState, statusState))
+	}
+
+	// Keep state change in history
+	transitionTime := time.Now()
+	if statusStateUpdated {
+		if err := r.AppendStateHistory(ctx, statusState, transitionTime); err != nil {
+			log.Error(err, "unable to update LMSMoodle '"+r.lmsMoodleCtx.name+"' state history")
+			return true, err
+		}
+	}
+
+	// Notify state change before saving it, unless paused, so that a state change whose
+	// event is not queued is not saved either, and is notified again on next reconcile
+	if statusStateUpdated && !r.lmsMoodleCtx.paused {
+		if err := r.notifyStateChange(ctx, previousStatusState, statusState, transitionTime); err != nil {
+			log.Error(err, "unable to notify LMSMoodle '"+r.lmsMoodleCtx.name+"' state")
+			return true, err
+		}
 	}
 
 	// Save status
@@ -309,6 +588,7 @@ func (r *LMSMoodleTemplateReconciler) updateLMSMoodleTemplateState(ctx context.C
 	state := r.setLMSMoodleTemplateState()
 
 	// set state in lms moodle object
+	previousState, _, _ := unstructured.NestedString(r.lmsMoodleTemplateCtx.lmsMoodleTemplate.Object, "status", "state")
 	stateUpdate, err := SetStatusState(r.lmsMoodleTemplateCtx.lmsMoodleTemplate, state)
 	if err != nil {
 		log.Error(err, "unable to update LMSMoodleTemplate '"+r.lmsMoodleTemplateCtx.lmsMoodleTemplate.GetName()+"' state")
@@ -320,6 +600,9 @@ func (r *LMSMoodleTemplateReconciler) updateLMSMoodleTemplateState(ctx context.C
 		return nil
 	}
 
+	// record state transition
+	r.Recorder.Event(r.lmsMoodleTemplateCtx.lmsMoodleTemplate, stateEventType(state), StateChangedEventReason, stateChangedMessage(previousState, state))
+
 	// save status
 	if err := r.Status().Update(ctx, r.lmsMoodleTemplateCtx.lmsMoodleTemplate); err != nil {
 		log.Error(err, "Unable to update LMSMoodleTemplate '"+r.lmsMoodleTemplateCtx.lmsMoodleTemplate.GetName()+"' state")
@@ -360,6 +643,92 @@ func getReadyStatus(ctx context.Context, obj *unstructured.Unstructured) (status
 	return status, err
 }
 
+// waitForDependantReady updates LMSMoodle status while waiting for a dependant to be ready
+// Once the dependant readiness timeout, if any, has passed, LMSMoodle is set as failed.
+// It requeues with exponential backoff on each attempt
+func (r *LMSMoodleReconciler) waitForDependantReady(ctx context.Context, dependantObj *unstructured.Unstructured, component string) (requeue bool, err error) {
+	log := log.FromContext(ctx)
+
+	// requeue delay doubles on each attempt waiting for the dependant, up to a maximum
+	r.lmsMoodleCtx.requeueAfter = readinessRequeueAfter(r.readinessAttempt(component))
+
+	// time waiting since dependant was created or last transitioned its ready condition
+	waitingSince := dependantObj.GetCreationTimestamp().Time
+	readyCondition, readyConditionFound, _ := getConditionByType(dependantObj, ReadyConditionType)
+	if readyConditionFound {
+		if lastTransitionTime, ok := readyCondition["lastTransitionTime"].(string); ok {
+			if parsedTime, err := time.Parse(time.RFC3339, lastTransitionTime); err == nil {
+				waitingSince = parsedTime
+			}
+		}
+	}
+	if waitingSince.IsZero() {
+		waitingSince = time.Now()
+	}
+	elapsed := time.Since(waitingSince)
+
+	if timeout := r.getReadinessTimeout(strings.ToLower(component)); timeout > 0 {
+		if elapsed >= timeout {
+			dependantMessage, _ := readyCondition["message"].(string)
+			r.lmsMoodleCtx.failedReason = component + "ReadinessTimeout"
+			r.lmsMoodleCtx.failedMessage = fmt.Sprintf("%s not ready after %s", component, timeout)
+			if dependantMessage != "" {
+				r.lmsMoodleCtx.failedMessage += ": " + dependantMessage
+			}
+			log.Info("Dependant readiness timeout", "Component", component, "Timeout", timeout.String())
+		} else if untilTimeout := timeout - elapsed; untilTimeout < r.lmsMoodleCtx.requeueAfter {
+			// requeue right after timeout
+			r.lmsMoodleCtx.requeueAfter = untilTimeout
+		}
+	}
+
+	return r.updateLMSMoodleStatus(ctx)
+}
+
+// getReadinessTimeout returns readiness timeout of a component from LMSMoodle spec or,
+// if not set, from LMSMoodleTemplate spec. It returns zero if no timeout is set
+func (r *LMSMoodleReconciler) getReadinessTimeout(component string) time.Duration {
+	for _, spec := range []map[string]interface{}{r.lmsMoodleCtx.spec, r.lmsMoodleCtx.lmsMoodleTemplateSpec} {
+		if timeoutString, found, _ := unstructured.NestedString(spec, "readinessTimeouts", component); found {
+			if timeout, err := time.ParseDuration(timeoutString); err == nil {
+				return timeout
+			}
+		}
+	}
+	return 0
+}
+
+// readinessAttempt counts an attempt waiting for a lms moodle component to be ready
+// and returns the number of attempts so far. Attempts are counted until every component
+// is ready, so that they keep growing even if the component ready condition flaps
+func (r *LMSMoodleReconciler) readinessAttempt(component string) int {
+	attempts, _ := r.readinessAttempts.LoadOrStore(r.lmsMoodleCtx.name+"/"+component, new(atomic.Int32))
+	return int(attempts.(*atomic.Int32).Add(1))
+}
+
+// forgetReadinessAttempts forgets attempts waiting for components of a lms moodle
+func (r *LMSMoodleReconciler) forgetReadinessAttempts(name string) {
+	r.readinessAttempts.Range(func(key, _ any) bool {
+		if strings.HasPrefix(key.(string), name+"/") {
+			r.readinessAttempts.Delete(key)
+		}
+		return true
+	})
+}
+
+// readinessRequeueAfter returns requeue delay based on attempts waiting for a dependant
+// Delay doubles from base delay on each attempt until reaching the maximum delay
+func readinessRequeueAfter(attempts int) time.Duration {
+	delay := ReadinessRequeueBaseDelay
+	for i := 1; i < attempts && delay < ReadinessRequeueMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > ReadinessRequeueMaxDelay {
+		delay = ReadinessRequeueMaxDelay
+	}
+	return delay
+}
+
 // getReadyReason return string from ready reason condition
 func getReadyReason(ctx context.Context, obj *unstructured.Unstructured) (reason string, err error) {
 	log := log.FromContext(ctx)
@@ -396,15 +765,23 @@ func getReadyReason(ctx context.Context, obj *unstructured.Unstructured) (reason
 func (r *LMSMoodleReconciler) getStatusState(ctx context.Context) (state string, err error) {
 	log := log.FromContext(ctx)
 
-	expectedStatusState := lmsv1alpha1.SuccessfulState
-	isSuspendedDesiredState := r.lmsMoodleCtx.desiredState == lmsv1alpha1.SuspendedState
+	// Feed state metrics once state is defined
+	defer func() {
+		lmsMoodleMetrics.SetState(r.lmsMoodleCtx.name, r.lmsMoodleCtx.lmsMoodleTemplateName, state, r.lmsMoodleCtx.desiredState, r.getComponentsReady(ctx))
+	}()
 
-	if isSuspendedDesiredState {
-		expectedStatusState = lmsv1alpha1.SuspendedState
+	// Paused, dependants are left as they are
+	if r.lmsMoodleCtx.paused {
+		state = lmsv1alpha1.PausedState
+		return state, err
 	}
 
+	isSuspendedDesiredState := r.lmsMoodleCtx.desiredState == lmsv1alpha1.SuspendedState
+
 	if isSuspendedDesiredState {
 		state = lmsv1alpha1.SuspendedState
+	} else if r.hasSuspendedComponent() {
+		state = lmsv1alpha1.PartiallySuspendedState
 	} else {
 		state = lmsv1alpha1.ReadyState
 	}
@@ -415,6 +792,12 @@ func (r *LMSMoodleReconciler) getStatusState(ctx context.Context) (state string,
 		return state, err
 	}
 
+	// Failed, a dependant did not become ready in time
+	if r.lmsMoodleCtx.failedReason != "" {
+		state = lmsv1alpha1.FailedState
+		return state, err
+	}
+
 	if r.lmsMoodleCtx.hasPostgres {
 		// get postgres ready condition
 		var postgresState string
@@ -422,9 +805,9 @@ func (r *LMSMoodleReconciler) getStatusState(ctx context.Context) (state string,
 			log.Error(err, "Postgres ready reason error")
 		}
 
-		if postgresState != "" && postgresState != expectedStatusState {
+		if postgresState != "" && postgresState != expectedComponentStatusState(r.lmsMoodleCtx.postgresDesiredState) {
 			state = "Postgres" + postgresState
-			if isSuspendedDesiredState {
+			if r.lmsMoodleCtx.postgresDesiredState == lmsv1alpha1.SuspendedState {
 				state = "Suspending" + state
 			} else {
 				return state, err
@@ -439,9 +822,9 @@ func (r *LMSMoodleReconciler) getStatusState(ctx context.Context) (state string,
 			log.Error(err, "Keydb ready reason error")
 		}
 
-		if keydbState != "" && keydbState != expectedStatusState {
+		if keydbState != "" && keydbState != expectedComponentStatusState(r.lmsMoodleCtx.keydbDesiredState) {
 			state = "Keydb" + keydbState
-			if isSuspendedDesiredState {
+			if r.lmsMoodleCtx.keydbDesiredState == lmsv1alpha1.SuspendedState {
 				state = "Suspending" + state
 			} else {
 				return state, err
@@ -456,9 +839,9 @@ func (r *LMSMoodleReconciler) getStatusState(ctx context.Context) (state string,
 			log.Error(err, "Nfs ready reason error")
 		}
 
-		if nfsState != "" && nfsState != expectedStatusState {
+		if nfsState != "" && nfsState != expectedComponentStatusState(r.lmsMoodleCtx.nfsDesiredState) {
 			state = "Nfs" + nfsState
-			if isSuspendedDesiredState {
+			if r.lmsMoodleCtx.nfsDesiredState == lmsv1alpha1.SuspendedState {
 				state = "Suspending" + state
 			} else {
 				return state, err
@@ -472,9 +855,9 @@ func (r *LMSMoodleReconciler) getStatusState(ctx context.Context) (state string,
 		log.Error(err, "Moodle ready reason error")
 	}
 
-	if moodleState != "" && moodleState != expectedStatusState {
+	if moodleState != "" && moodleState != expectedComponentStatusState(r.lmsMoodleCtx.moodleDesiredState) {
 		state = "Moodle" + moodleState
-		if isSuspendedDesiredState {
+		if r.lmsMoodleCtx.moodleDesiredState == lmsv1alpha1.SuspendedState {
 			state = "Suspending" + state
 		} else {
 			return state, err
@@ -484,17 +867,197 @@ func (r *LMSMoodleReconciler) getStatusState(ctx context.Context) (state string,
 	return state, err
 }
 
-// setNotifyUUID defines lms moodle uuid if notifying status to an endpoint
+// expectedComponentStatusState returns the ready reason expected from a dependant
+// according to its desired state
+func expectedComponentStatusState(desiredState string) string {
+	if desiredState == lmsv1alpha1.SuspendedState {
+		return lmsv1alpha1.SuspendedState
+	}
+	return lmsv1alpha1.SuccessfulState
+}
+
+// hasSuspendedComponent whether any present component has suspended as desired state
+func (r *LMSMoodleReconciler) hasSuspendedComponent() bool {
+	return r.lmsMoodleCtx.moodleDesiredState == lmsv1alpha1.SuspendedState ||
+		r.lmsMoodleCtx.moodleCronDesiredState == lmsv1alpha1.SuspendedState ||
+		(r.lmsMoodleCtx.hasPostgres && r.lmsMoodleCtx.postgresDesiredState == lmsv1alpha1.SuspendedState) ||
+		(r.lmsMoodleCtx.hasKeydb && r.lmsMoodleCtx.keydbDesiredState == lmsv1alpha1.SuspendedState) ||
+		(r.lmsMoodleCtx.hasNfs && r.lmsMoodleCtx.nfsDesiredState == lmsv1alpha1.SuspendedState)
+}
+
+// setComponentDesiredStates defines the desired state of each component
+// Every component is suspended when LMSMoodle desired state is suspended. Otherwise,
+// components follow componentStates spec, keeping Postgres, Keydb and NFS Ganesha ready
+// while Moodle is ready. Should be used once combinedMoodleSpec is set
+func (r *LMSMoodleReconciler) setComponentDesiredStates(ctx context.Context) error {
+	log := log.FromContext(ctx)
+
+	componentDesiredState := func(component string) string {
+		if r.lmsMoodleCtx.desiredState == lmsv1alpha1.SuspendedState {
+			return lmsv1alpha1.SuspendedState
+		}
+		if state, _, _ := unstructured.NestedString(r.lmsMoodleCtx.spec, "componentStates", component); state == lmsv1alpha1.SuspendedState {
+			return lmsv1alpha1.SuspendedState
+		}
+		return lmsv1alpha1.ReadyState
+	}
+
+	r.lmsMoodleCtx.moodleDesiredState = componentDesiredState("moodle")
+	r.lmsMoodleCtx.moodleCronDesiredState = componentDesiredState("moodleCron")
+	r.lmsMoodleCtx.postgresDesiredState = componentDesiredState("postgres")
+	r.lmsMoodleCtx.keydbDesiredState = componentDesiredState("keydb")
+	r.lmsMoodleCtx.nfsDesiredState = componentDesiredState("nfs")
+
+	// Moodle depends on Postgres, Keydb and NFS Ganesha. They cannot be suspended while Moodle is ready
+	var conflicts []string
+	if r.lmsMoodleCtx.moodleDesiredState == lmsv1alpha1.ReadyState {
+		if r.lmsMoodleCtx.postgresDesiredState == lmsv1alpha1.SuspendedState {
+			r.lmsMoodleCtx.postgresDesiredState = lmsv1alpha1.ReadyState
+			conflicts = append(conflicts, "Postgres")
+		}
+		if r.lmsMoodleCtx.keydbDesiredState == lmsv1alpha1.SuspendedState {
+			r.lmsMoodleCtx.keydbDesiredState = lmsv1alpha1.ReadyState
+			conflicts = append(conflicts, "Keydb")
+		}
+		if r.lmsMoodleCtx.nfsDesiredState == lmsv1alpha1.SuspendedState {
+			r.lmsMoodleCtx.nfsDesiredState = lmsv1alpha1.ReadyState
+			conflicts = append(conflicts, "Nfs")
+		}
+	}
+
+	if len(conflicts) > 0 {
+		message := strings.Join(conflicts, ", ") + " cannot be suspended while Moodle is ready"
+		log.Info(message)
+		if _, err := SetCondition(r.lmsMoodleCtx.lmsMoodle, map[string]interface{}{
+			"type":    ComponentStatesConditionType,
+			"status":  "False",
+			"reason":  "DependencyOrder",
+			"message": message,
+		}); err != nil {
+			return err
+		}
+	} else if _, err := SetCondition(r.lmsMoodleCtx.lmsMoodle, map[string]interface{}{
+		"type":    ComponentStatesConditionType,
+		"status":  "True",
+		"reason":  "Valid",
+		"message": "Component states are valid",
+	}); err != nil {
+		return err
+	}
+
+	// suspend Moodle cronjob
+	if r.lmsMoodleCtx.moodleCronDesiredState == lmsv1alpha1.SuspendedState {
+		if err := unstructured.SetNestedField(r.lmsMoodleCtx.combinedMoodleSpec, true, "moodleCronjobSuspend"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getComponentsReady returns whether each present component is ready, except Moodle cronjob
+func (r *LMSMoodleReconciler) getComponentsReady(ctx context.Context) map[string]bool {
+	componentsReady := map[string]bool{}
+	for component, status := range r.getComponentStates(ctx) {
+		if component != "moodleCron" {
+			componentsReady[component], _ = status.(map[string]interface{})["ready"].(bool)
+		}
+	}
+	return componentsReady
+}
+
+// dependantStatus returns the observed status of a dependant from its ready condition
+func dependantStatus(dependantObj *unstructured.Unstructured) map[string]interface{} {
+	status := map[string]interface{}{
+		"ready": false,
+	}
+
+	status["name"] = dependantObj.GetName()
+	status["namespace"] = dependantObj.GetNamespace()
+
+	if readyCondition, readyConditionFound, _ := getConditionByType(dependantObj, ReadyConditionType); readyConditionFound {
+		status["ready"] = readyCondition["status"] == "True"
+		for _, key := range []string{"reason", "message", "lastTransitionTime"} {
+			if value, ok := readyCondition[key].(string); ok && value != "" {
+				status[key] = value
+			}
+		}
+	}
+
+	if observedGeneration, observedGenerationFound, _ := unstructured.NestedInt64(dependantObj.Object, "status", "observedGeneration"); observedGenerationFound {
+		status["observedGeneration"] = observedGeneration
+	}
+
+	return status
+}
+
+// getComponentStates returns the state and observed status of each present component
+func (r *LMSMoodleReconciler) getComponentStates(ctx context.Context) map[string]interface{} {
+	componentStates := make(map[string]interface{})
+
+	componentStatus := func(obj *unstructured.Unstructured) map[string]interface{} {
+		status := dependantStatus(obj)
+		status["state"], _ = getReadyReason(ctx, obj)
+		if status["state"] == lmsv1alpha1.SuccessfulState {
+			status["state"] = lmsv1alpha1.ReadyState
+		}
+		return status
+	}
+
+	moodleStatus := componentStatus(r.lmsMoodleCtx.moodle)
+	componentStates["moodle"] = moodleStatus
+	previousMoodleCronState, _, _ := unstructured.NestedString(r.lmsMoodleCtx.lmsMoodle.Object, "status", "componentStates", "moodleCron", "state")
+	moodleCronState := moodleCronState(r.lmsMoodleCtx.moodle, moodleStatus["state"].(string), previousMoodleCronState)
+	componentStates["moodleCron"] = map[string]interface{}{
+		"state": moodleCronState,
+		"ready": moodleCronState == lmsv1alpha1.ReadyState,
+	}
+	if r.lmsMoodleCtx.hasPostgres {
+		componentStates["postgres"] = componentStatus(r.lmsMoodleCtx.postgres)
+	}
+	if r.lmsMoodleCtx.hasKeydb {
+		componentStates["keydb"] = componentStatus(r.lmsMoodleCtx.keydb)
+	}
+	if r.lmsMoodleCtx.hasNfs {
+		componentStates["nfs"] = componentStatus(r.lmsMoodleCtx.nfs)
+	}
+
+	return componentStates
+}
+
+// moodleCronState returns Moodle cronjob state observed from Moodle dependant: the state of
+// Moodle while not ready, otherwise whether its cronjob is suspended. Until Moodle observes
+// its current generation, the previous cronjob state is kept
+func moodleCronState(moodle *unstructured.Unstructured, moodleState string, previousState string) string {
+	if moodleState != lmsv1alpha1.ReadyState {
+		return moodleState
+	}
+
+	observedGeneration, observedGenerationFound, _ := unstructured.NestedInt64(moodle.Object, "status", "observedGeneration")
+	if observedGenerationFound && observedGeneration < moodle.GetGeneration() && previousState != "" {
+		return previousState
+	}
+
+	if suspended, _, _ := unstructured.NestedBool(moodle.Object, "spec", "moodleCronjobSuspend"); suspended {
+		return lmsv1alpha1.SuspendedState
+	}
+	return lmsv1alpha1.ReadyState
+}
+
+// setNotifyUUID defines lms moodle uuid if notifying status to an endpoint,
+// on creation or termination
 // Should be used once combinedMoodleSpec is set
 // By default, lms moodle name is used as UUID
 func (r *LMSMoodleReconciler) setNotifyUUID() error {
-	// whether it has to notify status to a url
-	_, lmsMoodleRoutineStatusCrNotifyFound, _ := unstructured.NestedMap(r.lmsMoodleCtx.combinedMoodleSpec, "routineStatusCrNotify")
-	if lmsMoodleRoutineStatusCrNotifyFound {
-		_, lmsMoodleRoutineStatusCrNotifyUuidFound, _ := unstructured.NestedMap(r.lmsMoodleCtx.combinedMoodleSpec, "routineStatusCrNotify", "uuid")
-		if !lmsMoodleRoutineStatusCrNotifyUuidFound {
+	for _, notifyKey := range []string{"routineStatusCrNotify", "routineStatusCrNotifyTermination"} {
+		// whether it has to notify status to a url
+		_, lmsMoodleRoutineStatusCrNotifyFound, _ := unstructured.NestedMap(r.lmsMoodleCtx.combinedMoodleSpec, notifyKey)
+		if !lmsMoodleRoutineStatusCrNotifyFound {
+			continue
+		}
+		if uuid, _, _ := unstructured.NestedString(r.lmsMoodleCtx.combinedMoodleSpec, notifyKey, "uuid"); uuid == "" {
 			// set uuid to notify about
-			if err := unstructured.SetNestedField(r.lmsMoodleCtx.combinedMoodleSpec, r.lmsMoodleCtx.name, "routineStatusCrNotify", "uuid"); err != nil {
+			if err := unstructured.SetNestedField(r.lmsMoodleCtx.combinedMoodleSpec, r.lmsMoodleCtx.name, notifyKey, "uuid"); err != nil {
 				return err
 			}
 		}
@@ -502,6 +1065,152 @@ func (r *LMSMoodleReconciler) setNotifyUUID() error {
 	return nil
 }
 
+// resolveNotifySecrets copies notify header and jwt secret values, read with getSecretKey, to the
+// notify secret in LMSMoodle namespace, and references it from Moodle spec instead.
+// Should be used once combinedMoodleSpec is set
+func (r *LMSMoodleReconciler) resolveNotifySecrets(ctx context.Context, getSecretKey func(context.Context, lmsv1alpha1.SecretKeySelector) (string, error)) error {
+	data := map[string][]byte{}
+	for _, notifyKey := range []string{"routineStatusCrNotify", "routineStatusCrNotifyTermination"} {
+		notify, notifyFound, _ := unstructured.NestedMap(r.lmsMoodleCtx.combinedMoodleSpec, notifyKey)
+		if !notifyFound {
+			continue
+		}
+
+		// headers from secrets
+		headersFrom, _, _ := unstructured.NestedSlice(notify, "headersFrom")
+		for i, headerU := range headersFrom {
+			header := lmsv1alpha1.RoutineStatusCrNotifyHeaderFrom{}
+			headerMap, _ := headerU.(map[string]interface{})
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(headerMap, &header); err != nil {
+				return err
+			}
+			key := notifyKey + ".header." + strconv.Itoa(i)
+			if err := r.copySecretKey(ctx, getSecretKey, header.SecretKeyRef, data, key); err != nil {
+				return err
+			}
+			headersFrom[i] = map[string]interface{}{
+				"name":         header.Name,
+				"secretKeyRef": r.notifySecretKeyRef(key),
+			}
+		}
+		if len(headersFrom) > 0 {
+			notify["headersFrom"] = headersFrom
+		}
+
+		// jwt secret
+		if jwtSecretRefU, jwtSecretRefFound, _ := unstructured.NestedMap(notify, "jwtSecretRef"); jwtSecretRefFound {
+			jwtSecretRef := lmsv1alpha1.SecretKeySelector{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(jwtSecretRefU, &jwtSecretRef); err != nil {
+				return err
+			}
+			key := notifyKey + ".jwtSecret"
+			if err := r.copySecretKey(ctx, getSecretKey, jwtSecretRef, data, key); err != nil {
+				return err
+			}
+			notify["jwtSecretRef"] = r.notifySecretKeyRef(key)
+		}
+
+		if err := unstructured.SetNestedMap(r.lmsMoodleCtx.combinedMoodleSpec, notify, notifyKey); err != nil {
+			return err
+		}
+	}
+
+	r.lmsMoodleCtx.notifySecret = nil
+	if len(data) > 0 {
+		r.lmsMoodleCtx.notifySecret = &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: r.notifySecretName(), Namespace: r.lmsMoodleCtx.namespaceName},
+			Data:       data,
+		}
+	}
+	return nil
+}
+
+// copySecretKey copies the value of a secret key, read with getSecretKey, to data. The secret
+// must be in the namespace created for the LMSMoodle or in one of the secret namespaces of
+// namespace policy, so that LMSMoodle owners can not read other secrets. Target namespaces,
+// which may be any existing namespace, must be in secret namespaces too
+func (r *LMSMoodleReconciler) copySecretKey(ctx context.Context, getSecretKey func(context.Context, lmsv1alpha1.SecretKeySelector) (string, error), secretKeySelector lmsv1alpha1.SecretKeySelector, data map[string][]byte, key string) error {
+	ownNamespace := r.lmsMoodleCtx.targetNamespace == "" && secretKeySelector.Namespace == r.lmsMoodleCtx.namespaceName
+	if !ownNamespace && !slices.Contains(r.NamespacePolicy.SecretNamespaces, secretKeySelector.Namespace) {
+		return fmt.Errorf("secret '%s/%s' must be in the namespace created for LMSMoodle or in an allowed secret namespace", secretKeySelector.Namespace, secretKeySelector.Name)
+	}
+
+	value, err := getSecretKey(ctx, secretKeySelector)
+	if err != nil {
+		return err
+	}
+	data[key] = []byte(value)
+	return nil
+}
+
+// notifySecretKeyRef returns a reference to a key of the notify secret
+func (r *LMSMoodleReconciler) notifySecretKeyRef(key string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":      r.notifySecretName(),
+		"namespace": r.lmsMoodleCtx.namespaceName,
+		"key":       key,
+	}
+}
+
+// notifySecretName returns name of the secret holding status notify values. In a
+// target namespace, it is prefixed by LMSMoodle base name, since the namespace may be shared
+func (r *LMSMoodleReconciler) notifySecretName() string {
+	if r.lmsMoodleCtx.targetNamespace != "" {
+		return r.lmsMoodleCtx.networkPolicyBaseName + "-" + NotifySecretName
+	}
+	return NotifySecretName
+}
+
+// reconcileNotifySecret applies the notify secret, if any. Otherwise, its values are removed
+// while applied Moodle spec still references it. It is not deleted, but garbage collected
+// along with LMSMoodle, so that secrets are never deleted by the operator
+func (r *LMSMoodleReconciler) reconcileNotifySecret(ctx context.Context) error {
+	if r.lmsMoodleCtx.notifySecret != nil {
+		return r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, r.lmsMoodleCtx.notifySecret)
+	}
+
+	moodle := newUnstructuredObject(r.MoodleGVK)
+	if err := r.Get(ctx, types.NamespacedName{Name: r.lmsMoodleCtx.moodle.GetName(), Namespace: r.lmsMoodleCtx.moodle.GetNamespace()}, moodle); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	referenced := false
+	for _, notifyKey := range []string{"routineStatusCrNotify", "routineStatusCrNotifyTermination"} {
+		_, headersFromFound, _ := unstructured.NestedFieldNoCopy(moodle.Object, "spec", notifyKey, "headersFrom")
+		_, jwtSecretRefFound, _ := unstructured.NestedFieldNoCopy(moodle.Object, "spec", notifyKey, "jwtSecretRef")
+		referenced = referenced || headersFromFound || jwtSecretRefFound
+	}
+	if !referenced {
+		return nil
+	}
+
+	notifySecret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: r.notifySecretName(), Namespace: r.lmsMoodleCtx.namespaceName},
+	}
+	return r.ReconcileApply(ctx, r.lmsMoodleCtx.lmsMoodle, notifySecret)
+}
+
+// getSecretKey returns the value of a secret key, reading it uncached
+func (r *LMSMoodleReconciler) getSecretKey(ctx context.Context, secretKeySelector lmsv1alpha1.SecretKeySelector) (string, error) {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: secretKeySelector.Name, Namespace: secretKeySelector.Namespace}, secret); err != nil {
+		return "", fmt.Errorf("unable to get secret '%s/%s': %w", secretKeySelector.Namespace, secretKeySelector.Name, err)
+	}
+
+	value, found := secret.Data[secretKeySelector.Key]
+	if !found {
+		return "", fmt.Errorf("secret '%s/%s' has no key '%s'", secretKeySelector.Namespace, secretKeySelector.Name, secretKeySelector.Key)
+	}
+
+	return string(value), nil
+}
+
 // setLMSMoodleTemplateState defines LMSMoodleTemplate state value
 // return state string
 func (r *LMSMoodleTemplateReconciler) setLMSMoodleTemplateState() string {
@@ -533,6 +1242,40 @@ func SetStatusState(objU *unstructured.Unstructured, state string) (bool, error)
 	return updateState, nil
 }
 
+// SetStatusComponentStates set status component states key in unstructure object
+// It returns a bool flag if component states were updated, and
+// any error
+func SetStatusComponentStates(objU *unstructured.Unstructured, componentStates map[string]interface{}) (bool, error) {
+	return setNestedFieldIfChanged(objU, componentStates, "status", "componentStates")
+}
+
+// SetStatusObservedGeneration set status observed generation from object generation
+// It returns a bool flag if observed generation was updated, and
+// any error
+func SetStatusObservedGeneration(objU *unstructured.Unstructured) (bool, error) {
+	return setNestedFieldIfChanged(objU, objU.GetGeneration(), "status", "observedGeneration")
+}
+
+// setNestedFieldIfChanged set a nested field in unstructure object only if its value differs
+// It returns a bool flag if field was updated, and
+// any error
+func setNestedFieldIfChanged(objU *unstructured.Unstructured, value interface{}, fields ...string) (bool, error) {
+	objValue, _, objValueErr := unstructured.NestedFieldNoCopy(objU.Object, fields...)
+	if objValueErr != nil {
+		return false, objValueErr
+	}
+
+	if reflect.DeepEqual(objValue, value) {
+		return false, nil
+	}
+
+	if err := unstructured.SetNestedField(objU.Object, runtime.DeepCopyJSONValue(value), fields...); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 // SetStatusFromMoodle set status keys from moodle CR in unstructure object
 // It returns a bool flag if status from moodle was updated, and
 // any error
@@ -607,6 +1350,9 @@ func SetStatusFromMoodle(siteU *unstructured.Unstructured, moodleU *unstructured
 		}
 	}
 
+	// Feed usage metrics from status
+	lmsMoodleMetrics.SetUsageFromStatus(siteU)
+
 	return updateStatusFromMoodle, nil
 }
 
@@ -686,6 +1432,23 @@ func FindUsageItemUnstructuredByName(usageUnstructured []interface{}, usageName
 	return make(map[string]interface{}), false
 }
 
+// combinedSpecsHash returns a hash of the combined dependant specs
+func (r *LMSMoodleReconciler) combinedSpecsHash() string {
+	return specHash(map[string]interface{}{
+		"moodle":   r.lmsMoodleCtx.combinedMoodleSpec,
+		"postgres": r.lmsMoodleCtx.combinedPostgresSpec,
+		"keydb":    r.lmsMoodleCtx.combinedKeydbSpec,
+		"nfs":      r.lmsMoodleCtx.combinedNfsSpec,
+	})
+}
+
+// specHash returns a sha256 hash of a spec. Map keys are sorted when marshaling,
+// so the hash is deterministic
+func specHash(spec interface{}) string {
+	specBytes, _ := json.Marshal(spec)
+	return fmt.Sprintf("%x", sha256.Sum256(specBytes))
+}
+
 // Init a new unstructured object with determined GVK
 func newUnstructuredObject(gvk schema.GroupVersionKind) *unstructured.Unstructured {
 	objU := &unstructured.Unstructured{}
@@ -707,30 +1470,65 @@ func truncate(str string, length int) (truncated string) {
 	return
 }
 
-// setSiteLabels set lms moodle base labels
+// setSiteLabels set lms moodle base labels, along with labels and annotations propagated
+// from lms moodle template, applying them as a dedicated field manager, so that those no
+// longer propagated are removed. Labels patched by operator versions before it are applied
+// again, once owned by the field manager, so that they are removed too
 func (r *LMSMoodleReconciler) setSiteLabels(ctx context.Context) error {
-	log := log.FromContext(ctx)
-	siteLabels := make(map[string]string)
+	siteLabels, siteAnnotations, err := r.defineSiteLabels()
+	if err != nil {
+		return err
+	}
 
-	// use lmsMoodleTemplate labels
-	for key, value := range r.lmsMoodleCtx.lmsMoodleTemplate.GetLabels() {
-		siteLabels[key] = value
+	siteMetadata := newUnstructuredObject(r.lmsMoodleCtx.lmsMoodle.GroupVersionKind())
+	siteMetadata.SetName(r.lmsMoodleCtx.lmsMoodle.GetName())
+	siteMetadata.SetLabels(siteLabels)
+	siteMetadata.SetAnnotations(siteAnnotations)
+	desiredSiteMetadata := siteMetadata.DeepCopy()
+	if err := r.applySiteMetadata(ctx, siteMetadata); err != nil {
+		return err
+	}
+	if moved, err := r.ReconcileLegacyLabels(ctx, siteMetadata, LMSMoodleMetadataFieldManager); err != nil {
+		return err
+	} else if moved {
+		siteMetadata = desiredSiteMetadata
+		if err := r.applySiteMetadata(ctx, siteMetadata); err != nil {
+			return err
+		}
 	}
+	r.lmsMoodleCtx.lmsMoodle.SetLabels(siteMetadata.GetLabels())
+	r.lmsMoodleCtx.lmsMoodle.SetAnnotations(siteMetadata.GetAnnotations())
+	r.lmsMoodleCtx.lmsMoodle.SetResourceVersion(siteMetadata.GetResourceVersion())
 
-	// set base labels
-	siteLabels[lmsv1alpha1.GroupVersion.Group+"/lms-name"] = r.lmsMoodleCtx.name
-	siteLabels[lmsv1alpha1.GroupVersion.Group+"/meta-operator-name"] = OPERATORNAME
+	return nil
+}
 
-	r.lmsMoodleCtx.lmsMoodle.SetLabels(siteLabels)
+// applySiteMetadata applies lms moodle labels and annotations as their field manager
+func (r *LMSMoodleReconciler) applySiteMetadata(ctx context.Context, siteMetadata *unstructured.Unstructured) error {
+	log := log.FromContext(ctx)
 
-	if err := r.Patch(ctx, r.lmsMoodleCtx.lmsMoodle, client.Merge); err != nil {
-		log.Error(err, "Failed to attempt patching lms moodle labels", "LMSMoodle", r.lmsMoodleCtx.lmsMoodle.GetName())
+	force := true
+	if err := r.Patch(ctx, siteMetadata, client.Apply, &client.PatchOptions{Force: &force, FieldManager: LMSMoodleMetadataFieldManager}); err != nil {
+		log.Error(err, "Failed to attempt patching lms moodle labels", "LMSMoodle", siteMetadata.GetName())
 		return err
 	}
-
 	return nil
 }
 
+// defineSiteLabels define lms moodle base labels, along with labels and annotations propagated
+// from lms moodle template, keeping lms moodle ones. It returns those propagated and base labels
+func (r *LMSMoodleReconciler) defineSiteLabels() (siteLabels map[string]string, siteAnnotations map[string]string, err error) {
+	siteLabels, siteAnnotations, err = r.propagateMetadata(r.lmsMoodleCtx.lmsMoodleTemplate, lmsv1alpha1.LMSMoodlePropagationTarget)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r.lmsMoodleCtx.lmsMoodle.SetLabels(mergeStringMaps(r.lmsMoodleCtx.lmsMoodle.GetLabels(), siteLabels))
+	r.lmsMoodleCtx.lmsMoodle.SetAnnotations(mergeStringMaps(r.lmsMoodleCtx.lmsMoodle.GetAnnotations(), siteAnnotations))
+
+	return siteLabels, siteAnnotations, nil
+}
+
 // setDefaultNetpolOmit set default netpol omit
 func (r *LMSMoodleReconciler) setDefaultNetpolOmit(objSpec map[string]interface{}, netpolOmitFieldName string) (err error) {
 	_, objSpecNetpolOmitFound, err := unstructured.NestedString(objSpec, netpolOmitFieldName)
@@ -794,25 +1592,19 @@ func (r *LMSMoodleReconciler) setDefaultKeydbNetpolOmit() (err error) {
 	return err
 }
 
-// commonLabels set common labels
+// commonLabels set common labels of pods, from lms moodle labels propagated to them
 func (r *LMSMoodleReconciler) commonLabels(objSpec map[string]interface{}) (err error) {
-	commonLabels := make(map[string]string)
-	for key, value := range r.lmsMoodleCtx.lmsMoodle.GetLabels() {
-		if strings.HasPrefix(key, "app.kubernetes.io") || key == "app" {
-			continue
-		}
-		commonLabels[key] = value
+	commonLabels, _, err := r.propagatedMetadata(lmsv1alpha1.PodsPropagationTarget)
+	if err != nil {
+		return err
 	}
 
 	siteLabelsBytes, _ := yaml.Marshal(commonLabels)
 	siteLabelsString := string(siteLabelsBytes)
 
-	objSpecCommonLabelsString, objSpecCommonLabelsFound, _ := unstructured.NestedString(objSpec, "commonLabels")
-	if objSpecCommonLabelsFound {
-		objSpec["commonLabels"] = siteLabelsString + "\n" + objSpecCommonLabelsString
-	} else {
-		objSpec["commonLabels"] = siteLabelsString
-	}
+	// common labels set in spec take precedence
+	objSpecCommonLabelsString, _, _ := unstructured.NestedString(objSpec, "commonLabels")
+	objSpec["commonLabels"], err = mergeYAMLMaps("commonLabels", siteLabelsString, objSpecCommonLabelsString)
 
 	return err
 }
@@ -845,39 +1637,16 @@ func (r *LMSMoodleReconciler) defaultAffinityYaml(objSpec map[string]interface{}
 		},
 	}
 
-	defaultAffinityYamlBytes, err = yaml.Marshal(defaultAffinity)
-
-	if objSpecDefaultAffinityString, objDefaultAffinityFound, err := unstructured.NestedString(objSpec, fieldName); err != nil {
-		return err
-	} else if objDefaultAffinityFound {
-		objSpec[fieldName] = string(defaultAffinityYamlBytes) + "\n" + objSpecDefaultAffinityString
-	} else {
-		objSpec[fieldName] = string(defaultAffinityYamlBytes)
-	}
-
-	return err
-}
-
-// Merge value in nested string present in both objects into the first object
-// string + '\n' + string
-func (r *LMSMoodleReconciler) mergeNestedString(firstObjSpec map[string]interface{}, secondObjSpec map[string]interface{}, fields ...string) (err error) {
-
-	firstObjSpecNestedField, firstObjSpecNestedFieldFound, err := unstructured.NestedString(firstObjSpec, fields...)
-	if err != nil {
+	if defaultAffinityYamlBytes, err = yaml.Marshal(defaultAffinity); err != nil {
 		return err
 	}
 
-	secondObjSpecNestedField, secondObjSpecFieldNestedFound, err := unstructured.NestedString(secondObjSpec, fields...)
+	// affinity set in spec takes precedence, by affinity type
+	objSpecDefaultAffinityString, _, err := unstructured.NestedString(objSpec, fieldName)
 	if err != nil {
 		return err
 	}
-
-	if firstObjSpecNestedFieldFound && secondObjSpecFieldNestedFound {
-		mergeNestedField := firstObjSpecNestedField + "\n" + secondObjSpecNestedField
-		if err := unstructured.SetNestedField(firstObjSpec, mergeNestedField, fields...); err != nil {
-			return err
-		}
-	}
+	objSpec[fieldName], err = mergeYAMLMaps(fieldName, string(defaultAffinityYamlBytes), objSpecDefaultAffinityString)
 
 	return err
 }
@@ -914,19 +1683,11 @@ func (r *LMSMoodleReconciler) postgresSpec() (err error) {
 	// Postgres kind from Postgres ansible operator
 	if r.lmsMoodleCtx.hasPostgres {
 		// Set Postgres host and secret, if not already present in Moodle spec
-		postgresRelatedMoodleSpec := map[string]interface{}{
-			"moodlePostgresMetaName": r.lmsMoodleCtx.postgresName,
-		}
-		// Merge Moodle related postgres spec with lmsMoodleTemplate Moodle spec
-		if err := mergo.MapWithOverwrite(&r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec, postgresRelatedMoodleSpec); err != nil {
+		r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec["moodlePostgresMetaName"] = r.lmsMoodleCtx.postgresName
+		// Merge LMSMoodle Postgres spec into lmsMoodleTemplate one
+		if r.lmsMoodleCtx.lmsMoodleTemplatePostgresSpec, err = r.mergeComponentSpec("postgresSpec", r.lmsMoodleCtx.lmsMoodleTemplatePostgresSpec, r.lmsMoodleCtx.postgresSpec); err != nil {
 			return err
 		}
-		// Merge Postgres spec if set on LMSMoodleSpec
-		if r.lmsMoodleCtx.postgresSpecFound {
-			if err := mergo.MapWithOverwrite(&r.lmsMoodleCtx.lmsMoodleTemplatePostgresSpec, r.lmsMoodleCtx.postgresSpec); err != nil {
-				return err
-			}
-		}
 		// Set lms moodle labels to postgres
 		if err := r.commonLabels(r.lmsMoodleCtx.lmsMoodleTemplatePostgresSpec); err != nil {
 			return err
@@ -939,6 +1700,10 @@ func (r *LMSMoodleReconciler) postgresSpec() (err error) {
 		if err := r.setDefaultPostgresNetpolOmit(); err != nil {
 			return err
 		}
+		// serialize typed network policy peers
+		if err := serializeNetpolPeers(r.lmsMoodleCtx.lmsMoodleTemplatePostgresSpec, "postgres", "pgbouncer"); err != nil {
+			return err
+		}
 		// save postgres spec
 		r.lmsMoodleCtx.combinedPostgresSpec = make(map[string]interface{})
 		r.lmsMoodleCtx.combinedPostgresSpec = r.lmsMoodleCtx.lmsMoodleTemplatePostgresSpec
@@ -959,19 +1724,11 @@ func (r *LMSMoodleReconciler) nfsSpec() (err error) {
 	// Ganesha server kind from NFS ansible operator
 	if r.lmsMoodleCtx.hasNfs {
 		// Set NFS storage class name and access modes when using NFS operator
-		nfsRelatedMoodleSpec := map[string]interface{}{
-			"moodleNfsMetaName": r.lmsMoodleCtx.nfsName,
-		}
-		// Merge Moodle related nfs spec with lmsMoodleTemplate Moodle spec
-		if err := mergo.MapWithOverwrite(&r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec, nfsRelatedMoodleSpec); err != nil {
+		r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec["moodleNfsMetaName"] = r.lmsMoodleCtx.nfsName
+		// Merge LMSMoodle NFS spec into lmsMoodleTemplate one
+		if r.lmsMoodleCtx.lmsMoodleTemplateNfsSpec, err = r.mergeComponentSpec("nfsSpec", r.lmsMoodleCtx.lmsMoodleTemplateNfsSpec, r.lmsMoodleCtx.nfsSpec); err != nil {
 			return err
 		}
-		// Merge NFS spec if set on LMSMoodleSpec
-		if r.lmsMoodleCtx.nfsSpecFound {
-			if err := mergo.MapWithOverwrite(&r.lmsMoodleCtx.lmsMoodleTemplateNfsSpec, r.lmsMoodleCtx.nfsSpec); err != nil {
-				return err
-			}
-		}
 		// Set lms moodle labels to nfs
 		if err := r.commonLabels(r.lmsMoodleCtx.lmsMoodleTemplateNfsSpec); err != nil {
 			return err
@@ -984,6 +1741,10 @@ func (r *LMSMoodleReconciler) nfsSpec() (err error) {
 		if err := r.setDefaultNfsNetpolOmit(); err != nil {
 			return err
 		}
+		// serialize typed network policy peers
+		if err := serializeNetpolPeers(r.lmsMoodleCtx.lmsMoodleTemplateNfsSpec, "ganesha"); err != nil {
+			return err
+		}
 		// save nfs spec
 		r.lmsMoodleCtx.combinedNfsSpec = make(map[string]interface{})
 		r.lmsMoodleCtx.combinedNfsSpec = r.lmsMoodleCtx.lmsMoodleTemplateNfsSpec
@@ -1004,19 +1765,11 @@ func (r *LMSMoodleReconciler) keydbSpec() (err error) {
 	// Keydb kind from Keydb ansible operator
 	if r.lmsMoodleCtx.hasKeydb {
 		// Set Keydb host and secret, if not already present in Moodle spec
-		keydbRelatedMoodleSpec := map[string]interface{}{
-			"moodleKeydbMetaName": r.lmsMoodleCtx.keydbName,
-		}
-		// Merge Moodle related keydb spec with lmsMoodleTemplate Moodle spec
-		if err := mergo.MapWithOverwrite(&r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec, keydbRelatedMoodleSpec); err != nil {
+		r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec["moodleKeydbMetaName"] = r.lmsMoodleCtx.keydbName
+		// Merge LMSMoodle Keydb spec into lmsMoodleTemplate one
+		if r.lmsMoodleCtx.lmsMoodleTemplateKeydbSpec, err = r.mergeComponentSpec("keydbSpec", r.lmsMoodleCtx.lmsMoodleTemplateKeydbSpec, r.lmsMoodleCtx.keydbSpec); err != nil {
 			return err
 		}
-		// Merge Keydb spec if set on LMSMoodleSpec
-		if r.lmsMoodleCtx.keydbSpecFound {
-			if err := mergo.MapWithOverwrite(&r.lmsMoodleCtx.lmsMoodleTemplateKeydbSpec, r.lmsMoodleCtx.keydbSpec); err != nil {
-				return err
-			}
-		}
 		// Set lms moodle labels to keydb
 		if err := r.commonLabels(r.lmsMoodleCtx.lmsMoodleTemplateKeydbSpec); err != nil {
 			return err
@@ -1029,6 +1782,10 @@ func (r *LMSMoodleReconciler) keydbSpec() (err error) {
 		if err := r.setDefaultKeydbNetpolOmit(); err != nil {
 			return err
 		}
+		// serialize typed network policy peers
+		if err := serializeNetpolPeers(r.lmsMoodleCtx.lmsMoodleTemplateKeydbSpec, "keydb"); err != nil {
+			return err
+		}
 		// save keydb spec
 		r.lmsMoodleCtx.combinedKeydbSpec = make(map[string]interface{})
 		r.lmsMoodleCtx.combinedKeydbSpec = r.lmsMoodleCtx.lmsMoodleTemplateKeydbSpec
@@ -1037,19 +1794,12 @@ func (r *LMSMoodleReconciler) keydbSpec() (err error) {
 	return err
 }
 
-// moodleSpec handle any keydb spec
+// moodleSpec handle moodle spec
 func (r *LMSMoodleReconciler) moodleSpec() (err error) {
-	// Merge ingress annotations
-	if err := r.mergeNestedString(r.lmsMoodleCtx.moodleSpec, r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec, "nginxIngressAnnotations"); err != nil {
+	// Merge LMSMoodle Moodle spec into lmsMoodleTemplate one
+	if r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec, err = r.mergeComponentSpec("moodleSpec", r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec, r.lmsMoodleCtx.moodleSpec); err != nil {
 		return err
 	}
-
-	// Merge Moodle spec if set on LMSMoodleSpec
-	if r.lmsMoodleCtx.moodleSpecFound {
-		if err := mergo.MapWithOverwrite(&r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec, r.lmsMoodleCtx.moodleSpec); err != nil {
-			return err
-		}
-	}
 	// Set lms moodle labels to Moodle
 	if err := r.commonLabels(r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec); err != nil {
 		return err
@@ -1062,6 +1812,10 @@ func (r *LMSMoodleReconciler) moodleSpec() (err error) {
 	if err := r.setDefaultMoodleNetpolOmit(); err != nil {
 		return err
 	}
+	// serialize typed network policy peers
+	if err := serializeNetpolPeers(r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec, "moodle", "nginx", "phpFpm"); err != nil {
+		return err
+	}
 	// save moodle spec
 	r.lmsMoodleCtx.combinedMoodleSpec = make(map[string]interface{})
 	r.lmsMoodleCtx.combinedMoodleSpec = r.lmsMoodleCtx.lmsMoodleTemplateMoodleSpec
@@ -1069,19 +1823,37 @@ func (r *LMSMoodleReconciler) moodleSpec() (err error) {
 	return err
 }
 
-// defineLMSMoodleDefaultNetpol define lms moodle network policy
-func (r *LMSMoodleReconciler) defineLMSMoodleDefaultNetpol() {
+// defineLMSMoodleDefaultNetpol define lms moodle network policy, isolating namespace
+// except for traffic allowed by namespace network policy. In a target namespace, only
+// LMSMoodle pods are isolated, leaving other workloads there untouched
+func (r *LMSMoodleReconciler) defineLMSMoodleDefaultNetpol() error {
+	namespaceNetworkPolicy, err := r.getNamespaceNetworkPolicy()
+	if err != nil {
+		return err
+	}
+
+	podSelector := metav1.LabelSelector{}
+	if r.lmsMoodleCtx.targetNamespace != "" {
+		podSelector.MatchLabels = map[string]string{lmsv1alpha1.GroupVersion.Group + "/lms-name": r.lmsMoodleCtx.name}
+	}
+
 	// default network policy, isolating namespace
 	r.lmsMoodleCtx.lmsMoodleDefaultNetpol = &networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
 		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: podSelector,
 			PolicyTypes: []networkingv1.PolicyType{
 				networkingv1.PolicyTypeIngress,
 				networkingv1.PolicyTypeEgress,
 			},
+			Ingress: namespaceNetpolIngressRules(namespaceNetworkPolicy),
+			Egress:  namespaceNetpolEgressRules(namespaceNetworkPolicy),
 		},
 	}
 	r.lmsMoodleCtx.lmsMoodleDefaultNetpol.SetNamespace(r.lmsMoodleCtx.namespaceName)
 	r.lmsMoodleCtx.lmsMoodleDefaultNetpol.SetName(r.lmsMoodleCtx.networkPolicyBaseName + "-netpol")
+
+	return nil
 }
 
 // isDependantSuspended whether dependant is suspended
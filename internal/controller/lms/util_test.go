@@ -0,0 +1,223 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lms
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+var _ = Describe("Component states", func() {
+	moodleWith := func(generation int64, observedGeneration int64, cronjobSuspend bool) *unstructured.Unstructured {
+		moodle := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec":   map[string]interface{}{"moodleCronjobSuspend": cronjobSuspend},
+			"status": map[string]interface{}{"observedGeneration": observedGeneration},
+		}}
+		moodle.SetGeneration(generation)
+		return moodle
+	}
+
+	Context("When observing Moodle cronjob state", func() {
+		It("should report Moodle state while Moodle is not ready", func() {
+			Expect(moodleCronState(moodleWith(1, 1, true), "Pending", lmsv1alpha1.ReadyState)).To(Equal("Pending"))
+		})
+
+		It("should report cronjob suspension observed by Moodle", func() {
+			Expect(moodleCronState(moodleWith(2, 2, true), lmsv1alpha1.ReadyState, lmsv1alpha1.ReadyState)).To(Equal(lmsv1alpha1.SuspendedState))
+			Expect(moodleCronState(moodleWith(2, 2, false), lmsv1alpha1.ReadyState, lmsv1alpha1.SuspendedState)).To(Equal(lmsv1alpha1.ReadyState))
+		})
+
+		It("should keep previous cronjob state until Moodle observes its generation", func() {
+			Expect(moodleCronState(moodleWith(3, 2, true), lmsv1alpha1.ReadyState, lmsv1alpha1.ReadyState)).To(Equal(lmsv1alpha1.ReadyState))
+			Expect(moodleCronState(moodleWith(3, 2, true), lmsv1alpha1.ReadyState, "")).To(Equal(lmsv1alpha1.SuspendedState))
+		})
+	})
+})
+
+var _ = Describe("Readiness backoff", func() {
+	Context("When computing requeue delay", func() {
+		It("should double base delay on each attempt", func() {
+			Expect(readinessRequeueAfter(1)).To(Equal(ReadinessRequeueBaseDelay))
+			Expect(readinessRequeueAfter(2)).To(Equal(2 * ReadinessRequeueBaseDelay))
+			Expect(readinessRequeueAfter(4)).To(Equal(8 * ReadinessRequeueBaseDelay))
+		})
+
+		It("should not exceed maximum delay", func() {
+			Expect(readinessRequeueAfter(100)).To(Equal(ReadinessRequeueMaxDelay))
+			Expect(readinessRequeueAfter(0)).To(Equal(ReadinessRequeueBaseDelay))
+		})
+	})
+
+	Context("When counting attempts", func() {
+		It("should keep counting by lms moodle and component until forgotten", func() {
+			r := &LMSMoodleReconciler{}
+			r.lmsMoodleCtx.name = "site-a"
+			Expect(r.readinessAttempt("postgres")).To(Equal(1))
+			Expect(r.readinessAttempt("postgres")).To(Equal(2))
+			Expect(r.readinessAttempt("moodle")).To(Equal(1))
+			Expect(readinessRequeueAfter(r.readinessAttempt("postgres"))).To(Equal(4 * ReadinessRequeueBaseDelay))
+
+			r.lmsMoodleCtx.name = "site-ab"
+			Expect(r.readinessAttempt("postgres")).To(Equal(1))
+
+			r.forgetReadinessAttempts("site-a")
+			r.lmsMoodleCtx.name = "site-a"
+			Expect(r.readinessAttempt("postgres")).To(Equal(1))
+			r.lmsMoodleCtx.name = "site-ab"
+			Expect(r.readinessAttempt("postgres")).To(Equal(2))
+		})
+	})
+})
+
+var _ = Describe("Status notify secrets", func() {
+	var r *LMSMoodleReconciler
+	secretValue := func(_ context.Context, secretKeySelector lmsv1alpha1.SecretKeySelector) (string, error) {
+		return secretKeySelector.Namespace + "/" + secretKeySelector.Name + "/" + secretKeySelector.Key, nil
+	}
+	secretKeyRef := func(namespace string, key string) map[string]interface{} {
+		return map[string]interface{}{"name": "notify", "namespace": namespace, "key": key}
+	}
+
+	BeforeEach(func() {
+		r = &LMSMoodleReconciler{NamespacePolicy: NamespacePolicy{SecretNamespaces: []string{"lms-secrets"}}}
+		r.lmsMoodleCtx.namespaceName = "lms-site"
+		r.lmsMoodleCtx.combinedMoodleSpec = map[string]interface{}{
+			"routineStatusCrNotify": map[string]interface{}{
+				"url":     "https://portal.example.com/status",
+				"headers": map[string]interface{}{"X-Tenant": "demo"},
+				"headersFrom": []interface{}{
+					map[string]interface{}{"name": "X-Api-Key", "secretKeyRef": secretKeyRef("lms-site", "apiKey")},
+				},
+				"jwtSecretRef": secretKeyRef("lms-secrets", "jwtSecret"),
+			},
+		}
+	})
+
+	It("should copy secret values to the notify secret and only reference it in Moodle spec", func() {
+		Expect(r.resolveNotifySecrets(ctx, secretValue)).To(Succeed())
+
+		Expect(r.lmsMoodleCtx.notifySecret).NotTo(BeNil())
+		Expect(r.lmsMoodleCtx.notifySecret.Namespace).To(Equal("lms-site"))
+		Expect(r.lmsMoodleCtx.notifySecret.Name).To(Equal(NotifySecretName))
+		Expect(r.lmsMoodleCtx.notifySecret.Data).To(Equal(map[string][]byte{
+			"routineStatusCrNotify.header.0":  []byte("lms-site/notify/apiKey"),
+			"routineStatusCrNotify.jwtSecret": []byte("lms-secrets/notify/jwtSecret"),
+		}))
+
+		notify, _, _ := unstructured.NestedMap(r.lmsMoodleCtx.combinedMoodleSpec, "routineStatusCrNotify")
+		Expect(notify["headers"]).To(Equal(map[string]interface{}{"X-Tenant": "demo"}))
+		Expect(notify["headersFrom"]).To(Equal([]interface{}{
+			map[string]interface{}{"name": "X-Api-Key", "secretKeyRef": map[string]interface{}{"name": NotifySecretName, "namespace": "lms-site", "key": "routineStatusCrNotify.header.0"}},
+		}))
+		Expect(notify["jwtSecretRef"]).To(Equal(map[string]interface{}{"name": NotifySecretName, "namespace": "lms-site", "key": "routineStatusCrNotify.jwtSecret"}))
+		Expect(notify).NotTo(HaveKey("jwtSecret"))
+	})
+
+	It("should reject secrets out of LMSMoodle and allowed secret namespaces", func() {
+		for _, namespace := range []string{"kube-system", "lms-moodle-operator-system"} {
+			Expect(unstructured.SetNestedMap(r.lmsMoodleCtx.combinedMoodleSpec, secretKeyRef(namespace, "jwtSecret"), "routineStatusCrNotify", "jwtSecretRef")).To(Succeed())
+
+			Expect(r.resolveNotifySecrets(ctx, secretValue)).NotTo(Succeed())
+		}
+	})
+
+	It("should reject secrets in a target namespace, unless it is an allowed secret namespace", func() {
+		r.lmsMoodleCtx.targetNamespace = "lms-site"
+		Expect(r.resolveNotifySecrets(ctx, secretValue)).NotTo(Succeed())
+
+		r.NamespacePolicy.SecretNamespaces = append(r.NamespacePolicy.SecretNamespaces, "lms-site")
+		Expect(r.resolveNotifySecrets(ctx, secretValue)).To(Succeed())
+	})
+
+	It("should not set a notify secret without secret references", func() {
+		unstructured.RemoveNestedField(r.lmsMoodleCtx.combinedMoodleSpec, "routineStatusCrNotify", "headersFrom")
+		unstructured.RemoveNestedField(r.lmsMoodleCtx.combinedMoodleSpec, "routineStatusCrNotify", "jwtSecretRef")
+
+		Expect(r.resolveNotifySecrets(ctx, secretValue)).To(Succeed())
+		Expect(r.lmsMoodleCtx.notifySecret).To(BeNil())
+	})
+})
+
+var _ = Describe("Target namespace finalization", func() {
+	It("should delete resources owned by the LMSMoodle only, keeping those of other LMSMoodles and secrets", func() {
+		scheme := newDependantsScheme()
+		lmsMoodle := newUnstructuredObject(lmsv1alpha1.GroupVersion.WithKind("LMSMoodle"))
+		lmsMoodle.SetName("site")
+		lmsMoodle.SetUID("site-uid")
+
+		controller := true
+		owned := func(obj client.Object, name string, ownerName string) client.Object {
+			obj.SetName(name)
+			obj.SetNamespace("shared")
+			obj.SetOwnerReferences([]metav1.OwnerReference{{
+				APIVersion: lmsv1alpha1.GroupVersion.String(),
+				Kind:       "LMSMoodle",
+				Name:       ownerName,
+				UID:        types.UID(ownerName + "-uid"),
+				Controller: &controller,
+			}})
+			return obj
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			lmsMoodle,
+			owned(&networkingv1.NetworkPolicy{}, "lms-site-default", "site"),
+			owned(&corev1.ConfigMap{}, "lms-site-"+EffectiveSpecsConfigMapName, "site"),
+			owned(&corev1.ConfigMap{}, "lms-site-"+StateHistoryConfigMapName, "site"),
+			owned(&corev1.Secret{}, "lms-site-"+NotifySecretName, "site"),
+			owned(&corev1.ConfigMap{}, "lms-other-"+StateHistoryConfigMapName, "other"),
+		).WithStatusSubresource(&lmsv1alpha1.LMSMoodle{}).Build()
+
+		r := &LMSMoodleReconciler{Client: c, Scheme: scheme, Recorder: &record.FakeRecorder{}, MoodleGVK: MoodleGVK}
+		r.lmsMoodleCtx.name = "site"
+		r.lmsMoodleCtx.lmsMoodle = lmsMoodle
+		r.lmsMoodleCtx.targetNamespace = "shared"
+		r.lmsMoodleCtx.markedToBeDeleted = true
+		r.setDependantNames("shared", "lms-site")
+		r.lmsMoodleCtx.lmsMoodleDefaultNetpol = &networkingv1.NetworkPolicy{}
+		r.lmsMoodleCtx.lmsMoodleDefaultNetpol.SetName("lms-site-default")
+		r.lmsMoodleCtx.lmsMoodleDefaultNetpol.SetNamespace("shared")
+
+		requeue, err := r.finalizeLMSMoodle(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requeue).To(BeFalse())
+
+		configMaps := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, configMaps)).To(Succeed())
+		Expect(configMaps.Items).To(HaveLen(1))
+		Expect(configMaps.Items[0].Name).To(Equal("lms-other-" + StateHistoryConfigMapName))
+		secrets := &corev1.SecretList{}
+		Expect(c.List(ctx, secrets)).To(Succeed())
+		// garbage collected along with LMSMoodle
+		Expect(secrets.Items).To(HaveLen(1))
+		networkPolicies := &networkingv1.NetworkPolicyList{}
+		Expect(c.List(ctx, networkPolicies)).To(Succeed())
+		Expect(networkPolicies.Items).To(BeEmpty())
+	})
+})
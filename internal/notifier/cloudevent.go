@@ -0,0 +1,32 @@
+package notifier
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// CloudEventsSpecVersion is the CloudEvents specification version of sent events
+	CloudEventsSpecVersion string = "1.0"
+	// CloudEventsContentType is the content type of events sent in structured mode
+	CloudEventsContentType string = "application/cloudevents+json"
+	// EventTypePrefix prefixes the type of every event, followed by the lower case state
+	EventTypePrefix string = "io.krestomat.lms.lmsmoodle."
+)
+
+// Event is a CloudEvent about a LMSMoodle state change
+type Event struct {
+	SpecVersion     string                 `json:"specversion"`
+	ID              string                 `json:"id"`
+	Source          string                 `json:"source"`
+	Type            string                 `json:"type"`
+	Subject         string                 `json:"subject,omitempty"`
+	Time            time.Time              `json:"time"`
+	DataContentType string                 `json:"datacontenttype,omitempty"`
+	Data            map[string]interface{} `json:"data,omitempty"`
+}
+
+// Marshal returns the event in CloudEvents JSON structured mode
+func (e *Event) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
@@ -0,0 +1,117 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// HostPolicy restricts the endpoints events are delivered to
+type HostPolicy struct {
+	// AllowedHosts endpoint hosts must match, such as hooks.example.com or *.example.com,
+	// which matches its subdomains. Any host if empty
+	AllowedHosts []string
+	// AllowPrivateAddresses whether endpoints can be reached at loopback, private, link-local
+	// or shared addresses, such as those of cluster services. Not allowed by default
+	AllowPrivateAddresses bool
+}
+
+// sharedAddresses are carrier-grade NAT addresses, not global ones either
+var sharedAddresses = netip.MustParsePrefix("100.64.0.0/10")
+
+// EndpointNotAllowedError is returned when an endpoint is not allowed by the notifier.
+// Its deliveries are not retried
+type EndpointNotAllowedError struct {
+	Message string // why the endpoint is not allowed
+}
+
+func (e *EndpointNotAllowedError) Error() string {
+	return e.Message
+}
+
+// isEndpointNotAllowed returns whether an error, or any it wraps, is an EndpointNotAllowedError
+func isEndpointNotAllowed(err error) bool {
+	var endpointNotAllowedError *EndpointNotAllowedError
+	return errors.As(err, &endpointNotAllowedError)
+}
+
+// hostAllowed returns whether a host is allowed by the policy
+func (p HostPolicy) hostAllowed(host string) bool {
+	if len(p.AllowedHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowedHost := range p.AllowedHosts {
+		allowedHost = strings.ToLower(allowedHost)
+		if host == allowedHost {
+			return true
+		}
+		if suffix, found := strings.CutPrefix(allowedHost, "*"); found && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkURL returns an EndpointNotAllowedError if the host of a request URL is not allowed
+func (p HostPolicy) checkURL(req *http.Request) error {
+	if !p.hostAllowed(req.URL.Hostname()) {
+		return &EndpointNotAllowedError{fmt.Sprintf("endpoint host '%s' is not allowed", req.URL.Hostname())}
+	}
+	return nil
+}
+
+// checkAddress returns an EndpointNotAllowedError if a dialed address is not a global one,
+// unless private addresses are allowed. Addresses are checked once resolved, so that hosts
+// resolving to private addresses are not reached either
+func (p HostPolicy) checkAddress(address string) error {
+	if p.AllowPrivateAddresses {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddresses.Contains(ip) {
+		return &EndpointNotAllowedError{fmt.Sprintf("endpoint address '%s' is not allowed", ip)}
+	}
+	return nil
+}
+
+// newHTTPClient returns an HTTP client reaching only endpoints allowed by the policy,
+// redirects included. Endpoints are reached directly, without proxy, so that their
+// addresses are the ones checked
+func newHTTPClient(hostPolicy HostPolicy) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   RequestTimeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return hostPolicy.checkAddress(address)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   RequestTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			return hostPolicy.checkURL(req)
+		},
+	}
+}
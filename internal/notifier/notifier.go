@@ -0,0 +1,333 @@
+// Package notifier sends CloudEvents about LMSMoodle state changes to HTTP endpoints.
+// Each pending delivery is persisted in its own ConfigMap, so they survive operator restarts
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultMaxAttempts before giving up on delivering an event
+	DefaultMaxAttempts int32 = 10
+	// RetryBaseDelay is the delay before the first retry. It doubles on each attempt
+	RetryBaseDelay time.Duration = 5 * time.Second
+	// RetryMaxDelay is the maximum delay between retries
+	RetryMaxDelay time.Duration = 10 * time.Minute
+	// PollInterval is how often due deliveries are checked
+	PollInterval time.Duration = 5 * time.Second
+	// RequestTimeout is the timeout of each delivery request
+	RequestTimeout time.Duration = 10 * time.Second
+	// serviceAccountNamespaceFile holds the namespace the operator runs in
+	serviceAccountNamespaceFile string = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// DefaultStates are notified when an endpoint does not set its states
+var DefaultStates = []lmsv1alpha1.NotifierState{
+	lmsv1alpha1.NotifierState(lmsv1alpha1.ReadyState),
+	lmsv1alpha1.NotifierState(lmsv1alpha1.FailedState),
+	lmsv1alpha1.NotifierState(lmsv1alpha1.SuspendedState),
+	lmsv1alpha1.NotifierState(lmsv1alpha1.TerminatedState),
+}
+
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=list;create;patch;delete,namespace=system
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get,namespace=system
+
+// Notifier delivers LMSMoodle events to endpoints, retrying with backoff
+type Notifier struct {
+	client     client.Client
+	reader     client.Reader
+	httpClient *http.Client
+	hostPolicy HostPolicy
+	namespace  string
+	mu         sync.Mutex
+	loaded     bool
+	pending    map[string]*Delivery
+	wake       chan struct{}
+}
+
+// New returns a notifier persisting its queue in given namespace, where signing
+// secrets are read from too, delivering events to endpoints allowed by host policy.
+// Reader is used for uncached reads of secrets and the queue
+func New(c client.Client, reader client.Reader, namespace string, hostPolicy HostPolicy) *Notifier {
+	return &Notifier{
+		client:     c,
+		reader:     reader,
+		httpClient: newHTTPClient(hostPolicy),
+		hostPolicy: hostPolicy,
+		namespace:  namespace,
+		pending:    map[string]*Delivery{},
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// OperatorNamespace returns the namespace the operator runs in, from
+// POD_NAMESPACE env var or the service account, if any
+func OperatorNamespace() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	if namespace, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+		return strings.TrimSpace(string(namespace))
+	}
+	return ""
+}
+
+// NewEvent returns an event about a LMSMoodle state change. Its ID is the same for
+// the same transition, from previous to new state at transition time, so it is only queued once
+func NewEvent(lmsMoodle client.Object, previousState string, state string, transitionTime time.Time, data map[string]interface{}) Event {
+	transitionTime = transitionTime.UTC().Truncate(time.Second)
+	id := sha256.Sum256([]byte(string(lmsMoodle.GetUID()) + "/" + previousState + "/" + state + "/" + transitionTime.Format(time.RFC3339)))
+
+	return Event{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              hex.EncodeToString(id[:16]),
+		Source:          "/apis/" + lmsv1alpha1.GroupVersion.String() + "/lmsmoodles/" + lmsMoodle.GetName(),
+		Type:            EventTypePrefix + strings.ToLower(state),
+		Subject:         lmsMoodle.GetName(),
+		Time:            transitionTime,
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// Notify queues an event for the endpoints notifying about the state. The event
+// is persisted before returning. Events already queued are ignored. Endpoints not
+// allowed are queued too, so that their delivery fails and is reported in status
+func (n *Notifier) Notify(ctx context.Context, lmsMoodle string, state string, endpoints []lmsv1alpha1.NotifierEndpoint, event Event) error {
+	var deliveries []*Delivery
+	for _, endpoint := range endpoints {
+		if !notifiesState(endpoint, state) {
+			continue
+		}
+		deliveries = append(deliveries, &Delivery{
+			ID:          event.ID + "." + endpoint.Name,
+			LMSMoodle:   lmsMoodle,
+			Endpoint:    endpoint,
+			Event:       event,
+			NextAttempt: event.Time,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.load(ctx); err != nil {
+		return err
+	}
+	if err := n.enqueue(ctx, deliveries...); err != nil {
+		return err
+	}
+
+	// wake up delivery loop
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (n *Notifier) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable, delivering due events until context is done
+func (n *Notifier) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("notifier")
+	ctx = log.IntoContext(ctx, logger)
+	logger.Info("Starting notifier", "Namespace", n.namespace)
+
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		n.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-n.wake:
+		}
+	}
+}
+
+// deliverDue attempts every due delivery
+func (n *Notifier) deliverDue(ctx context.Context) {
+	log := log.FromContext(ctx)
+
+	n.mu.Lock()
+	if err := n.load(ctx); err != nil {
+		n.mu.Unlock()
+		log.Error(err, "Unable to load notifier queue")
+		return
+	}
+	deliveries := n.due(time.Now())
+	n.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		n.attempt(ctx, delivery)
+	}
+}
+
+// attempt delivers an event once, then updates the queue and LMSMoodle status
+func (n *Notifier) attempt(ctx context.Context, delivery *Delivery) {
+	log := log.FromContext(ctx).WithValues("LMSMoodle", delivery.LMSMoodle, "Endpoint", delivery.Endpoint.Name, "Event", delivery.Event.ID)
+
+	deliverErr := n.deliver(ctx, delivery)
+	now := time.Now()
+
+	n.mu.Lock()
+	delivery.Attempts++
+	notificationStatus := lmsv1alpha1.NotificationStatus{
+		EventID:         delivery.Event.ID,
+		EventType:       delivery.Event.Type,
+		Attempts:        delivery.Attempts,
+		LastAttemptTime: &metav1.Time{Time: now},
+	}
+	switch {
+	case deliverErr == nil:
+		log.Info("Event delivered")
+		delete(n.pending, delivery.ID)
+		notificationStatus.Result = lmsv1alpha1.DeliveredNotificationResult
+		notificationStatus.Message = "Event delivered"
+	case delivery.Attempts >= maxAttempts(delivery.Endpoint) || isEndpointNotAllowed(deliverErr):
+		log.Error(deliverErr, "Event not delivered, giving up", "Attempts", delivery.Attempts)
+		delete(n.pending, delivery.ID)
+		notificationStatus.Result = lmsv1alpha1.FailedNotificationResult
+		notificationStatus.Message = deliverErr.Error()
+	default:
+		log.V(1).Info("Event not delivered, retrying", "Attempts", delivery.Attempts, "Error", deliverErr.Error())
+		delivery.NextAttempt = now.Add(retryDelay(delivery.Attempts))
+		notificationStatus.Result = lmsv1alpha1.RetryingNotificationResult
+		notificationStatus.Message = deliverErr.Error()
+	}
+	notificationStatus.Pending = n.pendingFor(delivery.target())
+	var persistErr error
+	if _, found := n.pending[delivery.ID]; found {
+		persistErr = n.persist(ctx, delivery)
+	} else {
+		persistErr = n.remove(ctx, delivery)
+	}
+	n.mu.Unlock()
+
+	if persistErr != nil {
+		log.Error(persistErr, "Unable to persist pending delivery")
+	}
+	if err := n.updateStatus(ctx, delivery, notificationStatus); err != nil {
+		log.Error(err, "Unable to update LMSMoodle notification status")
+	}
+}
+
+// deliver sends an event to an endpoint, in CloudEvents structured mode
+func (n *Notifier) deliver(ctx context.Context, delivery *Delivery) error {
+	body, err := delivery.Event.Marshal()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if err := n.hostPolicy.checkURL(req); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", CloudEventsContentType)
+	if err := n.sign(ctx, req, body, delivery); err != nil {
+		return err
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded with status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// updateStatus sets the notification status of an endpoint in LMSMoodle status
+func (n *Notifier) updateStatus(ctx context.Context, delivery *Delivery, notificationStatus lmsv1alpha1.NotificationStatus) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"notifications": map[string]interface{}{
+				delivery.Endpoint.Name: notificationStatus,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	lmsMoodle := &lmsv1alpha1.LMSMoodle{}
+	lmsMoodle.SetName(delivery.LMSMoodle)
+	if err := n.client.Status().Patch(ctx, lmsMoodle, client.RawPatch(types.MergePatchType, patch)); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// notifiesState returns whether an endpoint is notified about a state
+func notifiesState(endpoint lmsv1alpha1.NotifierEndpoint, state string) bool {
+	states := endpoint.States
+	if len(states) == 0 {
+		states = DefaultStates
+	}
+	for _, notifiedState := range states {
+		if string(notifiedState) == state {
+			return true
+		}
+	}
+	return false
+}
+
+// maxAttempts returns the attempts before giving up on an endpoint
+func maxAttempts(endpoint lmsv1alpha1.NotifierEndpoint) int32 {
+	if endpoint.MaxAttempts > 0 {
+		return endpoint.MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
+
+// retryDelay returns the delay before next attempt, doubling from
+// RetryBaseDelay up to RetryMaxDelay
+func retryDelay(attempts int32) time.Duration {
+	delay := RetryBaseDelay
+	for i := int32(1); i < attempts && delay < RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > RetryMaxDelay {
+		delay = RetryMaxDelay
+	}
+	return delay
+}
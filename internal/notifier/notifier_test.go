@@ -0,0 +1,283 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+)
+
+const operatorNamespace = "lms-moodle-operator-system"
+
+var _ = Describe("Notifier", func() {
+	var ctx context.Context
+	var c client.Client
+	var n *Notifier
+	var lmsMoodle *lmsv1alpha1.LMSMoodle
+	transitionTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(lmsv1alpha1.AddToScheme(scheme)).To(Succeed())
+		signingSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "signing", Namespace: operatorNamespace, Labels: map[string]string{SigningSecretLabel: "true"}},
+			Data:       map[string][]byte{"key": []byte("s3cr3t")},
+		}
+		operatorSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: operatorNamespace},
+			Data:       map[string][]byte{"key": []byte("operator")},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(signingSecret, operatorSecret).WithStatusSubresource(&lmsv1alpha1.LMSMoodle{}).Build()
+		n = New(c, c, operatorNamespace, HostPolicy{AllowPrivateAddresses: true})
+		lmsMoodle = &lmsv1alpha1.LMSMoodle{ObjectMeta: metav1.ObjectMeta{Name: "site", UID: types.UID("site-uid")}}
+	})
+
+	queued := func() []corev1.ConfigMap {
+		configMapList := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, configMapList, client.InNamespace(operatorNamespace), client.HasLabels{QueueLabel})).To(Succeed())
+		return configMapList.Items
+	}
+
+	Context("Event ID", func() {
+		It("should be the same for the same transition", func() {
+			event := NewEvent(lmsMoodle, lmsv1alpha1.UnknownState, lmsv1alpha1.ReadyState, transitionTime, nil)
+			lmsMoodle.SetResourceVersion("2")
+			Expect(NewEvent(lmsMoodle, lmsv1alpha1.UnknownState, lmsv1alpha1.ReadyState, transitionTime, nil).ID).To(Equal(event.ID))
+			Expect(event.Time).To(Equal(transitionTime))
+		})
+
+		It("should differ for another transition", func() {
+			event := NewEvent(lmsMoodle, lmsv1alpha1.UnknownState, lmsv1alpha1.ReadyState, transitionTime, nil)
+			Expect(NewEvent(lmsMoodle, lmsv1alpha1.SuspendedState, lmsv1alpha1.ReadyState, transitionTime, nil).ID).NotTo(Equal(event.ID))
+			Expect(NewEvent(lmsMoodle, lmsv1alpha1.UnknownState, lmsv1alpha1.ReadyState, transitionTime.Add(time.Minute), nil).ID).NotTo(Equal(event.ID))
+		})
+	})
+
+	Context("Retry delay", func() {
+		It("should double from base delay up to max delay", func() {
+			Expect(retryDelay(1)).To(Equal(RetryBaseDelay))
+			Expect(retryDelay(2)).To(Equal(2 * RetryBaseDelay))
+			Expect(retryDelay(3)).To(Equal(4 * RetryBaseDelay))
+			Expect(retryDelay(100)).To(Equal(RetryMaxDelay))
+		})
+	})
+
+	Context("Queue", func() {
+		var endpoints []lmsv1alpha1.NotifierEndpoint
+		var event Event
+
+		BeforeEach(func() {
+			endpoints = []lmsv1alpha1.NotifierEndpoint{
+				{Name: "ready", Url: "http://127.0.0.1:1/ready", States: []lmsv1alpha1.NotifierState{lmsv1alpha1.NotifierState(lmsv1alpha1.ReadyState)}},
+				{Name: "failed", Url: "http://127.0.0.1:1/failed", States: []lmsv1alpha1.NotifierState{lmsv1alpha1.NotifierState(lmsv1alpha1.FailedState)}},
+			}
+			event = NewEvent(lmsMoodle, lmsv1alpha1.UnknownState, lmsv1alpha1.ReadyState, transitionTime, nil)
+		})
+
+		It("should persist each delivery in its own ConfigMap, once", func() {
+			Expect(n.Notify(ctx, "site", lmsv1alpha1.ReadyState, endpoints, event)).To(Succeed())
+			Expect(n.Notify(ctx, "site", lmsv1alpha1.ReadyState, endpoints, event)).To(Succeed())
+
+			configMaps := queued()
+			Expect(configMaps).To(HaveLen(1))
+			Expect(configMaps[0].Name).To(Equal(QueueConfigMapPrefix + event.ID + ".ready"))
+		})
+
+		It("should not queue an event already persisted by a previous run", func() {
+			Expect(n.Notify(ctx, "site", lmsv1alpha1.ReadyState, endpoints, event)).To(Succeed())
+
+			restarted := New(c, c, operatorNamespace, HostPolicy{AllowPrivateAddresses: true})
+			Expect(restarted.Notify(ctx, "site", lmsv1alpha1.ReadyState, endpoints, event)).To(Succeed())
+			Expect(restarted.pending).To(HaveLen(1))
+			Expect(queued()).To(HaveLen(1))
+		})
+
+		It("should load pending deliveries with their attempts after a restart", func() {
+			Expect(n.Notify(ctx, "site", lmsv1alpha1.ReadyState, endpoints, event)).To(Succeed())
+			n.attempt(ctx, n.pending[event.ID+".ready"])
+
+			restarted := New(c, c, operatorNamespace, HostPolicy{AllowPrivateAddresses: true})
+			Expect(restarted.load(ctx)).To(Succeed())
+			Expect(restarted.pending).To(HaveKey(event.ID + ".ready"))
+			delivery := restarted.pending[event.ID+".ready"]
+			Expect(delivery.Attempts).To(Equal(int32(1)))
+			Expect(delivery.NextAttempt).To(BeTemporally(">", time.Now()))
+		})
+
+		It("should remove the ConfigMap once delivered", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+			endpoints[0].Url = server.URL
+
+			Expect(n.Notify(ctx, "site", lmsv1alpha1.ReadyState, endpoints, event)).To(Succeed())
+			n.deliverDue(ctx)
+
+			Expect(n.pending).To(BeEmpty())
+			Expect(queued()).To(BeEmpty())
+		})
+
+		It("should remove the ConfigMap when giving up", func() {
+			endpoints[0].MaxAttempts = 1
+
+			Expect(n.Notify(ctx, "site", lmsv1alpha1.ReadyState, endpoints, event)).To(Succeed())
+			n.deliverDue(ctx)
+
+			Expect(n.pending).To(BeEmpty())
+			Expect(queued()).To(BeEmpty())
+		})
+	})
+
+	Context("Host policy", func() {
+		It("should allow hosts listed, or subdomains of wildcard ones", func() {
+			hostPolicy := HostPolicy{AllowedHosts: []string{"hooks.example.com", "*.example.org"}}
+			Expect(hostPolicy.hostAllowed("hooks.example.com")).To(BeTrue())
+			Expect(hostPolicy.hostAllowed("HOOKS.example.com.")).To(BeTrue())
+			Expect(hostPolicy.hostAllowed("a.hooks.example.com")).To(BeFalse())
+			Expect(hostPolicy.hostAllowed("a.example.org")).To(BeTrue())
+			Expect(hostPolicy.hostAllowed("example.org")).To(BeFalse())
+			Expect(hostPolicy.hostAllowed("badexample.org")).To(BeFalse())
+			Expect(HostPolicy{}.hostAllowed("any.example.net")).To(BeTrue())
+		})
+
+		It("should not allow private addresses, unless set", func() {
+			for _, address := range []string{"127.0.0.1:80", "10.0.0.1:443", "169.254.169.254:80", "100.64.0.1:80", "[::1]:80", "[fd00::1]:80", "[::ffff:192.168.0.1]:80", "0.0.0.0:80"} {
+				Expect(isEndpointNotAllowed(HostPolicy{}.checkAddress(address))).To(BeTrue(), address)
+				Expect(HostPolicy{AllowPrivateAddresses: true}.checkAddress(address)).To(Succeed(), address)
+			}
+			Expect(HostPolicy{}.checkAddress("93.184.215.14:443")).To(Succeed())
+		})
+
+		It("should give up delivering to endpoints not allowed at once", func() {
+			var received bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = true
+			}))
+			defer server.Close()
+			endpoints := []lmsv1alpha1.NotifierEndpoint{{Name: "private", Url: server.URL}}
+			event := NewEvent(lmsMoodle, lmsv1alpha1.UnknownState, lmsv1alpha1.ReadyState, transitionTime, nil)
+
+			for _, hostPolicy := range []HostPolicy{{}, {AllowedHosts: []string{"hooks.example.com"}, AllowPrivateAddresses: true}} {
+				n = New(c, c, operatorNamespace, hostPolicy)
+				Expect(n.Notify(ctx, "site", lmsv1alpha1.ReadyState, endpoints, event)).To(Succeed())
+				n.deliverDue(ctx)
+				Expect(received).To(BeFalse())
+				Expect(n.pending).To(BeEmpty())
+				Expect(queued()).To(BeEmpty())
+			}
+		})
+
+		It("should not follow redirects to hosts not allowed", func() {
+			var redirected bool
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				redirected = true
+			}))
+			defer target.Close()
+			server := httptest.NewServer(http.RedirectHandler(strings.Replace(target.URL, "127.0.0.1", "localhost", 1), http.StatusTemporaryRedirect))
+			defer server.Close()
+
+			n = New(c, c, operatorNamespace, HostPolicy{AllowedHosts: []string{"127.0.0.1"}, AllowPrivateAddresses: true})
+			delivery := &Delivery{
+				ID:        "event.redirect",
+				LMSMoodle: "site",
+				Event:     NewEvent(lmsMoodle, lmsv1alpha1.UnknownState, lmsv1alpha1.ReadyState, transitionTime, nil),
+				Endpoint:  lmsv1alpha1.NotifierEndpoint{Name: "redirect", Url: server.URL},
+			}
+			Expect(n.deliver(ctx, delivery)).To(MatchError(ContainSubstring("endpoint host 'localhost' is not allowed")))
+			Expect(redirected).To(BeFalse())
+		})
+	})
+
+	Context("Signing", func() {
+		var received *http.Request
+		var receivedBody []byte
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			received = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				receivedBody, _ = io.ReadAll(r.Body)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		delivery := func(secretNamespace string, secretName string) *Delivery {
+			return &Delivery{
+				ID:        "event.hmac",
+				LMSMoodle: "site",
+				Event:     NewEvent(lmsMoodle, lmsv1alpha1.UnknownState, lmsv1alpha1.ReadyState, transitionTime, nil),
+				Endpoint: lmsv1alpha1.NotifierEndpoint{
+					Name: "hmac",
+					Url:  server.URL,
+					Signing: &lmsv1alpha1.NotifierSigning{
+						Method:    lmsv1alpha1.HMACSigningMethod,
+						SecretRef: lmsv1alpha1.SecretKeySelector{Name: secretName, Namespace: secretNamespace, Key: "key"},
+					},
+				},
+			}
+		}
+
+		It("should sign timestamp and body with HMAC-SHA256", func() {
+			Expect(n.deliver(ctx, delivery(operatorNamespace, "signing"))).To(Succeed())
+
+			timestamp := received.Header.Get(TimestampHeader)
+			Expect(timestamp).NotTo(BeEmpty())
+			Expect(received.Header.Get(SignatureHeader)).To(Equal("sha256=" + hmacSHA256Hex([]byte("s3cr3t"), []byte(timestamp+"."+string(receivedBody)))))
+			Expect(received.Header.Get("Content-Type")).To(Equal(CloudEventsContentType))
+		})
+
+		It("should not read signing secrets out of notifier namespace, giving up at once", func() {
+			Expect(isEndpointNotAllowed(n.deliver(ctx, delivery("default", "signing")))).To(BeTrue())
+			Expect(received).To(BeNil())
+
+			Expect(n.Notify(ctx, "site", lmsv1alpha1.ReadyState, []lmsv1alpha1.NotifierEndpoint{delivery("default", "signing").Endpoint}, delivery("default", "signing").Event)).To(Succeed())
+			n.deliverDue(ctx)
+			Expect(received).To(BeNil())
+			Expect(n.pending).To(BeEmpty())
+			Expect(queued()).To(BeEmpty())
+		})
+
+		It("should not read secrets not labeled as signing ones", func() {
+			Expect(n.deliver(ctx, delivery(operatorNamespace, "operator"))).To(MatchError(ContainSubstring("must be labeled " + SigningSecretLabel + "=true")))
+			Expect(received).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,199 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// QueueConfigMapPrefix prefixes the name of the ConfigMap persisting each pending delivery
+	QueueConfigMapPrefix string = "lms-moodle-operator-notifier-"
+	// QueueConfigMapKey is the ConfigMap key holding a pending delivery as JSON
+	QueueConfigMapKey string = "delivery.json"
+	// QueueLabel marks ConfigMaps persisting pending deliveries
+	QueueLabel string = "lms.krestomat.io/notifier-queue"
+	// MaxPendingDeliveries is the number of pending deliveries kept. Oldest are dropped first
+	MaxPendingDeliveries int = 500
+)
+
+// Delivery is an event pending to be delivered to an endpoint
+type Delivery struct {
+	ID          string                       `json:"id"`
+	LMSMoodle   string                       `json:"lmsMoodle"`
+	Endpoint    lmsv1alpha1.NotifierEndpoint `json:"endpoint"`
+	Event       Event                        `json:"event"`
+	Attempts    int32                        `json:"attempts"`
+	NextAttempt time.Time                    `json:"nextAttempt"`
+}
+
+// target identifies the LMSMoodle and endpoint of a delivery.
+// Deliveries to the same target are sent in order
+func (d *Delivery) target() string {
+	return d.LMSMoodle + "/" + d.Endpoint.Name
+}
+
+// configMapName returns the name of the ConfigMap persisting a delivery. Its ID
+// is an event ID, in hex, and the endpoint name, a DNS label
+func (d *Delivery) configMapName() string {
+	return QueueConfigMapPrefix + d.ID
+}
+
+// load reads pending deliveries from queue ConfigMaps, once. Lock must be held
+func (n *Notifier) load(ctx context.Context) error {
+	if n.loaded {
+		return nil
+	}
+
+	log := log.FromContext(ctx)
+
+	configMapList := &corev1.ConfigMapList{}
+	if err := n.reader.List(ctx, configMapList, client.InNamespace(n.namespace), client.HasLabels{QueueLabel}); err != nil {
+		return err
+	}
+
+	for _, configMap := range configMapList.Items {
+		delivery := &Delivery{}
+		if err := json.Unmarshal([]byte(configMap.Data[QueueConfigMapKey]), delivery); err != nil {
+			log.Error(err, "Dropping unreadable pending delivery", "ConfigMap", configMap.Name)
+			continue
+		}
+		n.pending[delivery.ID] = delivery
+	}
+	n.loaded = true
+
+	log.V(1).Info("Notifier queue loaded", "Pending", len(n.pending))
+	return nil
+}
+
+// configMap returns the ConfigMap persisting a delivery
+func (n *Notifier) configMap(delivery *Delivery) (*corev1.ConfigMap, error) {
+	value, err := json.Marshal(delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      delivery.configMapName(),
+			Namespace: n.namespace,
+			Labels:    map[string]string{QueueLabel: "true"},
+		},
+		Data: map[string]string{QueueConfigMapKey: string(value)},
+	}, nil
+}
+
+// create persists a new delivery in its own ConfigMap. It is not an error if already persisted
+func (n *Notifier) create(ctx context.Context, delivery *Delivery) error {
+	configMap, err := n.configMap(delivery)
+	if err != nil {
+		return err
+	}
+
+	if err := n.client.Create(ctx, configMap); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// persist updates the ConfigMap of a pending delivery. Only the notifier writes it,
+// so it is patched without conflicts
+func (n *Notifier) persist(ctx context.Context, delivery *Delivery) error {
+	configMap, err := n.configMap(delivery)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"data": configMap.Data})
+	if err != nil {
+		return err
+	}
+
+	return n.client.Patch(ctx, configMap, client.RawPatch(types.MergePatchType, patch))
+}
+
+// remove deletes the ConfigMap of a delivery no longer pending
+func (n *Notifier) remove(ctx context.Context, delivery *Delivery) error {
+	configMap := &corev1.ConfigMap{}
+	configMap.SetName(delivery.configMapName())
+	configMap.SetNamespace(n.namespace)
+
+	return client.IgnoreNotFound(n.client.Delete(ctx, configMap))
+}
+
+// enqueue persists and adds new deliveries, dropping oldest ones above the limit. Lock must be held
+func (n *Notifier) enqueue(ctx context.Context, deliveries ...*Delivery) error {
+	for _, delivery := range deliveries {
+		if _, found := n.pending[delivery.ID]; found {
+			continue
+		}
+		if err := n.create(ctx, delivery); err != nil {
+			return err
+		}
+		n.pending[delivery.ID] = delivery
+	}
+
+	if len(n.pending) <= MaxPendingDeliveries {
+		return nil
+	}
+	ordered := n.ordered()
+	for _, delivery := range ordered[:len(ordered)-MaxPendingDeliveries] {
+		if err := n.remove(ctx, delivery); err != nil {
+			return err
+		}
+		delete(n.pending, delivery.ID)
+	}
+
+	return nil
+}
+
+// ordered returns pending deliveries, oldest event first. Lock must be held
+func (n *Notifier) ordered() []*Delivery {
+	deliveries := make([]*Delivery, 0, len(n.pending))
+	for _, delivery := range n.pending {
+		deliveries = append(deliveries, delivery)
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		if deliveries[i].Event.Time.Equal(deliveries[j].Event.Time) {
+			return deliveries[i].ID < deliveries[j].ID
+		}
+		return deliveries[i].Event.Time.Before(deliveries[j].Event.Time)
+	})
+	return deliveries
+}
+
+// due returns the oldest pending delivery of each target, if its next attempt is due. Lock must be held
+func (n *Notifier) due(now time.Time) []*Delivery {
+	var deliveries []*Delivery
+	seen := map[string]bool{}
+	for _, delivery := range n.ordered() {
+		if seen[delivery.target()] {
+			continue
+		}
+		seen[delivery.target()] = true
+		if !delivery.NextAttempt.After(now) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries
+}
+
+// pendingFor returns the number of pending deliveries of a target. Lock must be held
+func (n *Notifier) pendingFor(target string) int32 {
+	var count int32
+	for _, delivery := range n.pending {
+		if delivery.target() == target {
+			count++
+		}
+	}
+	return count
+}
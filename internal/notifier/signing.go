@@ -0,0 +1,119 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	lmsv1alpha1 "github.com/krestomatio/lms-moodle-operator/api/lms/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// SignatureHeader holds the HMAC-SHA256 signature of timestamp and body
+	SignatureHeader string = "X-LMS-Signature"
+	// TimestampHeader holds the unix time the request was signed at
+	TimestampHeader string = "X-LMS-Timestamp"
+	// JWTIssuer is the issuer of JWT bearer tokens
+	JWTIssuer string = "lms-moodle-operator"
+	// JWTExpiration is the lifetime of JWT bearer tokens
+	JWTExpiration time.Duration = 5 * time.Minute
+	// SigningSecretLabel must be set to "true" on secrets holding signing keys
+	SigningSecretLabel string = "lms.krestomat.io/notifier-signing"
+)
+
+// sign adds signature headers to a request, using the signing key from a secret
+func (n *Notifier) sign(ctx context.Context, req *http.Request, body []byte, delivery *Delivery) error {
+	signing := delivery.Endpoint.Signing
+	if signing == nil {
+		return nil
+	}
+
+	key, err := n.signingKey(ctx, signing.SecretRef)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	switch signing.Method {
+	case lmsv1alpha1.HMACSigningMethod:
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+hmacSHA256Hex(key, []byte(timestamp+"."+string(body))))
+	case lmsv1alpha1.JWTSigningMethod:
+		bodySum := sha256.Sum256(body)
+		token, err := jwtHS256(key, map[string]interface{}{
+			"iss":         JWTIssuer,
+			"sub":         delivery.LMSMoodle,
+			"jti":         delivery.Event.ID,
+			"iat":         now.Unix(),
+			"exp":         now.Add(JWTExpiration).Unix(),
+			"body_sha256": hex.EncodeToString(bodySum[:]),
+		})
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	default:
+		return fmt.Errorf("unknown signing method '%s'", signing.Method)
+	}
+
+	return nil
+}
+
+// signingKey returns the signing key from a secret in notifier namespace, labeled as a
+// signing secret, so that LMSMoodle owners can neither read secrets of other namespaces
+// nor other secrets of notifier namespace, such as operator ones
+func (n *Notifier) signingKey(ctx context.Context, secretRef lmsv1alpha1.SecretKeySelector) ([]byte, error) {
+	if secretRef.Namespace != n.namespace {
+		return nil, &EndpointNotAllowedError{fmt.Sprintf("signing secret '%s/%s' must be in namespace '%s'", secretRef.Namespace, secretRef.Name, n.namespace)}
+	}
+
+	secret := &corev1.Secret{}
+	if err := n.reader.Get(ctx, types.NamespacedName{Name: secretRef.Name, Namespace: secretRef.Namespace}, secret); err != nil {
+		return nil, fmt.Errorf("unable to get signing secret '%s/%s': %w", secretRef.Namespace, secretRef.Name, err)
+	}
+	if secret.GetLabels()[SigningSecretLabel] != "true" {
+		return nil, &EndpointNotAllowedError{fmt.Sprintf("signing secret '%s/%s' must be labeled %s=true", secretRef.Namespace, secretRef.Name, SigningSecretLabel)}
+	}
+
+	key, found := secret.Data[secretRef.Key]
+	if !found || len(key) == 0 {
+		return nil, fmt.Errorf("signing secret '%s/%s' has no key '%s'", secretRef.Namespace, secretRef.Name, secretRef.Key)
+	}
+
+	return key, nil
+}
+
+// hmacSHA256Hex returns the hex encoded HMAC-SHA256 of a message
+func hmacSHA256Hex(key []byte, message []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// jwtHS256 returns a JWT with given claims, signed with HMAC-SHA256
+func jwtHS256(key []byte, claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}